@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/aws"
 	"github.com/eliran89c/tag-patrol/pkg/patrol"
@@ -10,19 +11,36 @@ import (
 )
 
 var (
-	viewARN string
-	profile string
-	region  string
+	viewARN     string
+	profile     string
+	region      string
+	awsBackend  string
+	regions     []string
+	allRegions  bool
+	accounts    []string
+	orgAccounts bool
+	roleName    string
+	endpointURL string
+
+	maxRetries        int
+	rateLimit         float64
+	definitionTimeout time.Duration
 )
 
 var (
 	awsCmd = &cobra.Command{
-		Use:   "aws",
-		Short: "Scan AWS resources",
-		Long:  "Scan AWS resources using Resource Explorer and validate their tags against a defined policy.",
+		Use:     "aws",
+		Short:   "Scan AWS resources",
+		Long:    "Scan AWS resources using Resource Explorer and validate their tags against a defined policy.",
+		PreRunE: requirePolicy,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			var providerOpts []aws.Option
+
+			providerOpts := []aws.Option{
+				aws.WithBackend(aws.Backend(awsBackend)),
+				aws.WithMaxRetries(maxRetries),
+				aws.WithRateLimit(rateLimit),
+			}
 			if profile != "" {
 				providerOpts = append(providerOpts, aws.WithProfile(profile))
 			}
@@ -32,52 +50,32 @@ var (
 			if viewARN != "" {
 				providerOpts = append(providerOpts, aws.WithViewARN(viewARN))
 			}
-			provider, err := aws.NewProvider(ctx, providerOpts...)
-			if err != nil {
-				return fmt.Errorf("error creating AWS provider: %w", err)
+			if len(regions) > 0 {
+				providerOpts = append(providerOpts, aws.WithRegions(regions...))
 			}
-
-			p := patrol.New(provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
-			results, err := p.RunFromFile(ctx, policyPath)
+			if allRegions {
+				providerOpts = append(providerOpts, aws.WithAllRegions())
+			}
+			if len(accounts) > 0 {
+				providerOpts = append(providerOpts, aws.WithAccounts(accounts...))
+			}
+			if orgAccounts {
+				providerOpts = append(providerOpts, aws.WithOrganizationAccounts())
+			}
+			if roleName != "" {
+				providerOpts = append(providerOpts, aws.WithRoleName(roleName))
+			}
+			if endpointURL != "" {
+				providerOpts = append(providerOpts, aws.WithEndpoint(endpointURL))
+			}
+			provider, err := aws.NewProvider(ctx, providerOpts...)
 			if err != nil {
-				return fmt.Errorf("error executing patrol: %w", err)
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating AWS provider: %w", err))
 			}
 
-			// TODO: create a `reporter` package to handle different output formats
-			fmt.Println(p.Summary(results))
-
-			for _, result := range results {
-				if result.Error != nil {
-					fmt.Printf("Error processing %s.%s: %v\n",
-						result.Definition.Service,
-						result.Definition.ResourceType,
-						result.Error)
-					continue
-				}
-
-				if result.NonCompliantCount > 0 {
-					fmt.Printf("\nResource: %s.%s - Compliant: %d, Non-compliant: %d\n",
-						result.Definition.Service,
-						result.Definition.ResourceType,
-						result.CompliantCount,
-						result.NonCompliantCount)
-
-					for _, resource := range result.Resources {
-						if !resource.IsCompliant() {
-							fmt.Printf("  Non-compliant resource: %s\n", resource.ID())
-
-							for _, e := range resource.ComplianceErrors() {
-								fmt.Printf("    Error: %s\n", e.Message)
-							}
-
-							for _, w := range resource.ComplianceWarnings() {
-								fmt.Printf("    Warning: %s\n", w.Message)
-							}
-						}
-					}
-				}
-			}
-			return nil
+			// a definition that fails, e.g. because it is still throttled after every retry, is
+			// reported with the others instead of aborting the run
+			return runPatrol(ctx, cmd, provider, &patrol.Options{ConcurrentWorkers: 10, DefinitionTimeout: definitionTimeout})
 		},
 	}
 )
@@ -86,4 +84,14 @@ func init() {
 	awsCmd.PersistentFlags().StringVar(&viewARN, "view-arn", "", "The ARN of the Resource Explorer view to use.")
 	awsCmd.PersistentFlags().StringVar(&profile, "profile", "", "The AWS profile to use.")
 	awsCmd.PersistentFlags().StringVar(&region, "region", "", "The AWS region to use.")
+	awsCmd.PersistentFlags().StringVar(&awsBackend, "backend", string(aws.BackendResourceExplorer), "The search backend (resource-explorer, tagging).")
+	awsCmd.PersistentFlags().StringSliceVar(&regions, "regions", nil, "The regions to search, each on its own (repeatable, defaults to --region).")
+	awsCmd.PersistentFlags().BoolVar(&allRegions, "all-regions", false, "Search every region with a Resource Explorer index.")
+	awsCmd.PersistentFlags().StringSliceVar(&accounts, "accounts", nil, "The account IDs to search by assuming --role-name in each of them (repeatable).")
+	awsCmd.PersistentFlags().BoolVar(&orgAccounts, "org-accounts", false, "Search every active account of the AWS Organization by assuming --role-name in each of them.")
+	awsCmd.PersistentFlags().StringVar(&roleName, "role-name", "", "The name of the role to assume in every searched account.")
+	awsCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", aws.DefaultMaxRetries, "The number of times a throttled or failed AWS API call is retried.")
+	awsCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", aws.DefaultRateLimit, "The AWS API calls per second shared by all searches, lowered while throttled (0 disables the limit).")
+	awsCmd.PersistentFlags().DurationVar(&definitionTimeout, "definition-timeout", 0, "The maximum time spent searching the resources of a definition, e.g. 5m (0 means no limit).")
+	awsCmd.PersistentFlags().StringVar(&endpointURL, "endpoint-url", "", "Override the AWS API endpoint, e.g. LocalStack or a fake server.")
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/azure"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	subscriptions    []string
+	managementGroups []string
+	azureAuth        string
+	azureCloud       string
+)
+
+var (
+	azureCmd = &cobra.Command{
+		Use:     "azure",
+		Short:   "Scan Azure resources",
+		Long:    "Scan Azure resources using Resource Graph and validate their tags against a defined policy.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			providerOpts := []azure.Option{
+				azure.WithAuthMethod(azure.AuthMethod(azureAuth)),
+				azure.WithCloud(azure.Cloud(azureCloud)),
+			}
+			if len(subscriptions) > 0 {
+				providerOpts = append(providerOpts, azure.WithSubscriptions(subscriptions...))
+			}
+			if len(managementGroups) > 0 {
+				providerOpts = append(providerOpts, azure.WithManagementGroups(managementGroups...))
+			}
+			provider, err := azure.NewProvider(ctx, providerOpts...)
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating Azure provider: %w", err))
+			}
+
+			return runPatrol(ctx, cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	azureCmd.Flags().StringSliceVar(&subscriptions, "subscription", nil, "The subscription IDs to scan (repeatable, defaults to all accessible subscriptions).")
+	azureCmd.Flags().StringSliceVar(&managementGroups, "management-group", nil, "The management groups to scan (repeatable).")
+	azureCmd.Flags().StringVar(&azureAuth, "auth", string(azure.AuthAuto), "The authentication method (auto, env, cli, managed-identity).")
+	azureCmd.Flags().StringVar(&azureCloud, "cloud", string(azure.CloudPublic), "The Azure cloud (public, china, usgovernment).")
+}
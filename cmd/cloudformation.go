@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/cloudformation"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templatePath   string
+	cfnTypeMapping map[string]string
+)
+
+var (
+	cloudformationCmd = &cobra.Command{
+		Use:     "cloudformation",
+		Short:   "Check the tags declared in a CloudFormation template",
+		Long:    "Validate the literal tags of the resources declared in a CloudFormation template (YAML or JSON) against a defined policy, before the stack is deployed.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := cloudformation.NewProvider(templatePath, cloudformation.WithTypeMapping(cfnTypeMapping))
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating CloudFormation provider: %w", err))
+			}
+
+			return runPatrol(context.Background(), cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	cloudformationCmd.Flags().StringVar(&templatePath, "template", "", "The path to the CloudFormation template (YAML or JSON).")
+	cloudformationCmd.MarkFlagRequired("template")
+	cloudformationCmd.Flags().StringToStringVar(&cfnTypeMapping, "type-mapping", nil, "Additional CloudFormation resource type mappings, e.g. AWS::Example::Thing=service:type (repeatable).")
+}
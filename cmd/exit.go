@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+)
+
+// Exit codes returned by the tagpatrol binary
+const (
+	// ExitOK indicates the run completed and passed the configured gate
+	ExitOK = 0
+	// ExitError indicates an unexpected error such as invalid flags
+	ExitError = 1
+	// ExitInvalidPolicy indicates the policy file could not be loaded or is invalid
+	ExitInvalidPolicy = 2
+	// ExitProviderError indicates the resources could not be retrieved from the provider
+	ExitProviderError = 3
+	// ExitNonCompliant indicates the results did not pass the configured gate
+	ExitNonCompliant = 4
+)
+
+// exitError associates an error with the exit code of the process
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return ExitError
+}
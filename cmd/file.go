@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/file"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inventoryPath   string
+	inventoryFormat string
+)
+
+var (
+	fileCmd = &cobra.Command{
+		Use:     "file",
+		Short:   "Scan resources from an inventory file",
+		Long:    "Validate the tags of resources exported to a JSON Lines or CSV inventory file against a defined policy, without any cloud access.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var providerOpts []file.Option
+			if inventoryFormat != "" {
+				providerOpts = append(providerOpts, file.WithFormat(file.Format(inventoryFormat)))
+			}
+			provider, err := file.NewProvider(inventoryPath, providerOpts...)
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating file provider: %w", err))
+			}
+
+			return runPatrol(context.Background(), cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	fileCmd.Flags().StringVar(&inventoryPath, "inventory", "", "The path to the inventory file (JSON Lines or CSV).")
+	fileCmd.MarkFlagRequired("inventory")
+	fileCmd.Flags().StringVar(&inventoryFormat, "format", "", "The inventory format (jsonl, csv), detected from the file extension by default.")
+}
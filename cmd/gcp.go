@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/gcp"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	organizations []string
+	folders       []string
+	projects      []string
+	gcpAuth       string
+	quotaProject  string
+)
+
+var (
+	gcpCmd = &cobra.Command{
+		Use:     "gcp",
+		Short:   "Scan Google Cloud resources",
+		Long:    "Scan Google Cloud resources using Cloud Asset Inventory and validate their labels against a defined policy.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			var scopes []string
+			for _, id := range organizations {
+				scopes = append(scopes, "organizations/"+id)
+			}
+			for _, id := range folders {
+				scopes = append(scopes, "folders/"+id)
+			}
+			for _, id := range projects {
+				scopes = append(scopes, "projects/"+id)
+			}
+
+			providerOpts := []gcp.Option{gcp.WithScopes(scopes...), gcp.WithAuthMethod(gcp.AuthMethod(gcpAuth))}
+			if quotaProject != "" {
+				providerOpts = append(providerOpts, gcp.WithQuotaProject(quotaProject))
+			}
+			provider, err := gcp.NewProvider(ctx, providerOpts...)
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating GCP provider: %w", err))
+			}
+
+			return runPatrol(ctx, cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	gcpCmd.Flags().StringSliceVar(&organizations, "organization", nil, "The organization IDs to scan (repeatable).")
+	gcpCmd.Flags().StringSliceVar(&folders, "folder", nil, "The folder IDs to scan (repeatable).")
+	gcpCmd.Flags().StringSliceVar(&projects, "project", nil, "The project IDs or numbers to scan (repeatable).")
+	gcpCmd.Flags().StringVar(&gcpAuth, "auth", string(gcp.AuthAuto), "The authentication method (auto, adc, gcloud, metadata).")
+	gcpCmd.Flags().StringVar(&quotaProject, "quota-project", "", "The project billed for Cloud Asset Inventory requests.")
+	gcpCmd.MarkFlagsOneRequired("organization", "folder", "project")
+}
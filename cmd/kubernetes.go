@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/kubernetes"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeconfigPath  string
+	kubeContext     string
+	namespace       string
+	manifestPaths   []string
+	withAnnotations bool
+)
+
+var (
+	kubernetesCmd = &cobra.Command{
+		Use:     "kubernetes",
+		Short:   "Scan Kubernetes objects",
+		Long:    "Scan Kubernetes objects from the API server or from manifest files and validate their labels against a defined policy, using the API group as service and the kind as resource type.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			var providerOpts []kubernetes.Option
+			if kubeconfigPath != "" {
+				providerOpts = append(providerOpts, kubernetes.WithKubeconfig(kubeconfigPath))
+			}
+			if kubeContext != "" {
+				providerOpts = append(providerOpts, kubernetes.WithContext(kubeContext))
+			}
+			if namespace != "" {
+				providerOpts = append(providerOpts, kubernetes.WithNamespace(namespace))
+			}
+			if len(manifestPaths) > 0 {
+				providerOpts = append(providerOpts, kubernetes.WithManifests(manifestPaths...))
+			}
+			if withAnnotations {
+				providerOpts = append(providerOpts, kubernetes.WithAnnotations())
+			}
+			provider, err := kubernetes.NewProvider(ctx, providerOpts...)
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating Kubernetes provider: %w", err))
+			}
+
+			return runPatrol(ctx, cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	kubernetesCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "The path to the kubeconfig file (defaults to the files in $KUBECONFIG or ~/.kube/config).")
+	kubernetesCmd.Flags().StringVar(&kubeContext, "context", "", "The kubeconfig context to use (defaults to the current context).")
+	kubernetesCmd.Flags().StringVar(&namespace, "namespace", "", "Only scan objects in the given namespace (defaults to all namespaces).")
+	kubernetesCmd.Flags().StringSliceVar(&manifestPaths, "manifest", nil, "Read objects from manifest files, kubectl get -o json dumps or directories instead of the API (repeatable).")
+	kubernetesCmd.Flags().BoolVar(&withAnnotations, "annotations", false, "Treat annotations as tags in addition to labels.")
+}
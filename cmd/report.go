@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resultsPath string
+	filter      patrol.Filter
+)
+
+var (
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Render saved scan results",
+		Long: `Render the results saved by a previous scan (--save) in any output format without
+querying the cloud provider again. Results can be narrowed down by service, resource type,
+account and region.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep, err := newReporter()
+			if err != nil {
+				return err
+			}
+
+			gate, err := newGate()
+			if err != nil {
+				return err
+			}
+
+			baseline, err := loadBaseline()
+			if err != nil {
+				return err
+			}
+
+			results, err := loadResults(resultsPath)
+			if err != nil {
+				return err
+			}
+
+			if baseline != nil {
+				baseline = filter.Apply(baseline)
+			}
+
+			return renderResults(cmd, rep, gate, baseline, filter.Apply(results))
+		},
+	}
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&resultsPath, "results", "", "The path to the results file saved with --save.")
+	reportCmd.MarkFlagRequired("results")
+	reportCmd.Flags().StringSliceVar(&filter.Services, "service", nil, "Only include the given services (repeatable or comma separated).")
+	reportCmd.Flags().StringSliceVar(&filter.ResourceTypes, "resource-type", nil, "Only include the given resource types (repeatable or comma separated).")
+	reportCmd.Flags().StringSliceVar(&filter.Owners, "account", nil, "Only include resources owned by the given accounts (repeatable or comma separated).")
+	reportCmd.Flags().StringSliceVar(&filter.Regions, "region", nil, "Only include resources in the given regions (repeatable or comma separated).")
+	reportCmd.Flags().BoolVar(&filter.NonCompliantOnly, "non-compliant-only", false, "Only include non-compliant resources.")
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/eliran89c/tag-patrol/pkg/reporter"
 	"github.com/spf13/cobra"
 )
 
@@ -12,7 +14,15 @@ var (
 	arch    = "dev"
 
 	// Flags
-	policyPath string
+	policyPath   string
+	outputFormat string
+	maxRows      int
+	savePath     string
+	baselinePath string
+
+	failOn          string
+	minCompliance   float64
+	maxNonCompliant int
 )
 
 var (
@@ -37,14 +47,38 @@ helping maintain governance and consistency.`,
 	}
 )
 
+// requirePolicy ensures the policy flag is set for commands that validate resources
+func requirePolicy(cmd *cobra.Command, args []string) error {
+	if policyPath == "" {
+		return fmt.Errorf(`required flag(s) "policy" not set`)
+	}
+
+	return nil
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
 	rootCmd.AddCommand(awsCmd)
+	rootCmd.AddCommand(azureCmd)
+	rootCmd.AddCommand(gcpCmd)
+	rootCmd.AddCommand(fileCmd)
+	rootCmd.AddCommand(terraformStateCmd)
+	rootCmd.AddCommand(terraformPlanCmd)
+	rootCmd.AddCommand(cloudformationCmd)
+	rootCmd.AddCommand(kubernetesCmd)
+	rootCmd.AddCommand(webhookCmd)
+	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	rootCmd.PersistentFlags().StringVar(&policyPath, "policy", "", "The path to the policy file (YAML format).")
-	rootCmd.MarkPersistentFlagRequired("policy")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", string(reporter.FormatText), "The output format (text, json, sarif, junit, csv, html, markdown, prometheus).")
+	rootCmd.PersistentFlags().IntVar(&maxRows, "max-rows", 0, "The maximum number of resource rows in size limited output formats like markdown (0 means no limit).")
+	rootCmd.PersistentFlags().StringVar(&savePath, "save", "", "The path to save the full scan results to (JSON format), for later use with the report command.")
+	rootCmd.PersistentFlags().StringVar(&baselinePath, "baseline", "", "The path to previously saved results, only violations that are not in the baseline are reported and fail the run.")
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", string(patrol.FailOnNone), "The lowest finding severity that fails the run (error, warning, none). Gating is opt-in, with none the run passes whatever the findings.")
+	rootCmd.PersistentFlags().Float64Var(&minCompliance, "min-compliance", 0, "The minimum percentage of compliant resources required to pass (0 disables the check).")
+	rootCmd.PersistentFlags().IntVar(&maxNonCompliant, "max-non-compliant", -1, "The maximum number of non-compliant resources allowed per resource definition (-1 disables the check).")
 }
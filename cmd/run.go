@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/eliran89c/tag-patrol/pkg/reporter"
+	"github.com/spf13/cobra"
+)
+
+// newReporter creates the reporter selected by the output flags
+func newReporter() (reporter.Reporter, error) {
+	return reporter.New(reporter.Format(outputFormat), &reporter.Options{MaxRows: maxRows})
+}
+
+// newGate creates the gate selected by the failure flags
+func newGate() (*patrol.Gate, error) {
+	gate := &patrol.Gate{
+		FailOn:          patrol.FailOn(failOn),
+		MinCompliance:   minCompliance,
+		MaxNonCompliant: maxNonCompliant,
+	}
+
+	if err := gate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return gate, nil
+}
+
+// runPatrol validates the resources returned by the finder against the policy,
+// renders the results and maps the outcome to the process exit code
+func runPatrol(ctx context.Context, cmd *cobra.Command, finder patrol.Finder, options *patrol.Options) error {
+	rep, err := newReporter()
+	if err != nil {
+		return err
+	}
+
+	gate, err := newGate()
+	if err != nil {
+		return err
+	}
+
+	baseline, err := loadBaseline()
+	if err != nil {
+		return err
+	}
+
+	p := patrol.New(finder, options)
+
+	definitions, err := p.Parser.ParseFile(policyPath)
+	if err != nil {
+		return withExitCode(ExitInvalidPolicy, fmt.Errorf("error parsing policy file: %w", err))
+	}
+
+	results, err := p.Run(ctx, definitions)
+	if err != nil {
+		return withExitCode(ExitProviderError, fmt.Errorf("error executing patrol: %w", err))
+	}
+
+	return renderResults(cmd, rep, gate, baseline, results)
+}
+
+// renderResults saves the full results, narrows them down to new violations when
+// a baseline is given, renders them and evaluates them against the gate. Reporters
+// implementing DiffReporter also render the fixed and unchanged resources.
+func renderResults(cmd *cobra.Command, rep reporter.Reporter, gate *patrol.Gate, baseline, results []patrol.Result) error {
+	if savePath != "" {
+		if err := saveResults(savePath, results); err != nil {
+			return err
+		}
+	}
+
+	if baseline == nil {
+		if err := rep.Report(cmd.OutOrStdout(), results); err != nil {
+			return fmt.Errorf("error writing report: %w", err)
+		}
+		return evaluateResults(results, gate)
+	}
+
+	diff := patrol.Compare(baseline, results)
+	fmt.Fprint(cmd.ErrOrStderr(), diff.String())
+
+	var err error
+	if dr, ok := rep.(reporter.DiffReporter); ok {
+		err = dr.ReportDiff(cmd.OutOrStdout(), diff)
+	} else {
+		err = rep.Report(cmd.OutOrStdout(), diff.Results())
+	}
+	if err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+
+	return evaluateResults(diff.Results(), gate)
+}
+
+// evaluateResults maps definition errors and gate violations to exit codes
+func evaluateResults(results []patrol.Result, gate *patrol.Gate) error {
+	if summary := patrol.Summarize(results); summary.DefinitionsWithErrors > 0 {
+		return withExitCode(ExitProviderError, fmt.Errorf("%d resource definition(s) had errors", summary.DefinitionsWithErrors))
+	}
+
+	if violations := gate.Evaluate(results); len(violations) > 0 {
+		return withExitCode(ExitNonCompliant, fmt.Errorf("compliance check failed:\n- %s", strings.Join(violations, "\n- ")))
+	}
+
+	return nil
+}
+
+// saveResults writes the full results as a JSON document to the given path
+func saveResults(path string, results []patrol.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error saving results: %w", err)
+	}
+	defer f.Close()
+
+	if err := reporter.NewJSONReporter().Report(f, results); err != nil {
+		return fmt.Errorf("error saving results: %w", err)
+	}
+
+	return f.Close()
+}
+
+// loadBaseline reads the baseline results, it returns nil when no baseline is set
+func loadBaseline() ([]patrol.Result, error) {
+	if baselinePath == "" {
+		return nil, nil
+	}
+
+	return loadResults(baselinePath)
+}
+
+// loadResults reads the results saved by a previous scan
+func loadResults(path string) ([]patrol.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading results: %w", err)
+	}
+	defer f.Close()
+
+	doc, err := reporter.ReadJSONDocument(f)
+	if err != nil {
+		return nil, fmt.Errorf("error loading results from %s: %w", path, err)
+	}
+
+	return doc.Results(), nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/terraform"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statePath   string
+	planPath    string
+	typeMapping map[string]string
+)
+
+var (
+	terraformStateCmd = &cobra.Command{
+		Use:     "terraform-state",
+		Short:   "Scan resources from a Terraform state file",
+		Long:    "Validate the tags of the AWS resources managed in a Terraform state file (v4 JSON format) against a defined policy.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := terraform.NewStateProvider(statePath, terraform.WithTypeMapping(typeMapping))
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating Terraform state provider: %w", err))
+			}
+
+			return runPatrol(context.Background(), cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+
+	terraformPlanCmd = &cobra.Command{
+		Use:     "terraform-plan",
+		Short:   "Check the planned tags of a Terraform plan",
+		Long:    "Validate the planned tags of the AWS resources created or updated by a Terraform plan (`terraform show -json` output) against a defined policy, before they are applied.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := terraform.NewPlanProvider(planPath, terraform.WithTypeMapping(typeMapping))
+			if err != nil {
+				return withExitCode(ExitProviderError, fmt.Errorf("error creating Terraform plan provider: %w", err))
+			}
+
+			return runPatrol(context.Background(), cmd, provider, &patrol.Options{StopOnError: true, ConcurrentWorkers: 10})
+		},
+	}
+)
+
+func init() {
+	terraformStateCmd.Flags().StringVar(&statePath, "state", "", "The path to the Terraform state file.")
+	terraformStateCmd.MarkFlagRequired("state")
+	terraformStateCmd.Flags().StringToStringVar(&typeMapping, "type-mapping", nil, "Additional Terraform resource type mappings, e.g. aws_example=service:type (repeatable).")
+
+	terraformPlanCmd.Flags().StringVar(&planPath, "plan", "", "The path to the Terraform plan in JSON format (terraform show -json).")
+	terraformPlanCmd.MarkFlagRequired("plan")
+	terraformPlanCmd.Flags().StringToStringVar(&typeMapping, "type-mapping", nil, "Additional Terraform resource type mappings, e.g. aws_example=service:type (repeatable).")
+}
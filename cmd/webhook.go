@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eliran89c/tag-patrol/pkg/policy"
+	"github.com/eliran89c/tag-patrol/pkg/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listenAddr      string
+	tlsCertPath     string
+	tlsKeyPath      string
+	webhookWarnOnly bool
+	webhookAnnotate bool
+)
+
+var (
+	webhookCmd = &cobra.Command{
+		Use:     "webhook",
+		Short:   "Run a Kubernetes validating admission webhook",
+		Long:    "Run an HTTPS validating admission webhook that rejects Kubernetes objects whose labels violate the policy and returns compliance warnings to the client.",
+		PreRunE: requirePolicy,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			definitions, err := policy.NewParser().ParseFile(policyPath)
+			if err != nil {
+				return withExitCode(ExitInvalidPolicy, fmt.Errorf("error parsing policy file: %w", err))
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/validate", webhook.New(definitions, &webhook.Options{
+				Annotations: webhookAnnotate,
+				WarnOnly:    webhookWarnOnly,
+			}))
+			mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			server := &http.Server{
+				Addr:              listenAddr,
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			errCh := make(chan error, 1)
+			go func() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Serving admission webhook on %s\n", listenAddr)
+				errCh <- server.ListenAndServeTLS(tlsCertPath, tlsKeyPath)
+			}()
+
+			select {
+			case err := <-errCh:
+				if !errors.Is(err, http.ErrServerClosed) {
+					return fmt.Errorf("error serving webhook: %w", err)
+				}
+				return nil
+			case <-ctx.Done():
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			return server.Shutdown(shutdownCtx)
+		},
+	}
+)
+
+func init() {
+	webhookCmd.Flags().StringVar(&listenAddr, "listen", ":8443", "The address to serve the webhook on.")
+	webhookCmd.Flags().StringVar(&tlsCertPath, "tls-cert", "", "The path to the TLS certificate file.")
+	webhookCmd.MarkFlagRequired("tls-cert")
+	webhookCmd.Flags().StringVar(&tlsKeyPath, "tls-key", "", "The path to the TLS private key file.")
+	webhookCmd.MarkFlagRequired("tls-key")
+	webhookCmd.Flags().BoolVar(&webhookAnnotate, "annotations", false, "Treat annotations as tags in addition to labels.")
+	webhookCmd.Flags().BoolVar(&webhookWarnOnly, "warn-only", false, "Admit non-compliant objects and return their errors as warnings.")
+}
@@ -0,0 +1,242 @@
+//go:build e2e
+
+package integration_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/aws/fake"
+	"github.com/eliran89c/tag-patrol/pkg/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagpatrolBin is the binary built by TestMain
+var tagpatrolBin string
+
+const e2ePolicy = `
+resources:
+  ec2:
+    instance:
+      mandatoryKeys:
+        - env
+`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tagpatrol-e2e")
+	if err != nil {
+		panic(err)
+	}
+
+	tagpatrolBin = filepath.Join(dir, "tagpatrol")
+	build := exec.Command("go", "build", "-o", tagpatrolBin, "..")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// runAWS runs `tagpatrol aws` against the fake with dummy credentials, returning the JSON
+// report and the exit code
+func runAWS(t *testing.T, explorer *fake.ResourceExplorer, args ...string) (*reporter.JSONDocument, int) {
+	server := httptest.NewServer(explorer)
+	t.Cleanup(server.Close)
+
+	policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(e2ePolicy), 0o600))
+
+	cmd := exec.Command(tagpatrolBin, append([]string{
+		"aws",
+		"--policy", policyPath,
+		"--endpoint-url", server.URL,
+		"--region", "us-east-1",
+		"--output", "json",
+	}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"AWS_ACCESS_KEY_ID=test",
+		"AWS_SECRET_ACCESS_KEY=test",
+		"AWS_CONFIG_FILE="+filepath.Join(t.TempDir(), "config"),
+		"AWS_SHARED_CREDENTIALS_FILE="+filepath.Join(t.TempDir(), "credentials"),
+	)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else {
+		require.NoError(t, err)
+	}
+
+	var report reporter.JSONDocument
+	require.NoError(t, json.Unmarshal(out, &report), "output: %s", out)
+	return &report, code
+}
+
+func instance(id string, tags map[string]string) *fake.Resource {
+	return &fake.Resource{
+		ARN:     "arn:aws:ec2:us-east-1:123456789012:instance/" + id,
+		Type:    "ec2:instance",
+		Service: "ec2",
+		Account: "123456789012",
+		Region:  "us-east-1",
+		Tags:    tags,
+	}
+}
+
+func TestAWSEndToEnd_Pagination(t *testing.T) {
+	explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{
+		instance("i-1", map[string]string{"env": "prod"}),
+		instance("i-2", map[string]string{"env": "dev"}),
+		instance("i-3", map[string]string{"env": "dev"}),
+		instance("i-4", nil),
+		instance("i-5", map[string]string{"env": "prod"}),
+	}}
+
+	report, code := runAWS(t, explorer, "--fail-on", "error")
+	assert.Equal(t, 4, code)
+	assert.Equal(t, 5, report.Summary.Resources)
+	assert.Equal(t, 4, report.Summary.Compliant)
+	assert.Equal(t, 1, report.Summary.NonCompliant)
+	assert.Equal(t, 3, explorer.Requests())
+}
+
+func TestAWSEndToEnd_Throttling(t *testing.T) {
+	explorer := &fake.ResourceExplorer{
+		Resources: []*fake.Resource{instance("i-1", map[string]string{"env": "prod"})},
+		Throttle:  2,
+	}
+
+	report, code := runAWS(t, explorer, "--fail-on", "error")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 1, report.Summary.Compliant)
+	assert.Equal(t, 3, explorer.Requests())
+}
+
+func TestAWSEndToEnd_MalformedTags(t *testing.T) {
+	malformed := instance("i-1", nil)
+	malformed.RawTags = json.RawMessage(`{"env": "prod"}`)
+	explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{malformed}}
+
+	// a tags document that can't be decoded is reported as a resource without tags, with a warning
+	report, code := runAWS(t, explorer)
+	assert.Equal(t, 0, code)
+	require.Len(t, report.Definitions, 1)
+	require.Len(t, report.Definitions[0].Resources, 1)
+	assert.False(t, report.Definitions[0].Resources[0].Compliant)
+	assert.Empty(t, report.Definitions[0].Resources[0].Tags)
+	require.Len(t, report.Definitions[0].Warnings, 1)
+	assert.Contains(t, report.Definitions[0].Warnings[0], "could not be decoded")
+}
+
+func TestAWSEndToEnd_AllRegions(t *testing.T) {
+	east := instance("i-1", map[string]string{"env": "prod"})
+	west := instance("i-2", nil)
+	west.Region = "eu-west-1"
+	west.ARN = "arn:aws:ec2:eu-west-1:123456789012:instance/i-2"
+	unindexed := instance("i-3", nil)
+	unindexed.Region = "ap-south-1"
+	unindexed.ARN = "arn:aws:ec2:ap-south-1:123456789012:instance/i-3"
+
+	explorer := &fake.ResourceExplorer{
+		Resources: []*fake.Resource{east, west, unindexed},
+		Indexes:   []string{"us-east-1", "eu-west-1"},
+	}
+
+	// every indexed region is searched on its own
+	report, code := runAWS(t, explorer, "--all-regions", "--fail-on", "error")
+	assert.Equal(t, 4, code)
+	assert.Equal(t, 2, report.Summary.Resources)
+	assert.Equal(t, 1, report.Summary.NonCompliant)
+}
+
+func TestAWSEndToEnd_TaggingBackend(t *testing.T) {
+	west := instance("i-3", map[string]string{"env": "dev"})
+	west.Region = "eu-west-1"
+	west.ARN = "arn:aws:ec2:eu-west-1:123456789012:instance/i-3"
+
+	explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{
+		instance("i-1", map[string]string{"env": "prod"}),
+		instance("i-2", nil),
+		west,
+	}}
+
+	// resources that were never tagged are invisible to the Tagging API
+	report, code := runAWS(t, explorer, "--backend", "tagging", "--regions", "us-east-1,eu-west-1", "--fail-on", "error")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 2, report.Summary.Resources)
+	assert.Equal(t, 1, report.Summary.DefinitionsWithWarnings)
+	require.Len(t, report.Definitions[0].Warnings, 1)
+	assert.Contains(t, report.Definitions[0].Warnings[0], "never tagged")
+	assert.Zero(t, explorer.Requests())
+}
+
+func TestAWSEndToEnd_OrganizationAccounts(t *testing.T) {
+	var resources []*fake.Resource
+	for _, account := range []string{"111111111111", "222222222222", "333333333333"} {
+		resource := instance("i-"+account, map[string]string{"env": "prod"})
+		resource.Account = account
+		resource.ARN = "arn:aws:ec2:us-east-1:" + account + ":instance/i-1"
+		resources = append(resources, resource)
+	}
+
+	explorer := &fake.ResourceExplorer{
+		Resources:      resources,
+		Accounts:       []string{"111111111111", "222222222222", "333333333333"},
+		DeniedAccounts: []string{"333333333333"},
+	}
+
+	// the account whose role can't be assumed is reported as a warning
+	report, code := runAWS(t, explorer, "--org-accounts", "--role-name", "TagPatrol", "--max-retries", "0")
+	assert.Equal(t, 0, code)
+	require.Len(t, report.Definitions, 1)
+	require.Len(t, report.Definitions[0].Resources, 2)
+	assert.Equal(t, "111111111111", report.Definitions[0].Resources[0].OwnerID)
+	assert.Equal(t, "222222222222", report.Definitions[0].Resources[1].OwnerID)
+	require.Len(t, report.Definitions[0].Warnings, 1)
+	assert.Contains(t, report.Definitions[0].Warnings[0], "error searching account 333333333333")
+}
+
+func TestAWSEndToEnd_TruncatedSearch(t *testing.T) {
+	var resources []*fake.Resource
+	tags := []map[string]string{{"env": "prod"}, {"env": "dev"}, nil, {"env": "prod"}, {"env": "prod"}}
+	for i, region := range []string{"us-east-1", "us-east-1", "us-east-1", "eu-west-1", "eu-west-1"} {
+		resource := instance(fmt.Sprintf("i-%d", i), tags[i])
+		resource.Region = region
+		resource.ARN = fmt.Sprintf("arn:aws:ec2:%s:123456789012:instance/i-%d", region, i)
+		resources = append(resources, resource)
+	}
+
+	// every region fits in a partition
+	report, code := runAWS(t, &fake.ResourceExplorer{Resources: resources, MaxResults: 3})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 5, report.Summary.Resources)
+	assert.Zero(t, report.Summary.DefinitionsWithWarnings)
+
+	// a single region and account is split by tag key and value
+	report, code = runAWS(t, &fake.ResourceExplorer{Resources: resources, MaxResults: 2})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 5, report.Summary.Resources)
+	assert.Zero(t, report.Summary.DefinitionsWithWarnings)
+
+	// resources with the same tags in a single region and account can't be partitioned
+	identical := []*fake.Resource{resources[0], instance("i-5", map[string]string{"env": "prod"}), instance("i-6", map[string]string{"env": "prod"})}
+	report, code = runAWS(t, &fake.ResourceExplorer{Resources: identical, MaxResults: 2})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 2, report.Summary.Resources)
+	assert.Equal(t, 1, report.Summary.DefinitionsWithWarnings)
+	require.Len(t, report.Definitions[0].Warnings, 1)
+	assert.Contains(t, report.Definitions[0].Warnings[0], "some resources may be missing")
+}
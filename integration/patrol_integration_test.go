@@ -37,11 +37,11 @@ func (m *inMemoryResource) Region() string          { return m.region }
 func (m *inMemoryResource) OwnerID() string         { return m.ownerID }
 func (m *inMemoryResource) Tags() map[string]string { return m.tags }
 func (m *inMemoryResource) IsCompliant() bool       { return len(m.errors) == 0 }
-func (m *inMemoryResource) AddComplianceError(msg string) {
-	m.errors = append(m.errors, &cr.ComplianceError{Message: msg})
+func (m *inMemoryResource) AddComplianceError(err *cr.ComplianceError) {
+	m.errors = append(m.errors, err)
 }
-func (m *inMemoryResource) AddComplianceWarning(msg string) {
-	m.warnings = append(m.warnings, &cr.ComplianceWarning{Message: msg})
+func (m *inMemoryResource) AddComplianceWarning(warning *cr.ComplianceWarning) {
+	m.warnings = append(m.warnings, warning)
 }
 func (m *inMemoryResource) ComplianceErrors() []*cr.ComplianceError     { return m.errors }
 func (m *inMemoryResource) ComplianceWarnings() []*cr.ComplianceWarning { return m.warnings }
@@ -0,0 +1,91 @@
+package cloudresource
+
+import "slices"
+
+// GenericResource is a provider agnostic CloudResource for resources that are
+// not backed by a live cloud API, e.g. loaded from saved results or inventory files
+type GenericResource struct {
+	ResourceID     string
+	ResourceType   string
+	ServiceName    string
+	ProviderName   string
+	ResourceRegion string
+	Owner          string
+	ResourceTags   map[string]string
+	// UnknownValues lists the tag keys whose values are only known at deploy time
+	UnknownValues []string
+	// UnknownTags is set when tags that are not in ResourceTags may still be set at deploy
+	// time, e.g. when the whole tag map of a planned resource is unknown
+	UnknownTags bool
+	Errors      []*ComplianceError
+	Warnings    []*ComplianceWarning
+}
+
+// ID returns the unique identifier of the resource
+func (r *GenericResource) ID() string {
+	return r.ResourceID
+}
+
+// Type returns the resource type
+func (r *GenericResource) Type() string {
+	return r.ResourceType
+}
+
+// Service returns the service name
+func (r *GenericResource) Service() string {
+	return r.ServiceName
+}
+
+// Provider returns the cloud provider name
+func (r *GenericResource) Provider() string {
+	return r.ProviderName
+}
+
+// Region returns the region where the resource is located
+func (r *GenericResource) Region() string {
+	return r.ResourceRegion
+}
+
+// OwnerID returns the ID of the account/project/subscription that owns the resource
+func (r *GenericResource) OwnerID() string {
+	return r.Owner
+}
+
+// Tags returns the resource tags as a map of key-value pairs
+func (r *GenericResource) Tags() map[string]string {
+	return r.ResourceTags
+}
+
+// IsUnknownValue returns true if the value of the tag key is only known at deploy time,
+// including keys that are missing from a tag map that is only partly known
+func (r *GenericResource) IsUnknownValue(key string) bool {
+	if _, exists := r.ResourceTags[key]; !exists {
+		return r.UnknownTags
+	}
+	return slices.Contains(r.UnknownValues, key)
+}
+
+// IsCompliant returns true if the resource has no compliance errors
+func (r *GenericResource) IsCompliant() bool {
+	return len(r.Errors) == 0
+}
+
+// AddComplianceError adds a new compliance error to the resource
+func (r *GenericResource) AddComplianceError(err *ComplianceError) {
+	r.Errors = append(r.Errors, err)
+}
+
+// AddComplianceWarning adds a new compliance warning to the resource
+func (r *GenericResource) AddComplianceWarning(warning *ComplianceWarning) {
+	r.Warnings = append(r.Warnings, warning)
+}
+
+// ComplianceErrors returns all compliance errors for this resource
+func (r *GenericResource) ComplianceErrors() []*ComplianceError {
+	return r.Errors
+}
+
+// ComplianceWarnings returns all compliance warnings for this resource
+func (r *GenericResource) ComplianceWarnings() []*ComplianceWarning {
+	return r.Warnings
+}
@@ -0,0 +1,177 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// RoleSessionName is the session name used when assuming roles in member accounts
+const RoleSessionName = "tagpatrol"
+
+// accountConcurrency is the number of accounts searched at the same time per resource definition
+const accountConcurrency = 5
+
+// STSClient defines the interface for AWS STS API interactions
+type STSClient interface {
+	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+}
+
+// OrganizationsClient defines the interface for AWS Organizations API interactions
+type OrganizationsClient interface {
+	ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+}
+
+// newOrganizationsClient creates an Organizations client, the SDK resolves the global
+// endpoint of the partition
+func newOrganizationsClient(awsCfg awssdk.Config) *organizations.Client {
+	return organizations.NewFromConfig(awsCfg, func(o *organizations.Options) {
+		// the provider retryer retries the calls
+		o.Retryer = awssdk.NopRetryer{}
+	})
+}
+
+// listOrganizationAccounts returns the IDs of the active accounts of the organization
+func listOrganizationAccounts(ctx context.Context, retryer *retryer, client OrganizationsClient) ([]string, error) {
+	var accounts []string
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+
+	for paginator.HasMorePages() {
+		// a failed page doesn't advance the paginator, so it is requested again when retried
+		var resp *organizations.ListAccountsOutput
+		err := retryer.do(ctx, func() (err error) {
+			resp, err = paginator.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing organization accounts: %w", err)
+		}
+
+		for _, account := range resp.Accounts {
+			if account.Status == types.AccountStatusActive {
+				accounts = append(accounts, awssdk.ToString(account.Id))
+			}
+		}
+	}
+
+	return accounts, nil
+}
+
+// assumeRoleCredentials retrieves the temporary credentials of a role
+type assumeRoleCredentials struct {
+	client  STSClient
+	roleARN string
+}
+
+// Retrieve assumes the role, implementing the aws.CredentialsProvider interface
+func (c *assumeRoleCredentials) Retrieve(ctx context.Context) (awssdk.Credentials, error) {
+	resp, err := c.client.AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         awssdk.String(c.roleARN),
+		RoleSessionName: awssdk.String(RoleSessionName),
+	})
+	if err != nil {
+		return awssdk.Credentials{}, fmt.Errorf("error assuming role %s: %w", c.roleARN, err)
+	}
+
+	credentials := awssdk.Credentials{
+		AccessKeyID:     awssdk.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: awssdk.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    awssdk.ToString(resp.Credentials.SessionToken),
+		Source:          "AssumeRole",
+	}
+	if resp.Credentials.Expiration != nil {
+		credentials.CanExpire = true
+		credentials.Expires = *resp.Credentials.Expiration
+	}
+
+	return credentials, nil
+}
+
+// roleARN returns the ARN of a role in an account, the role name may include a path
+func roleARN(region, accountID, roleName string) string {
+	partition := "aws"
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		partition = "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		partition = "aws-us-gov"
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, accountID, strings.Trim(roleName, "/"))
+}
+
+// findAccountResources searches every member account concurrently and aggregates the results.
+// An account that can't be searched, e.g. because its role can't be assumed, is reported as a
+// warning of the search, unless no account could be searched.
+func (p *Provider) findAccountResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	results := make([][]cr.CloudResource, len(p.accounts))
+	errs := make([]error, len(p.accounts))
+
+	fanOut(len(p.accounts), accountConcurrency, func(i int) {
+		results[i], errs[i] = p.accounts[i].findResources(ctx, serviceName, resourceName)
+	})
+
+	var resources []cr.CloudResource
+	seen := make(map[string]bool)
+	failed := 0
+
+	for i, account := range p.accounts {
+		if errs[i] != nil {
+			err := fmt.Errorf("error searching account %s: %w", account.accountID, errs[i])
+			failed++
+			if ctx.Err() != nil || failed == len(p.accounts) {
+				return nil, err
+			}
+			recordWarning(ctx, "%s, its resources are missing", err)
+			continue
+		}
+
+		for _, resource := range results[i] {
+			if seen[resource.ID()] {
+				continue
+			}
+			seen[resource.ID()] = true
+
+			// ARNs of some resources, e.g. S3 buckets, have no account
+			if awsResource, ok := resource.(*AWSResource); ok && awsResource.AccountID == "" {
+				awsResource.AccountID = account.accountID
+			}
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
+// newAccountProviders creates a provider per account, authenticated with the role assumed in the account
+func newAccountProviders(awsCfg awssdk.Config, cfg *providerConfig, accounts []string, retryer *retryer) ([]*Provider, error) {
+	slices.Sort(accounts)
+	accounts = slices.Compact(accounts)
+
+	stsClient := sts.NewFromConfig(awsCfg)
+
+	providers := make([]*Provider, 0, len(accounts))
+	for _, accountID := range accounts {
+		accountCfg := awsCfg.Copy()
+		accountCfg.Credentials = awssdk.NewCredentialsCache(&assumeRoleCredentials{
+			client:  stsClient,
+			roleARN: roleARN(awsCfg.Region, accountID, cfg.roleName),
+		})
+
+		provider, err := newProvider(accountCfg, cfg, retryer)
+		if err != nil {
+			return nil, err
+		}
+		provider.accountID = accountID
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
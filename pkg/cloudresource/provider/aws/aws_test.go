@@ -2,13 +2,25 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
 	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2"
 	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/aws/fake"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -23,6 +35,11 @@ func (m *MockResourceExplorerClient) Search(ctx context.Context, params *resourc
 	return args.Get(0).(*resourceexplorer2.SearchOutput), args.Error(1)
 }
 
+func (m *MockResourceExplorerClient) GetDefaultView(ctx context.Context, params *resourceexplorer2.GetDefaultViewInput, optFns ...func(*resourceexplorer2.Options)) (*resourceexplorer2.GetDefaultViewOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*resourceexplorer2.GetDefaultViewOutput), args.Error(1)
+}
+
 func TestAWSResource(t *testing.T) {
 	t.Run("Basic Properties", func(t *testing.T) {
 		resource := &AWSResource{
@@ -72,18 +89,18 @@ func TestAWSResource(t *testing.T) {
 		assert.Empty(t, resource.ComplianceErrors())
 		assert.Empty(t, resource.ComplianceWarnings())
 
-		resource.AddComplianceError("Error 1")
+		resource.AddComplianceError(&cr.ComplianceError{Message: "Error 1"})
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 1)
 		assert.Equal(t, "Error 1", resource.ComplianceErrors()[0].Message)
 
-		resource.AddComplianceError("Error 2")
-		resource.AddComplianceError("Error 3")
+		resource.AddComplianceError(&cr.ComplianceError{Message: "Error 2"})
+		resource.AddComplianceError(&cr.ComplianceError{Message: "Error 3"})
 		assert.Len(t, resource.ComplianceErrors(), 3)
 		assert.Equal(t, "Error 3", resource.ComplianceErrors()[2].Message)
 
-		resource.AddComplianceWarning("Warning 1")
-		resource.AddComplianceWarning("Warning 2")
+		resource.AddComplianceWarning(&cr.ComplianceWarning{Message: "Warning 1"})
+		resource.AddComplianceWarning(&cr.ComplianceWarning{Message: "Warning 2"})
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceWarnings(), 2)
 		assert.Equal(t, "Warning 1", resource.ComplianceWarnings()[0].Message)
@@ -93,7 +110,7 @@ func TestAWSResource(t *testing.T) {
 			ResourceARN:  "arn:aws:ec2:us-west-2:123456789012:instance/i-test",
 			ResourceTags: map[string]string{},
 		}
-		resource.AddComplianceWarning("Warning Only")
+		resource.AddComplianceWarning(&cr.ComplianceWarning{Message: "Warning Only"})
 		assert.True(t, resource.IsCompliant()) // Still compliant with warnings
 	})
 }
@@ -432,3 +449,954 @@ func TestAWSResourceImplementsCloudResource(t *testing.T) {
 	require.NotNil(t, resource.ComplianceErrors)
 	require.NotNil(t, resource.ComplianceWarnings)
 }
+
+// apiError is an AWS API error response, like the errors of the SDK clients
+type apiError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (status %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// ErrorCode returns the error code, implementing the smithy.APIError interface
+func (e *apiError) ErrorCode() string {
+	return e.Code
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *apiError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+type MockTaggingClient struct {
+	mock.Mock
+}
+
+func (m *MockTaggingClient) GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*resourcegroupstaggingapi.GetResourcesOutput), args.Error(1)
+}
+
+func TestBackendOptions(t *testing.T) {
+	cfg := &providerConfig{}
+	for _, opt := range []Option{WithBackend(BackendTagging), WithRegions("us-east-1"), WithRegions("eu-west-1")} {
+		opt(cfg)
+	}
+
+	assert.Equal(t, BackendTagging, cfg.backend)
+	assert.Equal(t, []string{"us-east-1", "eu-west-1"}, cfg.regions)
+
+	_, err := NewProvider(context.Background(), WithBackend("config"))
+	assert.ErrorContains(t, err, "unsupported backend `config`")
+
+	_, err = NewProvider(context.Background(), WithBackend(BackendTagging), WithViewARN("arn:aws:resource-explorer-2:us-east-1:123456789012:view/test-view/1234567890"))
+	assert.ErrorContains(t, err, "a view ARN is only supported by the resource-explorer backend")
+}
+
+func TestFindTaggedResources(t *testing.T) {
+	t.Run("Multiple Regions", func(t *testing.T) {
+		ctx := context.Background()
+		east, west := new(MockTaggingClient), new(MockTaggingClient)
+
+		provider := &Provider{
+			backend:        BackendTagging,
+			taggingClients: map[string]TaggingClient{"us-east-1": east, "us-west-2": west},
+		}
+
+		east.On("GetResources", ctx, &resourcegroupstaggingapi.GetResourcesInput{
+			ResourceTypeFilters: []string{"s3:bucket"},
+			ResourcesPerPage:    aws.Int32(taggingResourcesPerPage),
+		}).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{
+				{ResourceARN: aws.String("arn:aws:s3:::bucket-1"), Tags: []taggingtypes.Tag{{Key: aws.String("owner"), Value: aws.String("team-a")}}},
+			},
+			PaginationToken: aws.String("next"),
+		}, nil)
+		east.On("GetResources", ctx, &resourcegroupstaggingapi.GetResourcesInput{
+			ResourceTypeFilters: []string{"s3:bucket"},
+			ResourcesPerPage:    aws.Int32(taggingResourcesPerPage),
+			PaginationToken:     aws.String("next"),
+		}).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String("arn:aws:s3:::bucket-2")}},
+		}, nil)
+		west.On("GetResources", ctx, mock.Anything).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{
+				{ResourceARN: aws.String("arn:aws:s3:::bucket-2")},
+				{ResourceARN: aws.String("arn:aws:s3:::bucket-3")},
+			},
+		}, nil)
+
+		resources, err := provider.FindResources(ctx, "s3", "bucket")
+		require.NoError(t, err)
+		require.Len(t, resources, 3)
+
+		assert.Equal(t, "arn:aws:s3:::bucket-1", resources[0].ID())
+		assert.Equal(t, "s3:bucket", resources[0].Type())
+		assert.Equal(t, "s3", resources[0].Service())
+		assert.Equal(t, "us-east-1", resources[0].Region())
+		assert.Empty(t, resources[0].OwnerID())
+		assert.Equal(t, map[string]string{"owner": "team-a"}, resources[0].Tags())
+
+		assert.Equal(t, "arn:aws:s3:::bucket-3", resources[2].ID())
+		assert.Equal(t, "us-west-2", resources[2].Region())
+		assert.NotNil(t, resources[2].Tags())
+
+		east.AssertExpectations(t)
+		west.AssertExpectations(t)
+	})
+
+	t.Run("Region And Account From ARN", func(t *testing.T) {
+		ctx := context.Background()
+		client := new(MockTaggingClient)
+		provider := &Provider{backend: BackendTagging, taggingClients: map[string]TaggingClient{"us-east-1": client}}
+
+		client.On("GetResources", ctx, mock.Anything).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{
+				{ResourceARN: aws.String("arn:aws:ec2:us-east-1:123456789012:instance/i-1")},
+			},
+		}, nil)
+
+		resources, err := provider.FindResources(ctx, "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "us-east-1", resources[0].Region())
+		assert.Equal(t, "123456789012", resources[0].OwnerID())
+	})
+
+	t.Run("Never Tagged Resources Warning", func(t *testing.T) {
+		client := new(MockTaggingClient)
+		provider := &Provider{
+			backend:        BackendTagging,
+			taggingClients: map[string]TaggingClient{"us-east-1": client},
+			stats:          make(map[string]*searchStats),
+		}
+
+		client.On("GetResources", mock.Anything, mock.Anything).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{
+				{ResourceARN: aws.String("arn:aws:ec2:us-east-1:123456789012:instance/i-1")},
+			},
+		}, nil)
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+
+		warnings := provider.Warnings("ec2", "instance")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "ec2:instance resources that were never tagged are not checked")
+	})
+
+	t.Run("Error Response", func(t *testing.T) {
+		ctx := context.Background()
+		client := new(MockTaggingClient)
+		provider := &Provider{backend: BackendTagging, taggingClients: map[string]TaggingClient{"eu-west-1": client}}
+
+		client.On("GetResources", ctx, mock.Anything).Return((*resourcegroupstaggingapi.GetResourcesOutput)(nil), &apiError{StatusCode: 400, Code: "ThrottledException", Message: "Rate exceeded"})
+
+		_, err := provider.FindResources(ctx, "ec2", "instance")
+		assert.EqualError(t, err, "error getting resources in eu-west-1: ThrottledException: Rate exceeded (status 400)")
+
+		var apiErr *apiError
+		assert.ErrorAs(t, err, &apiErr)
+	})
+}
+
+type MockSTSClient struct {
+	mock.Mock
+}
+
+func (m *MockSTSClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*sts.AssumeRoleOutput), args.Error(1)
+}
+
+type MockOrganizationsClient struct {
+	mock.Mock
+}
+
+func (m *MockOrganizationsClient) ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*organizations.ListAccountsOutput), args.Error(1)
+}
+
+func TestMultiAccountOptions(t *testing.T) {
+	cfg := &providerConfig{}
+	for _, opt := range []Option{WithAccounts("111111111111"), WithAccounts("222222222222"), WithOrganizationAccounts(), WithRoleName("TagPatrolReadOnly")} {
+		opt(cfg)
+	}
+
+	assert.Equal(t, []string{"111111111111", "222222222222"}, cfg.accounts)
+	assert.True(t, cfg.organizationAccounts)
+	assert.Equal(t, "TagPatrolReadOnly", cfg.roleName)
+
+	_, err := NewProvider(context.Background(), WithAccounts("111111111111"))
+	assert.ErrorContains(t, err, "a role name is required to search multiple accounts")
+
+	_, err = NewProvider(context.Background(), WithOrganizationAccounts(), WithRoleName("TagPatrolReadOnly"), WithViewARN("arn:aws:resource-explorer-2:us-east-1:123456789012:view/test-view/1234567890"))
+	assert.ErrorContains(t, err, "a view ARN cannot be used to search multiple accounts")
+}
+
+func TestRoleARN(t *testing.T) {
+	assert.Equal(t, "arn:aws:iam::111111111111:role/TagPatrol", roleARN("eu-west-1", "111111111111", "TagPatrol"))
+	assert.Equal(t, "arn:aws:iam::111111111111:role/security/TagPatrol", roleARN("", "111111111111", "/security/TagPatrol"))
+	assert.Equal(t, "arn:aws-cn:iam::111111111111:role/TagPatrol", roleARN("cn-north-1", "111111111111", "TagPatrol"))
+	assert.Equal(t, "arn:aws-us-gov:iam::111111111111:role/TagPatrol", roleARN("us-gov-west-1", "111111111111", "TagPatrol"))
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	ctx := context.Background()
+	client := new(MockSTSClient)
+	expires := time.Now().Add(time.Hour)
+
+	client.On("AssumeRole", ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::111111111111:role/TagPatrol"),
+		RoleSessionName: aws.String(RoleSessionName),
+	}).Return(&sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("AKID"),
+		SecretAccessKey: aws.String("SECRET"),
+		SessionToken:    aws.String("TOKEN"),
+		Expiration:      &expires,
+	}}, nil).Once()
+
+	credentials := &assumeRoleCredentials{client: client, roleARN: "arn:aws:iam::111111111111:role/TagPatrol"}
+
+	creds, err := credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", creds.AccessKeyID)
+	assert.Equal(t, "TOKEN", creds.SessionToken)
+	assert.True(t, creds.CanExpire)
+	assert.Equal(t, expires, creds.Expires)
+
+	client.On("AssumeRole", ctx, mock.Anything).Return((*sts.AssumeRoleOutput)(nil), errors.New("AccessDenied"))
+
+	_, err = credentials.Retrieve(ctx)
+	assert.EqualError(t, err, "error assuming role arn:aws:iam::111111111111:role/TagPatrol: AccessDenied")
+}
+
+func TestListOrganizationAccounts(t *testing.T) {
+	ctx := context.Background()
+	client := new(MockOrganizationsClient)
+
+	client.On("ListAccounts", ctx, &organizations.ListAccountsInput{}).Return(&organizations.ListAccountsOutput{
+		Accounts: []orgtypes.Account{
+			{Id: aws.String("111111111111"), Status: orgtypes.AccountStatusActive},
+			{Id: aws.String("222222222222"), Status: orgtypes.AccountStatusSuspended},
+		},
+		NextToken: aws.String("next"),
+	}, nil)
+	client.On("ListAccounts", ctx, &organizations.ListAccountsInput{NextToken: aws.String("next")}).Return((*organizations.ListAccountsOutput)(nil), &apiError{StatusCode: 400, Code: "TooManyRequestsException"}).Once()
+	client.On("ListAccounts", ctx, &organizations.ListAccountsInput{NextToken: aws.String("next")}).Return(&organizations.ListAccountsOutput{
+		Accounts: []orgtypes.Account{{Id: aws.String("333333333333"), Status: orgtypes.AccountStatusActive}},
+	}, nil)
+
+	// throttled calls are retried
+	accounts, err := listOrganizationAccounts(ctx, newTestRetryer(1), client)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"111111111111", "333333333333"}, accounts)
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "ListAccounts", 3)
+
+	failing := new(MockOrganizationsClient)
+	failing.On("ListAccounts", ctx, mock.Anything).Return((*organizations.ListAccountsOutput)(nil), &apiError{StatusCode: 400, Code: "AWSOrganizationsNotInUseException"})
+
+	_, err = listOrganizationAccounts(ctx, newTestRetryer(1), failing)
+	assert.ErrorContains(t, err, "error listing organization accounts: AWSOrganizationsNotInUseException")
+}
+
+func TestFindAccountResources(t *testing.T) {
+	t.Run("Aggregates Accounts", func(t *testing.T) {
+		ctx := context.Background()
+		explorer := new(MockResourceExplorerClient)
+		tagging := new(MockTaggingClient)
+
+		provider := &Provider{accounts: []*Provider{
+			{client: explorer, accountID: "111111111111"},
+			{backend: BackendTagging, taggingClients: map[string]TaggingClient{"us-east-1": tagging}, accountID: "222222222222"},
+		}}
+
+		explorer.On("Search", ctx, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{
+				{
+					Arn:             aws.String("arn:aws:s3:::bucket-1"),
+					ResourceType:    aws.String("s3:bucket"),
+					Service:         aws.String("s3"),
+					OwningAccountId: aws.String("111111111111"),
+					Region:          aws.String("us-east-1"),
+				},
+			},
+		}, nil)
+		tagging.On("GetResources", ctx, mock.Anything).Return(&resourcegroupstaggingapi.GetResourcesOutput{
+			ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String("arn:aws:s3:::bucket-2")}},
+		}, nil)
+
+		resources, err := provider.FindResources(ctx, "s3", "bucket")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+
+		assert.Equal(t, "arn:aws:s3:::bucket-1", resources[0].ID())
+		assert.Equal(t, "111111111111", resources[0].OwnerID())
+		assert.Equal(t, "arn:aws:s3:::bucket-2", resources[1].ID())
+		assert.Equal(t, "222222222222", resources[1].OwnerID())
+	})
+
+	t.Run("Account Error", func(t *testing.T) {
+		ok, failing := new(MockResourceExplorerClient), new(MockResourceExplorerClient)
+
+		provider := &Provider{
+			accounts: []*Provider{
+				{client: ok, accountID: "111111111111"},
+				{client: failing, accountID: "222222222222"},
+			},
+			stats: make(map[string]*searchStats),
+		}
+
+		ok.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{newExplorerResource("arn:aws:ec2:us-east-1:111111111111:instance/i-1", "us-east-1")},
+		}, nil)
+		failing.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{}, errors.New("error assuming role"))
+
+		// the other accounts are still reported
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "arn:aws:ec2:us-east-1:111111111111:instance/i-1", resources[0].ID())
+		assert.Equal(t, []string{"error searching account 222222222222: error assuming role, its resources are missing"}, provider.Warnings("ec2", "instance"))
+	})
+
+	t.Run("Every Account Fails", func(t *testing.T) {
+		failing := new(MockResourceExplorerClient)
+		provider := &Provider{accounts: []*Provider{
+			{client: failing, accountID: "111111111111"},
+			{client: failing, accountID: "222222222222"},
+		}}
+
+		failing.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{}, errors.New("error assuming role"))
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		assert.EqualError(t, err, "error searching account 222222222222: error assuming role")
+	})
+}
+
+type MockIndexLister struct {
+	mock.Mock
+}
+
+func (m *MockIndexLister) ListIndexes(ctx context.Context, params *resourceexplorer2.ListIndexesInput, optFns ...func(*resourceexplorer2.Options)) (*resourceexplorer2.ListIndexesOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*resourceexplorer2.ListIndexesOutput), args.Error(1)
+}
+
+func newExplorerResource(arn, region string) types.Resource {
+	return types.Resource{
+		Arn:             aws.String(arn),
+		ResourceType:    aws.String("ec2:instance"),
+		Service:         aws.String("ec2"),
+		OwningAccountId: aws.String("123456789012"),
+		Region:          aws.String(region),
+	}
+}
+
+func TestRegionOptions(t *testing.T) {
+	cfg := &providerConfig{}
+	WithAllRegions()(cfg)
+	assert.True(t, cfg.allRegions)
+
+	_, err := NewProvider(context.Background(), WithBackend(BackendTagging), WithAllRegions())
+	assert.ErrorContains(t, err, "searching all regions is only supported by the resource-explorer backend")
+
+	_, err = NewProvider(context.Background(), WithRegions("us-east-1"), WithViewARN("arn:aws:resource-explorer-2:us-east-1:123456789012:view/test-view/1234567890"))
+	assert.ErrorContains(t, err, "a view ARN cannot be used to search multiple regions")
+}
+
+func TestListIndexRegions(t *testing.T) {
+	ctx := context.Background()
+	client := new(MockIndexLister)
+
+	client.On("ListIndexes", ctx, &resourceexplorer2.ListIndexesInput{}).Return(&resourceexplorer2.ListIndexesOutput{
+		Indexes:   []types.Index{{Region: aws.String("us-east-1"), Type: types.IndexTypeAggregator}},
+		NextToken: aws.String("next"),
+	}, nil)
+	client.On("ListIndexes", ctx, &resourceexplorer2.ListIndexesInput{NextToken: aws.String("next")}).Return((*resourceexplorer2.ListIndexesOutput)(nil), &types.ThrottlingException{Message: aws.String("Rate exceeded")}).Once()
+	client.On("ListIndexes", ctx, &resourceexplorer2.ListIndexesInput{NextToken: aws.String("next")}).Return(&resourceexplorer2.ListIndexesOutput{
+		Indexes: []types.Index{{Region: aws.String("eu-west-1"), Type: types.IndexTypeLocal}},
+	}, nil)
+
+	// throttled calls are retried
+	regions, err := listIndexRegions(ctx, newTestRetryer(1), client)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1", "eu-west-1"}, regions)
+	client.AssertNumberOfCalls(t, "ListIndexes", 3)
+
+	empty := new(MockIndexLister)
+	empty.On("ListIndexes", ctx, mock.Anything).Return(&resourceexplorer2.ListIndexesOutput{}, nil)
+
+	_, err = listIndexRegions(ctx, newTestRetryer(1), empty)
+	assert.ErrorContains(t, err, "no Resource Explorer index found in any region")
+}
+
+func TestFindRegionalResources(t *testing.T) {
+	t.Run("Deduplicates By ARN", func(t *testing.T) {
+		ctx := context.Background()
+		aggregator, local := new(MockResourceExplorerClient), new(MockResourceExplorerClient)
+
+		provider := &Provider{explorerClients: map[string]ResourceExplorerClient{
+			"us-east-1": aggregator,
+			"eu-west-1": local,
+		}}
+
+		aggregator.On("Search", ctx, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{
+				newExplorerResource("arn:aws:ec2:us-east-1:123456789012:instance/i-1", "us-east-1"),
+				newExplorerResource("arn:aws:ec2:eu-west-1:123456789012:instance/i-2", "eu-west-1"),
+			},
+		}, nil)
+		local.On("Search", ctx, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{
+				newExplorerResource("arn:aws:ec2:eu-west-1:123456789012:instance/i-2", "eu-west-1"),
+				newExplorerResource("arn:aws:ec2:eu-west-1:123456789012:instance/i-3", "eu-west-1"),
+			},
+		}, nil)
+
+		resources, err := provider.FindResources(ctx, "ec2", "instance")
+		require.NoError(t, err)
+
+		var arns []string
+		for _, resource := range resources {
+			arns = append(arns, resource.ID())
+		}
+		assert.Equal(t, []string{
+			"arn:aws:ec2:eu-west-1:123456789012:instance/i-2",
+			"arn:aws:ec2:eu-west-1:123456789012:instance/i-3",
+			"arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+		}, arns)
+	})
+
+	t.Run("Discovered Regions", func(t *testing.T) {
+		ctx := context.Background()
+		client := new(MockResourceExplorerClient)
+		discoveries := 0
+
+		provider := &Provider{discoverRegions: func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			discoveries++
+			return map[string]RegionalExplorerClient{"ap-south-1": client}, nil
+		}}
+
+		client.On("GetDefaultView", ctx, mock.Anything).Return(&resourceexplorer2.GetDefaultViewOutput{ViewArn: aws.String("arn:aws:resource-explorer-2:ap-south-1:123456789012:view/default/1")}, nil).Once()
+
+		client.On("Search", ctx, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{newExplorerResource("arn:aws:ec2:ap-south-1:123456789012:instance/i-1", "ap-south-1")},
+		}, nil)
+
+		for range 2 {
+			resources, err := provider.FindResources(ctx, "ec2", "instance")
+			require.NoError(t, err)
+			assert.Len(t, resources, 1)
+		}
+		assert.Equal(t, 1, discoveries)
+	})
+
+	t.Run("Region Error", func(t *testing.T) {
+		ctx := context.Background()
+		client := new(MockResourceExplorerClient)
+		provider := &Provider{explorerClients: map[string]ResourceExplorerClient{"eu-west-1": client}}
+
+		client.On("Search", ctx, mock.Anything).Return(&resourceexplorer2.SearchOutput{}, errors.New("UnauthorizedException"))
+
+		_, err := provider.FindResources(ctx, "ec2", "instance")
+		assert.EqualError(t, err, "error searching eu-west-1: UnauthorizedException")
+	})
+
+	t.Run("Discovery Error", func(t *testing.T) {
+		client := new(MockResourceExplorerClient)
+		discoveries := 0
+
+		provider := &Provider{discoverRegions: func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			discoveries++
+			if discoveries == 1 {
+				return nil, context.DeadlineExceeded
+			}
+			return map[string]RegionalExplorerClient{"ap-south-1": client}, nil
+		}}
+
+		client.On("GetDefaultView", mock.Anything, mock.Anything).Return(&resourceexplorer2.GetDefaultViewOutput{ViewArn: aws.String("arn:aws:resource-explorer-2:ap-south-1:123456789012:view/default/1")}, nil)
+
+		client.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{newExplorerResource("arn:aws:ec2:ap-south-1:123456789012:instance/i-1", "ap-south-1")},
+		}, nil)
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// a failed discovery runs again for the next definition
+		resources, err := provider.FindResources(context.Background(), "s3", "bucket")
+		require.NoError(t, err)
+		assert.Len(t, resources, 1)
+		assert.Equal(t, 2, discoveries)
+	})
+
+	t.Run("Regions Without Default View", func(t *testing.T) {
+		ctx := context.Background()
+		withView, withoutView, notFound := new(MockResourceExplorerClient), new(MockResourceExplorerClient), new(MockResourceExplorerClient)
+
+		provider := &Provider{
+			stats: make(map[string]*searchStats),
+			discoverRegions: func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+				return map[string]RegionalExplorerClient{"us-east-1": withView, "eu-west-1": withoutView, "ap-south-1": notFound}, nil
+			},
+		}
+
+		withView.On("GetDefaultView", mock.Anything, mock.Anything).Return(&resourceexplorer2.GetDefaultViewOutput{ViewArn: aws.String("arn:aws:resource-explorer-2:us-east-1:123456789012:view/default/1")}, nil).Once()
+		withoutView.On("GetDefaultView", mock.Anything, mock.Anything).Return(&resourceexplorer2.GetDefaultViewOutput{}, nil).Once()
+		notFound.On("GetDefaultView", mock.Anything, mock.Anything).Return((*resourceexplorer2.GetDefaultViewOutput)(nil), &types.ResourceNotFoundException{Message: aws.String("no default view")}).Once()
+		withView.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+			Resources: []types.Resource{newExplorerResource("arn:aws:ec2:us-east-1:123456789012:instance/i-1", "us-east-1")},
+		}, nil)
+
+		for range 2 {
+			resources, err := provider.FindResources(ctx, "ec2", "instance")
+			require.NoError(t, err)
+			assert.Len(t, resources, 1)
+
+			warnings := provider.Warnings("ec2", "instance")
+			require.Len(t, warnings, 2)
+			assert.Contains(t, warnings[0], "Region ap-south-1 has no default Resource Explorer view")
+			assert.Contains(t, warnings[1], "Region eu-west-1 has no default Resource Explorer view")
+		}
+		withoutView.AssertNotCalled(t, "Search", mock.Anything, mock.Anything)
+		notFound.AssertNotCalled(t, "Search", mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Default View In Any Region", func(t *testing.T) {
+		client := new(MockResourceExplorerClient)
+		provider := &Provider{discoverRegions: func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			return map[string]RegionalExplorerClient{"eu-west-1": client}, nil
+		}}
+
+		client.On("GetDefaultView", mock.Anything, mock.Anything).Return(&resourceexplorer2.GetDefaultViewOutput{}, nil)
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		assert.EqualError(t, err, "no default Resource Explorer view found in any region")
+	})
+
+	t.Run("Default View Error", func(t *testing.T) {
+		client := new(MockResourceExplorerClient)
+		provider := &Provider{discoverRegions: func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			return map[string]RegionalExplorerClient{"eu-west-1": client}, nil
+		}}
+
+		client.On("GetDefaultView", mock.Anything, mock.Anything).Return((*resourceexplorer2.GetDefaultViewOutput)(nil), errors.New("AccessDeniedException"))
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		assert.EqualError(t, err, "error getting the default Resource Explorer view of eu-west-1: AccessDeniedException")
+	})
+}
+
+func newTestRetryer(maxRetries int) *retryer {
+	return &retryer{
+		maxRetries: maxRetries,
+		limiter:    newRateLimiter(1000),
+		sleep:      func(ctx context.Context, d time.Duration) error { return nil },
+	}
+}
+
+func TestRetryer(t *testing.T) {
+	throttled := &types.ThrottlingException{Message: aws.String("Rate exceeded")}
+
+	t.Run("Retryable Errors", func(t *testing.T) {
+		for _, err := range []error{
+			throttled,
+			&apiError{StatusCode: 400, Code: "ThrottledException"},
+			&apiError{StatusCode: 429, Code: "Too Many Requests"},
+			&apiError{StatusCode: 503, Code: "ServiceUnavailable"},
+		} {
+			assert.True(t, isRetryable(err), err.Error())
+		}
+
+		assert.False(t, isRetryable(&apiError{StatusCode: 403, Code: "AccessDeniedException"}))
+		assert.False(t, isRetryable(errors.New("invalid query")))
+		assert.False(t, isThrottle(&apiError{StatusCode: 503, Code: "ServiceUnavailable"}))
+	})
+
+	t.Run("Retries Until Success", func(t *testing.T) {
+		ctx, stats := withSearchStats(context.Background())
+		calls := 0
+		err := newTestRetryer(3).do(ctx, func() error {
+			calls++
+			if calls < 3 {
+				return throttled
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 2, stats.retries)
+	})
+
+	t.Run("Gives Up After Max Retries", func(t *testing.T) {
+		calls := 0
+		err := newTestRetryer(2).do(context.Background(), func() error {
+			calls++
+			return throttled
+		})
+
+		assert.ErrorIs(t, err, throttled)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Does Not Retry Client Errors", func(t *testing.T) {
+		calls := 0
+		err := newTestRetryer(2).do(context.Background(), func() error {
+			calls++
+			return &apiError{StatusCode: 403, Code: "AccessDeniedException"}
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Backoff", func(t *testing.T) {
+		for attempt := range 20 {
+			delay := backoff(attempt)
+			assert.Positive(t, delay)
+			assert.LessOrEqual(t, delay, retryMaxDelay)
+		}
+		assert.LessOrEqual(t, backoff(0), retryBaseDelay)
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(10)
+
+	limiter.throttled()
+	limiter.throttled()
+	assert.Equal(t, 2.5, limiter.rate)
+
+	for range 100 {
+		limiter.throttled()
+	}
+	assert.Equal(t, minRateLimit, limiter.rate)
+
+	for range 100 {
+		limiter.succeeded()
+	}
+	assert.Equal(t, 10.0, limiter.rate)
+
+	// waiting for the next call honors the context
+	slow := newRateLimiter(0.01)
+	require.NoError(t, slow.wait(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, slow.wait(ctx), context.Canceled)
+
+	assert.Nil(t, newRateLimiter(0))
+	assert.NoError(t, newRateLimiter(0).wait(ctx))
+}
+
+func TestProviderRetries(t *testing.T) {
+	mockClient := new(MockResourceExplorerClient)
+	provider := &Provider{client: mockClient, retryer: newTestRetryer(3), stats: make(map[string]*searchStats)}
+
+	mockClient.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{}, &types.ThrottlingException{Message: aws.String("Rate exceeded")}).Twice()
+	mockClient.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+		Resources: []types.Resource{newExplorerResource("arn:aws:ec2:us-east-1:123456789012:instance/i-1", "us-east-1")},
+	}, nil).Once()
+
+	resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+	require.NoError(t, err)
+	assert.Len(t, resources, 1)
+	assert.Equal(t, 2, provider.Retries("ec2", "instance"))
+	assert.Equal(t, 0, provider.Retries("s3", "bucket"))
+
+	_, err = NewProvider(context.Background(), WithMaxRetries(-1))
+	assert.ErrorContains(t, err, "max retries must not be negative")
+
+	_, err = NewProvider(context.Background(), WithRateLimit(-1))
+	assert.ErrorContains(t, err, "rate limit must not be negative")
+}
+
+func newFakeExplorerProvider(t *testing.T, explorer *fake.ResourceExplorer, opts ...Option) *Provider {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	server := httptest.NewServer(explorer)
+	t.Cleanup(server.Close)
+
+	provider, err := NewProvider(context.Background(), append([]Option{WithRegion("us-east-1"), WithEndpoint(server.URL)}, opts...)...)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestFakeResourceExplorer(t *testing.T) {
+	instance := func(id string, tags map[string]string) *fake.Resource {
+		return &fake.Resource{
+			ARN:     "arn:aws:ec2:us-east-1:123456789012:instance/" + id,
+			Type:    "ec2:instance",
+			Service: "ec2",
+			Account: "123456789012",
+			Region:  "us-east-1",
+			Tags:    tags,
+		}
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{
+			instance("i-1", map[string]string{"env": "prod"}),
+			instance("i-2", nil),
+			instance("i-3", nil),
+			instance("i-4", nil),
+			instance("i-5", map[string]string{"owner": "team-a"}),
+			{ARN: "arn:aws:s3:::bucket-1", Type: "s3:bucket", Service: "s3", Account: "123456789012", Region: "us-east-1"},
+		}}
+		provider := newFakeExplorerProvider(t, explorer)
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 5)
+		assert.Equal(t, map[string]string{"env": "prod"}, resources[0].Tags())
+		assert.Equal(t, map[string]string{"owner": "team-a"}, resources[4].Tags())
+		assert.Equal(t, 3, explorer.Requests())
+	})
+
+	t.Run("Throttling", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{instance("i-1", nil)}, Throttle: 1}
+		provider := newFakeExplorerProvider(t, explorer)
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Len(t, resources, 1)
+		assert.Equal(t, 2, explorer.Requests())
+		assert.Equal(t, 1, provider.Retries("ec2", "instance"))
+	})
+
+	t.Run("Malformed Tags Document", func(t *testing.T) {
+		malformed := instance("i-1", nil)
+		malformed.RawTags = json.RawMessage(`{"env": "prod"}`)
+		explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{malformed, instance("i-2", map[string]string{"env": "dev"})}}
+		provider := newFakeExplorerProvider(t, explorer)
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Empty(t, resources[0].Tags())
+		assert.Equal(t, map[string]string{"env": "dev"}, resources[1].Tags())
+
+		warnings := provider.Warnings("ec2", "instance")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "The tags of arn:aws:ec2:us-east-1:123456789012:instance/i-1 could not be decoded")
+	})
+
+	t.Run("All Regions", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{
+			Indexes:          []string{"us-east-1", "eu-west-1"},
+			AggregatorRegion: "us-east-1",
+			Resources: []*fake.Resource{
+				{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Account: "123456789012", Region: "us-east-1"},
+				{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-2", Type: "ec2:instance", Account: "123456789012", Region: "eu-west-1"},
+			},
+		}
+		provider := newFakeExplorerProvider(t, explorer, WithAllRegions())
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Len(t, resources, 2)
+	})
+
+	t.Run("Region Without Default View", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{
+			Indexes:       []string{"us-east-1", "eu-west-1"},
+			NoDefaultView: []string{"eu-west-1"},
+			Resources: []*fake.Resource{
+				{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Account: "123456789012", Region: "us-east-1"},
+				{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-2", Type: "ec2:instance", Account: "123456789012", Region: "eu-west-1"},
+			},
+		}
+		provider := newFakeExplorerProvider(t, explorer, WithAllRegions())
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "us-east-1", resources[0].Region())
+
+		warnings := provider.Warnings("ec2", "instance")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "Region eu-west-1 has no default Resource Explorer view")
+	})
+
+	t.Run("Tagging Backend", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{
+			{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Account: "123456789012", Region: "us-east-1", Tags: map[string]string{"env": "prod"}},
+			{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-2", Type: "ec2:instance", Account: "123456789012", Region: "us-east-1"},
+			{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-3", Type: "ec2:instance", Account: "123456789012", Region: "eu-west-1", Tags: map[string]string{"env": "dev"}},
+		}}
+		provider := newFakeExplorerProvider(t, explorer, WithBackend(BackendTagging), WithRegions("us-east-1", "eu-west-1"))
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Equal(t, "eu-west-1", resources[0].Region())
+		assert.Equal(t, "us-east-1", resources[1].Region())
+		assert.Len(t, provider.Warnings("ec2", "instance"), 1)
+	})
+
+	t.Run("Organization Accounts", func(t *testing.T) {
+		explorer := &fake.ResourceExplorer{
+			Accounts:       []string{"111111111111", "222222222222", "333333333333"},
+			DeniedAccounts: []string{"333333333333"},
+			Resources: []*fake.Resource{
+				{ARN: "arn:aws:ec2:us-east-1:111111111111:instance/i-1", Type: "ec2:instance", Account: "111111111111", Region: "us-east-1"},
+				{ARN: "arn:aws:ec2:us-east-1:222222222222:instance/i-2", Type: "ec2:instance", Account: "222222222222", Region: "us-east-1"},
+				{ARN: "arn:aws:ec2:us-east-1:333333333333:instance/i-3", Type: "ec2:instance", Account: "333333333333", Region: "us-east-1"},
+			},
+		}
+		provider := newFakeExplorerProvider(t, explorer, WithOrganizationAccounts(), WithRoleName("TagPatrol"), WithMaxRetries(0))
+
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Equal(t, "111111111111", resources[0].OwnerID())
+		assert.Equal(t, "222222222222", resources[1].OwnerID())
+
+		warnings := provider.Warnings("ec2", "instance")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "error searching account 333333333333")
+		assert.Contains(t, warnings[0], "AccessDenied")
+	})
+
+	t.Run("Custom HTTP Client", func(t *testing.T) {
+		requests := 0
+		client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return http.DefaultTransport.RoundTrip(r)
+		})}
+
+		explorer := &fake.ResourceExplorer{Resources: []*fake.Resource{instance("i-1", nil)}}
+		provider := newFakeExplorerProvider(t, explorer, WithHTTPClient(client))
+
+		_, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+	})
+}
+
+func TestSearchIncompleteResources(t *testing.T) {
+	client := new(MockResourceExplorerClient)
+	provider := &Provider{client: client, stats: make(map[string]*searchStats)}
+
+	client.On("Search", mock.Anything, mock.Anything).Return(&resourceexplorer2.SearchOutput{
+		Resources: []types.Resource{
+			{Arn: aws.String("arn:aws:ec2:us-east-1:123456789012:instance/i-1"), Properties: []types.ResourceProperty{{}}},
+			{ResourceType: aws.String("ec2:instance")},
+		},
+	}, nil)
+
+	// missing fields don't panic, resources without an ARN are skipped
+	resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-1", resources[0].ID())
+	assert.Empty(t, resources[0].OwnerID())
+	assert.Empty(t, resources[0].Tags())
+
+	warnings := provider.Warnings("ec2", "instance")
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "a resource without an ARN")
+}
+
+func TestPartitionTruncatedSearch(t *testing.T) {
+	resource := func(region, account string, i int) *fake.Resource {
+		return &fake.Resource{
+			ARN:     fmt.Sprintf("arn:aws:ec2:%s:%s:instance/i-%d", region, account, i),
+			Type:    "ec2:instance",
+			Service: "ec2",
+			Account: account,
+			Region:  region,
+		}
+	}
+
+	t.Run("Complete", func(t *testing.T) {
+		var resources []*fake.Resource
+		for i, region := range []string{"us-east-1", "us-east-1", "us-east-1", "us-east-1", "eu-west-1", "ap-southeast-2", "ap-southeast-2"} {
+			account := []string{"111111111111", "222222222222"}[i%2]
+			resources = append(resources, resource(region, account, i))
+		}
+
+		explorer := &fake.ResourceExplorer{Resources: resources, MaxResults: 3}
+		provider := newFakeExplorerProvider(t, explorer, WithRateLimit(0))
+
+		found, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Len(t, found, len(resources))
+		assert.Empty(t, provider.Warnings("ec2", "instance"))
+	})
+
+	t.Run("Single Account And Region", func(t *testing.T) {
+		// more than 1,000 resources in one account and region are split by tag key and value
+		var resources []*fake.Resource
+		for i := range 2500 {
+			resource := resource("us-east-1", "111111111111", i)
+			switch {
+			case i%10 == 0:
+				// untagged
+			case i%2 == 0:
+				resource.Tags = map[string]string{"team": fmt.Sprintf("team-%d", i%4), "env": "prod"}
+			default:
+				resource.Tags = map[string]string{"env": fmt.Sprintf("env-%d", i%7)}
+			}
+			resources = append(resources, resource)
+		}
+
+		explorer := &fake.ResourceExplorer{Resources: resources, PageSize: 1000}
+		provider := newFakeExplorerProvider(t, explorer, WithRateLimit(0))
+
+		found, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Len(t, found, len(resources))
+		assert.Empty(t, provider.Warnings("ec2", "instance"))
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		var resources []*fake.Resource
+		for i := range 5 {
+			resources = append(resources, resource("us-east-1", "111111111111", i))
+		}
+
+		explorer := &fake.ResourceExplorer{Resources: resources, MaxResults: 3}
+		provider := newFakeExplorerProvider(t, explorer, WithRateLimit(0))
+
+		found, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		assert.Len(t, found, 3)
+
+		warnings := provider.Warnings("ec2", "instance")
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "resourcetype:ec2:instance region:us-east-1 accountid:111111111111")
+		assert.Contains(t, warnings[0], "some resources may be missing")
+	})
+}
+
+func TestPartitionTerms(t *testing.T) {
+	resources := []*AWSResource{
+		{ResourceARN: "a", ResourceRegion: "us-east-1", ResourceTags: map[string]string{"env": "prod", "team": "a"}},
+		{ResourceARN: "b", ResourceRegion: "eu-west-1", ResourceTags: map[string]string{"env": "dev", "Cost Center": "1"}},
+		{ResourceARN: "c", ResourceRegion: "us-east-1", ResourceTags: map[string]string{"env": "prod", "note": "has spaces"}},
+		{ResourceARN: "d", ResourceRegion: ""},
+		{ResourceARN: "e", ResourceRegion: "ap-southeast-2"},
+	}
+
+	assert.Equal(t, []string{"region:eu-west-1", "region:us-east-1"}, partitionTerms(resources, partitionFilters[0], []string{"region:ap-southeast-2"}))
+	assert.Equal(t, []string{"tag.key:env", "tag.key:note", "tag.key:team", "tag:none"}, partitionTerms(resources, partitionFilters[2], nil))
+	assert.Equal(t, []string{"tag:env=dev", "tag:team=a"}, partitionTerms(resources, partitionFilters[3], []string{"tag:env=prod"}))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -3,6 +3,9 @@ package aws
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"slices"
+	"sync"
 
 	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -11,16 +14,54 @@ import (
 	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
 )
 
+// Backend selects the AWS API used to search for resources
+type Backend string
+
+const (
+	// BackendResourceExplorer searches with Resource Explorer, which must be enabled
+	BackendResourceExplorer Backend = "resource-explorer"
+	// BackendTagging searches with the Resource Groups Tagging API, region by region. Only
+	// resources that have, or once had, tags are returned.
+	BackendTagging Backend = "tagging"
+)
+
 // Provider implements the CloudResource Finder interface for AWS
 type Provider struct {
-	client  ResourceExplorerClient
-	viewARN string
+	client         ResourceExplorerClient
+	viewARN        string
+	backend        Backend
+	taggingClients map[string]TaggingClient
+	accountID      string
+	accounts       []*Provider
+
+	// retryer is shared by the providers of every member account
+	retryer *retryer
+
+	// stats of the last search of every resource type, only collected by the top level provider
+	statsMu sync.Mutex
+	stats   map[string]*searchStats
+
+	// regional Resource Explorer clients of the regions with a default view, resolved on first use
+	regionsMu       sync.Mutex
+	explorerClients map[string]ResourceExplorerClient
+	viewlessRegions []string
+	discoverRegions func(ctx context.Context) (map[string]RegionalExplorerClient, error)
 }
 
 type providerConfig struct {
-	profile string
-	region  string
-	viewARN string
+	profile              string
+	region               string
+	viewARN              string
+	backend              Backend
+	regions              []string
+	allRegions           bool
+	accounts             []string
+	organizationAccounts bool
+	roleName             string
+	endpoint             string
+	httpClient           *http.Client
+	maxRetries           int
+	rateLimit            float64
 }
 
 // Option is a function that configures the AWS provider
@@ -47,6 +88,83 @@ func WithViewARN(arn string) Option {
 	}
 }
 
+// WithBackend sets the API used to search for resources, BackendResourceExplorer by default
+func WithBackend(backend Backend) Option {
+	return func(c *providerConfig) {
+		c.backend = backend
+	}
+}
+
+// WithRegions sets the regions to search, each with its own client, and merges the results.
+// Without regions, Resource Explorer is searched in the configured region only, which
+// only finds resources of other regions when it holds the aggregator index.
+func WithRegions(regions ...string) Option {
+	return func(c *providerConfig) {
+		c.regions = append(c.regions, regions...)
+	}
+}
+
+// WithAllRegions searches every region with a Resource Explorer index
+func WithAllRegions() Option {
+	return func(c *providerConfig) {
+		c.allRegions = true
+	}
+}
+
+// WithAccounts searches the given accounts by assuming the role set with WithRoleName in each of them
+func WithAccounts(accounts ...string) Option {
+	return func(c *providerConfig) {
+		c.accounts = append(c.accounts, accounts...)
+	}
+}
+
+// WithOrganizationAccounts searches every active account of the AWS Organization, listed
+// with the configured credentials, by assuming the role set with WithRoleName in each of them
+func WithOrganizationAccounts() Option {
+	return func(c *providerConfig) {
+		c.organizationAccounts = true
+	}
+}
+
+// WithRoleName sets the name of the role assumed in every searched account
+func WithRoleName(name string) Option {
+	return func(c *providerConfig) {
+		c.roleName = name
+	}
+}
+
+// WithEndpoint sets the endpoint URL of every AWS API the provider calls, e.g. LocalStack
+// or a fake Resource Explorer server
+func WithEndpoint(endpoint string) Option {
+	return func(c *providerConfig) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for AWS API calls
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *providerConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithMaxRetries sets the number of times a throttled or failed API call is retried,
+// DefaultMaxRetries by default
+func WithMaxRetries(retries int) Option {
+	return func(c *providerConfig) {
+		c.maxRetries = retries
+	}
+}
+
+// WithRateLimit sets the number of API calls per second shared by all the searches of the
+// provider, DefaultRateLimit by default. The rate is lowered while calls are throttled, a
+// limit of zero disables rate limiting.
+func WithRateLimit(callsPerSecond float64) Option {
+	return func(c *providerConfig) {
+		c.rateLimit = callsPerSecond
+	}
+}
+
 // ResourceExplorerClient defines the interface for AWS Resource Explorer API interactions
 type ResourceExplorerClient interface {
 	Search(ctx context.Context, params *resourceexplorer2.SearchInput, optFns ...func(*resourceexplorer2.Options)) (*resourceexplorer2.SearchOutput, error)
@@ -54,12 +172,48 @@ type ResourceExplorerClient interface {
 
 // NewProvider creates a new AWS provider with the specified options
 func NewProvider(ctx context.Context, opts ...Option) (*Provider, error) {
-	cfg := &providerConfig{}
+	cfg := &providerConfig{
+		backend:    BackendResourceExplorer,
+		maxRetries: DefaultMaxRetries,
+		rateLimit:  DefaultRateLimit,
+	}
 
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
+	switch cfg.backend {
+	case BackendResourceExplorer:
+	case BackendTagging:
+		if cfg.viewARN != "" {
+			return nil, fmt.Errorf("a view ARN is only supported by the %s backend", BackendResourceExplorer)
+		}
+		if cfg.allRegions {
+			return nil, fmt.Errorf("searching all regions is only supported by the %s backend", BackendResourceExplorer)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported backend `%s`, must be one of: %s, %s", cfg.backend, BackendResourceExplorer, BackendTagging)
+	}
+
+	if cfg.viewARN != "" && (len(cfg.regions) > 0 || cfg.allRegions) {
+		return nil, fmt.Errorf("a view ARN cannot be used to search multiple regions")
+	}
+
+	if cfg.maxRetries < 0 {
+		return nil, fmt.Errorf("max retries must not be negative")
+	}
+	if cfg.rateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must not be negative")
+	}
+
+	multiAccount := len(cfg.accounts) > 0 || cfg.organizationAccounts
+	if multiAccount && cfg.roleName == "" {
+		return nil, fmt.Errorf("a role name is required to search multiple accounts")
+	}
+	if multiAccount && cfg.viewARN != "" {
+		return nil, fmt.Errorf("a view ARN cannot be used to search multiple accounts")
+	}
+
 	var awsLoadOpts []func(*config.LoadOptions) error
 	if cfg.profile != "" {
 		awsLoadOpts = append(awsLoadOpts, config.WithSharedConfigProfile(cfg.profile))
@@ -73,46 +227,241 @@ func NewProvider(ctx context.Context, opts ...Option) (*Provider, error) {
 		return nil, fmt.Errorf("error loading AWS config: %w", err)
 	}
 
+	if cfg.endpoint != "" {
+		awsCfg.BaseEndpoint = awssdk.String(cfg.endpoint)
+	}
+	if cfg.httpClient != nil {
+		awsCfg.HTTPClient = cfg.httpClient
+	}
+
+	retryer := newRetryer(cfg.maxRetries, cfg.rateLimit)
+
+	if !multiAccount {
+		provider, err := newProvider(awsCfg, cfg, retryer)
+		if err != nil {
+			return nil, err
+		}
+		provider.stats = make(map[string]*searchStats)
+		return provider, nil
+	}
+
+	accounts := slices.Clone(cfg.accounts)
+	if cfg.organizationAccounts {
+		organizationAccounts, err := listOrganizationAccounts(ctx, retryer, newOrganizationsClient(awsCfg))
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, organizationAccounts...)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no accounts to search")
+	}
+
+	members, err := newAccountProviders(awsCfg, cfg, accounts, retryer)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Provider{
-		client:  resourceexplorer2.NewFromConfig(awsCfg),
-		viewARN: cfg.viewARN,
+		backend:  cfg.backend,
+		accounts: members,
+		retryer:  retryer,
+		stats:    make(map[string]*searchStats),
 	}, nil
 }
 
+// newProvider creates a provider searching the account of the given AWS config
+func newProvider(awsCfg awssdk.Config, cfg *providerConfig, retryer *retryer) (*Provider, error) {
+	if cfg.backend == BackendTagging {
+		regions := cfg.regions
+		if len(regions) == 0 {
+			if awsCfg.Region == "" {
+				return nil, fmt.Errorf("no region configured for the %s backend", BackendTagging)
+			}
+			regions = []string{awsCfg.Region}
+		}
+
+		clients := make(map[string]TaggingClient, len(regions))
+		for _, region := range regions {
+			clients[region] = newTaggingClient(awsCfg, region)
+		}
+
+		return &Provider{backend: BackendTagging, taggingClients: clients, retryer: retryer}, nil
+	}
+
+	client := resourceexplorer2.NewFromConfig(awsCfg)
+	provider := &Provider{
+		client:  client,
+		viewARN: cfg.viewARN,
+		backend: BackendResourceExplorer,
+		retryer: retryer,
+	}
+
+	newRegionalClient := func(region string) RegionalExplorerClient {
+		return resourceexplorer2.NewFromConfig(awsCfg, func(o *resourceexplorer2.Options) {
+			o.Region = region
+		})
+	}
+
+	switch {
+	case cfg.allRegions:
+		provider.discoverRegions = func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			regions, err := listIndexRegions(ctx, retryer, client)
+			if err != nil {
+				return nil, err
+			}
+			return newRegionalClients(regions, newRegionalClient), nil
+		}
+	case len(cfg.regions) > 0:
+		provider.discoverRegions = func(ctx context.Context) (map[string]RegionalExplorerClient, error) {
+			return newRegionalClients(cfg.regions, newRegionalClient), nil
+		}
+	}
+
+	return provider, nil
+}
+
 // FindResources searches for AWS resources of the specified service and resource type
 func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	if p.stats == nil {
+		return p.findResources(ctx, serviceName, resourceName)
+	}
+
+	ctx, stats := withSearchStats(ctx)
+	resources, err := p.findResources(ctx, serviceName, resourceName)
+	if err == nil && p.backend == BackendTagging {
+		recordWarning(ctx, "The Resource Groups Tagging API only returns resources that have, or once had, tags, %s:%s resources that were never tagged are not checked", serviceName, resourceName)
+	}
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	p.stats[fmt.Sprintf("%s:%s", serviceName, resourceName)] = stats
+
+	return resources, err
+}
+
+// Retries returns the number of API calls retried by the last search of a resource type,
+// implementing the patrol.RetryCounter interface
+func (p *Provider) Retries(serviceName, resourceName string) int {
+	stats := p.searchStats(serviceName, resourceName)
+	if stats == nil {
+		return 0
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return stats.retries
+}
+
+// Warnings returns the warnings of the last search of a resource type, e.g. when resources
+// may be missing, implementing the patrol.WarningReporter interface
+func (p *Provider) Warnings(serviceName, resourceName string) []string {
+	stats := p.searchStats(serviceName, resourceName)
+	if stats == nil {
+		return nil
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return slices.Clone(stats.warnings)
+}
+
+func (p *Provider) searchStats(serviceName, resourceName string) *searchStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.stats[fmt.Sprintf("%s:%s", serviceName, resourceName)]
+}
+
+func (p *Provider) findResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	if len(p.accounts) > 0 {
+		return p.findAccountResources(ctx, serviceName, resourceName)
+	}
+
+	if p.backend == BackendTagging {
+		return p.findTaggedResources(ctx, serviceName, resourceName)
+	}
+
+	clients, viewless, err := p.regionalClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range viewless {
+		recordWarning(ctx, "Region %s has no default Resource Explorer view and is not searched, its resources are missing unless a region holding the aggregator index returns them", region)
+	}
+	if len(clients) > 0 {
+		return p.findRegionalResources(ctx, clients, serviceName, resourceName)
+	}
+
+	return p.search(ctx, p.client, serviceName, resourceName)
+}
+
+// search runs a Resource Explorer search of a resource type with the given client. Searches
+// truncated by Resource Explorer are partitioned, see searchPartitioned.
+func (p *Provider) search(ctx context.Context, client ResourceExplorerClient, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	found, err := p.searchPartitioned(ctx, client, fmt.Sprintf("resourcetype:%s:%s", serviceName, resourceName), 0)
+	if err != nil {
+		return nil, err
+	}
+
 	var resources []cr.CloudResource
+	for _, resource := range found {
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// searchQuery runs a paginated Resource Explorer search of a query. It reports whether the
+// results are complete, Resource Explorer returns at most 1,000 results per query.
+func (p *Provider) searchQuery(ctx context.Context, client ResourceExplorerClient, query string) ([]*AWSResource, bool, error) {
+	var resources []*AWSResource
 	var nextToken, view *string
+	complete := true
 
 	if p.viewARN != "" {
 		view = awssdk.String(p.viewARN)
 	}
 
 	for {
-		resp, err := p.client.Search(ctx, &resourceexplorer2.SearchInput{
-			QueryString: awssdk.String(fmt.Sprintf("resourcetype:%s:%s", serviceName, resourceName)),
+		input := &resourceexplorer2.SearchInput{
+			QueryString: awssdk.String(query),
 			ViewArn:     view,
 			NextToken:   nextToken,
+		}
+
+		var resp *resourceexplorer2.SearchOutput
+		err := p.retryer.do(ctx, func() (err error) {
+			resp, err = client.Search(ctx, input, withoutSDKRetries)
+			return err
 		})
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+
+		if resp.Count != nil && resp.Count.Complete != nil && !*resp.Count.Complete {
+			complete = false
 		}
 
 		for _, r := range resp.Resources {
+			if awssdk.ToString(r.Arn) == "" {
+				recordWarning(ctx, "Resource Explorer returned a resource without an ARN for `%s`, it was skipped", query)
+				continue
+			}
+
 			awsResource := &AWSResource{
-				ResourceARN:    *r.Arn,
-				ResourceType:   *r.ResourceType,
-				ServiceName:    *r.Service,
-				AccountID:      *r.OwningAccountId,
-				ResourceRegion: *r.Region,
+				ResourceARN:    awssdk.ToString(r.Arn),
+				ResourceType:   awssdk.ToString(r.ResourceType),
+				ServiceName:    awssdk.ToString(r.Service),
+				AccountID:      awssdk.ToString(r.OwningAccountId),
+				ResourceRegion: awssdk.ToString(r.Region),
 				ResourceTags:   make(map[string]string),
 			}
 
 			for _, prop := range r.Properties {
-				if *prop.Name != "tags" {
+				if awssdk.ToString(prop.Name) != "tags" || prop.Data == nil {
 					continue
 				}
-				awsResource.ResourceTags = p.unmarshalTags(prop.Data)
+				awsResource.ResourceTags = unmarshalTags(ctx, awsResource.ResourceARN, prop.Data)
 			}
 
 			resources = append(resources, awsResource)
@@ -125,10 +474,17 @@ func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName
 		}
 	}
 
-	return resources, nil
+	return resources, complete, nil
 }
 
-func (p *Provider) unmarshalTags(d document.Interface) map[string]string {
+// withoutSDKRetries disables the retries of the SDK, the provider retryer retries searches
+func withoutSDKRetries(o *resourceexplorer2.Options) {
+	o.Retryer = awssdk.NopRetryer{}
+}
+
+// unmarshalTags decodes the tags property of a resource. A document that can't be decoded
+// is reported as a warning of the search and the resource as having no tags.
+func unmarshalTags(ctx context.Context, arn string, d document.Interface) map[string]string {
 	type Tag struct {
 		Key   string `json:"Key"`
 		Value string `json:"Value"`
@@ -137,8 +493,13 @@ func (p *Provider) unmarshalTags(d document.Interface) map[string]string {
 	var tags []*Tag
 	var tagMap = make(map[string]string)
 
-	if err := d.UnmarshalSmithyDocument(&tags); err == nil {
-		for _, tag := range tags {
+	if err := d.UnmarshalSmithyDocument(&tags); err != nil {
+		recordWarning(ctx, "The tags of %s could not be decoded and were checked as empty: %v", arn, err)
+		return tagMap
+	}
+
+	for _, tag := range tags {
+		if tag != nil {
 			tagMap[tag.Key] = tag.Value
 		}
 	}
@@ -0,0 +1,119 @@
+package fake
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type listAccountsInput struct {
+	NextToken string `json:"NextToken"`
+}
+
+type listAccountsOutput struct {
+	Accounts  []account `json:"Accounts"`
+	NextToken string    `json:"NextToken,omitempty"`
+}
+
+type account struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+}
+
+func (f *ResourceExplorer) serveListAccounts(w http.ResponseWriter, r *http.Request) {
+	var input listAccountsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidInputException", err.Error())
+		return
+	}
+
+	start, end, next, ok := f.page(len(f.Accounts), input.NextToken)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidInputException", "invalid NextToken")
+		return
+	}
+
+	out := listAccountsOutput{Accounts: make([]account, 0, end-start), NextToken: next}
+	for _, id := range f.Accounts[start:end] {
+		out.Accounts = append(out.Accounts, account{ID: id, Name: "account-" + id, Status: "ACTIVE"})
+	}
+
+	writeJSON(w, out)
+}
+
+type assumeRoleResponse struct {
+	XMLName     xml.Name        `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleResponse"`
+	Credentials stsCredentials  `xml:"AssumeRoleResult>Credentials"`
+	User        assumedRoleUser `xml:"AssumeRoleResult>AssumedRoleUser"`
+	RequestID   string          `xml:"ResponseMetadata>RequestId"`
+}
+
+type stsCredentials struct {
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string `xml:"SecretAccessKey"`
+	SessionToken    string `xml:"SessionToken"`
+	Expiration      string `xml:"Expiration"`
+}
+
+type assumedRoleUser struct {
+	Arn           string `xml:"Arn"`
+	AssumedRoleID string `xml:"AssumedRoleId"`
+}
+
+type stsErrorResponse struct {
+	XMLName   xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ ErrorResponse"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// serveSTS serves AssumeRole, returning credentials that only see the resources of the
+// account of the role
+func (f *ResourceExplorer) serveSTS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("Action") != "AssumeRole" {
+		writeSTSError(w, http.StatusBadRequest, "InvalidAction", "operation not supported by the fake")
+		return
+	}
+
+	// arn:aws:iam::111111111111:role/Name
+	roleARN := r.PostForm.Get("RoleArn")
+	parts := strings.Split(roleARN, ":")
+	if len(parts) != 6 {
+		writeSTSError(w, http.StatusBadRequest, "ValidationError", "invalid RoleArn")
+		return
+	}
+	accountID := parts[4]
+
+	if slices.Contains(f.DeniedAccounts, accountID) {
+		writeSTSError(w, http.StatusForbidden, "AccessDenied", "not authorized to perform sts:AssumeRole on resource: "+roleARN)
+		return
+	}
+
+	out := assumeRoleResponse{
+		Credentials: stsCredentials{
+			AccessKeyID:     assumedKeyPrefix + accountID,
+			SecretAccessKey: "fake",
+			SessionToken:    "fake",
+			Expiration:      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		},
+		User: assumedRoleUser{
+			Arn:           "arn:aws:sts::" + accountID + ":assumed-role/" + r.PostForm.Get("RoleSessionName"),
+			AssumedRoleID: "AROAFAKE:" + r.PostForm.Get("RoleSessionName"),
+		},
+		RequestID: "fake",
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	_ = xml.NewEncoder(w).Encode(out)
+}
+
+func writeSTSError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(stsErrorResponse{Type: "Sender", Code: code, Message: message, RequestID: "fake"})
+}
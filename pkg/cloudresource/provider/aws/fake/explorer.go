@@ -0,0 +1,383 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the number of resources returned per Search page
+	DefaultPageSize = 2
+	// DefaultMaxResults is the number of results a query returns at most, like Resource Explorer
+	DefaultMaxResults = 1000
+)
+
+// Resource is a resource served by the fake
+type Resource struct {
+	ARN     string
+	Type    string // e.g. ec2:instance
+	Service string
+	Account string
+	Region  string
+	Tags    map[string]string
+
+	// RawTags replaces the tags document when set, e.g. to serve a malformed document
+	RawTags json.RawMessage
+}
+
+// ResourceExplorer is an in-memory fake of the AWS APIs the provider calls, serving
+// Resource Explorer Search, ListIndexes and GetDefaultView, Resource Groups Tagging GetResources,
+// Organizations ListAccounts and STS AssumeRole requests from a list of resources for
+// end-to-end tests without AWS credentials. Search queries support the resourcetype, region,
+// accountid, tag.key and tag filters, which may be negated. It is safe for concurrent use.
+//
+// Requests are scoped by the credentials they are signed with: credentials returned by
+// AssumeRole only see the resources of the account of the role, and regional APIs only the
+// resources of the region a request is signed for.
+type ResourceExplorer struct {
+	// Resources are the resources returned by Search, filtered by resource type
+	Resources []*Resource
+
+	// PageSize is the number of resources per page, DefaultPageSize when zero
+	PageSize int
+
+	// MaxResults is the number of results of a query, DefaultMaxResults when zero. Queries
+	// matching more resources are truncated and reported as incomplete.
+	MaxResults int
+
+	// Throttle is the number of Search requests answered with a ThrottlingException
+	// before requests are served
+	Throttle int
+
+	// Indexes are the regions with a Resource Explorer index returned by ListIndexes. When
+	// set, Search only returns the resources of the region a request is signed for, unless
+	// it is the AggregatorRegion. Otherwise Search returns the resources of every region.
+	Indexes          []string
+	AggregatorRegion string
+
+	// NoDefaultView are the regions without a default view, where GetDefaultView returns
+	// no view and Search is rejected with an UnauthorizedException
+	NoDefaultView []string
+
+	// Accounts are the active accounts returned by ListAccounts
+	Accounts []string
+
+	// DeniedAccounts are the accounts where AssumeRole is denied
+	DeniedAccounts []string
+
+	mu       sync.Mutex
+	requests int
+}
+
+// Requests returns the number of Search requests received, including throttled ones
+func (f *ResourceExplorer) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+type searchInput struct {
+	QueryString string `json:"QueryString"`
+	ViewArn     string `json:"ViewArn"`
+	NextToken   string `json:"NextToken"`
+}
+
+type searchOutput struct {
+	Count     resourceCount    `json:"Count"`
+	NextToken string           `json:"NextToken,omitempty"`
+	Resources []searchResource `json:"Resources"`
+	ViewArn   string           `json:"ViewArn"`
+}
+
+type resourceCount struct {
+	Complete       bool  `json:"Complete"`
+	TotalResources int64 `json:"TotalResources"`
+}
+
+type searchResource struct {
+	Arn             string             `json:"Arn"`
+	LastReportedAt  string             `json:"LastReportedAt"`
+	OwningAccountId string             `json:"OwningAccountId"`
+	Properties      []resourceProperty `json:"Properties"`
+	Region          string             `json:"Region"`
+	ResourceType    string             `json:"ResourceType"`
+	Service         string             `json:"Service"`
+}
+
+type resourceProperty struct {
+	Data           json.RawMessage `json:"Data"`
+	LastReportedAt string          `json:"LastReportedAt"`
+	Name           string          `json:"Name"`
+}
+
+type tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// ServeHTTP implements the http.Handler interface
+func (f *ResourceExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusNotFound, "UnknownOperationException", "operation not supported by the fake")
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/Search":
+		f.serveSearch(w, r)
+	case r.URL.Path == "/ListIndexes":
+		f.serveListIndexes(w)
+	case r.URL.Path == "/GetDefaultView":
+		f.serveGetDefaultView(w, r)
+	case r.Header.Get("X-Amz-Target") == "ResourceGroupsTaggingAPI_20170126.GetResources":
+		f.serveGetResources(w, r)
+	case r.Header.Get("X-Amz-Target") == "AWSOrganizationsV20161128.ListAccounts":
+		f.serveListAccounts(w, r)
+	case r.URL.Path == "/" && r.Header.Get("X-Amz-Target") == "":
+		f.serveSTS(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "UnknownOperationException", "operation not supported by the fake")
+	}
+}
+
+func (f *ResourceExplorer) serveSearch(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	throttled := f.Throttle > 0
+	if throttled {
+		f.Throttle--
+	}
+	f.mu.Unlock()
+
+	if throttled {
+		writeError(w, http.StatusTooManyRequests, "ThrottlingException", "Rate exceeded")
+		return
+	}
+
+	var input searchInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationException", err.Error())
+		return
+	}
+
+	caller := newCaller(r)
+	if input.ViewArn == "" && slices.Contains(f.NoDefaultView, caller.region) {
+		writeError(w, http.StatusUnauthorized, "UnauthorizedException", "no default view in "+caller.region)
+		return
+	}
+	if len(f.Indexes) == 0 || caller.region == f.AggregatorRegion {
+		caller.region = ""
+	}
+
+	var matches []*Resource
+	for _, resource := range f.Resources {
+		if caller.sees(resource) && resource.matches(input.QueryString) {
+			matches = append(matches, resource)
+		}
+	}
+
+	maxResults := f.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultMaxResults
+	}
+	complete := len(matches) <= maxResults
+	if !complete {
+		matches = matches[:maxResults]
+	}
+
+	start, end, next, ok := f.page(len(matches), input.NextToken)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "ValidationException", "invalid NextToken")
+		return
+	}
+
+	out := searchOutput{
+		Count:     resourceCount{Complete: complete, TotalResources: int64(len(matches))},
+		NextToken: next,
+		Resources: make([]searchResource, 0, end-start),
+		ViewArn:   input.ViewArn,
+	}
+	for _, resource := range matches[start:end] {
+		out.Resources = append(out.Resources, resource.searchResource())
+	}
+
+	writeJSON(w, out)
+}
+
+type listIndexesOutput struct {
+	Indexes []index `json:"Indexes"`
+}
+
+type index struct {
+	Arn    string `json:"Arn"`
+	Region string `json:"Region"`
+	Type   string `json:"Type"`
+}
+
+func (f *ResourceExplorer) serveListIndexes(w http.ResponseWriter) {
+	out := listIndexesOutput{Indexes: make([]index, 0, len(f.Indexes))}
+	for _, region := range f.Indexes {
+		indexType := "LOCAL"
+		if region == f.AggregatorRegion {
+			indexType = "AGGREGATOR"
+		}
+		out.Indexes = append(out.Indexes, index{
+			Arn:    "arn:aws:resource-explorer-2:" + region + ":123456789012:index/fake",
+			Region: region,
+			Type:   indexType,
+		})
+	}
+
+	writeJSON(w, out)
+}
+
+type getDefaultViewOutput struct {
+	ViewArn string `json:"ViewArn,omitempty"`
+}
+
+func (f *ResourceExplorer) serveGetDefaultView(w http.ResponseWriter, r *http.Request) {
+	region := newCaller(r).region
+
+	var out getDefaultViewOutput
+	if !slices.Contains(f.NoDefaultView, region) {
+		out.ViewArn = "arn:aws:resource-explorer-2:" + region + ":123456789012:view/fake/default"
+	}
+
+	writeJSON(w, out)
+}
+
+// page returns the bounds of the page of a list starting at token, and the token of the
+// next page
+func (f *ResourceExplorer) page(total int, token string) (start, end int, next string, ok bool) {
+	if token != "" {
+		var err error
+		if start, err = strconv.Atoi(token); err != nil || start < 0 || start > total {
+			return 0, 0, "", false
+		}
+	}
+
+	pageSize := f.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	end = min(start+pageSize, total)
+
+	if end < total {
+		next = strconv.Itoa(end)
+	}
+	return start, end, next, true
+}
+
+// assumedKeyPrefix prefixes the access key IDs returned by AssumeRole, followed by the
+// account of the role
+const assumedKeyPrefix = "ASIAFAKE"
+
+// caller is the account and region a request is scoped to, empty when not scoped
+type caller struct {
+	account string
+	region  string
+}
+
+// newCaller returns the scope of a request from its SigV4 credential scope, e.g.
+// `Credential=ASIAFAKE111111111111/20240101/us-east-1/tagging/aws4_request`
+func newCaller(r *http.Request) caller {
+	_, credential, ok := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+	if !ok {
+		return caller{}
+	}
+	credential, _, _ = strings.Cut(credential, ",")
+	parts := strings.Split(credential, "/")
+
+	var c caller
+	if account, found := strings.CutPrefix(parts[0], assumedKeyPrefix); found {
+		c.account = account
+	}
+	if len(parts) > 2 {
+		c.region = parts[2]
+	}
+	return c
+}
+
+// sees reports whether a resource is in the scope of the caller
+func (c caller) sees(resource *Resource) bool {
+	return (c.account == "" || resource.Account == c.account) && (c.region == "" || resource.Region == c.region)
+}
+
+func (r *Resource) matches(query string) bool {
+	for _, term := range strings.Fields(query) {
+		negated := strings.HasPrefix(term, "-")
+		name, value, _ := strings.Cut(strings.TrimPrefix(term, "-"), ":")
+
+		var matched bool
+		switch name {
+		case "resourcetype":
+			matched = r.Type == value
+		case "region":
+			matched = r.Region == value
+		case "accountid":
+			matched = r.Account == value
+		case "tag.key":
+			_, matched = r.Tags[value]
+		case "tag":
+			if value == "none" {
+				matched = len(r.Tags) == 0
+				break
+			}
+			key, tagValue, _ := strings.Cut(value, "=")
+			actual, exists := r.Tags[key]
+			matched = exists && actual == tagValue
+		default:
+			continue
+		}
+
+		if matched == negated {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Resource) searchResource() searchResource {
+	reportedAt := time.Unix(1700000000, 0).UTC().Format(time.RFC3339)
+
+	data := r.RawTags
+	if data == nil {
+		data, _ = json.Marshal(r.tags())
+	}
+
+	return searchResource{
+		Arn:             r.ARN,
+		LastReportedAt:  reportedAt,
+		OwningAccountId: r.Account,
+		Properties:      []resourceProperty{{Name: "tags", Data: data, LastReportedAt: reportedAt}},
+		Region:          r.Region,
+		ResourceType:    r.Type,
+		Service:         r.Service,
+	}
+}
+
+// tags returns the tags of the resource as a list of key value pairs
+func (r *Resource) tags() []tag {
+	tags := make([]tag, 0, len(r.Tags))
+	for key, value := range r.Tags {
+		tags = append(tags, tag{Key: key, Value: value})
+	}
+	return tags
+}
+
+func writeJSON(w http.ResponseWriter, out any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,310 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2"
+	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCredentials = aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+}))
+
+func newClient(url string) *resourceexplorer2.Client {
+	return newRegionalClient(url, "us-east-1", testCredentials)
+}
+
+func newRegionalClient(url, region string, credentials aws.CredentialsProvider) *resourceexplorer2.Client {
+	return resourceexplorer2.New(resourceexplorer2.Options{
+		Region:       region,
+		BaseEndpoint: aws.String(url),
+		Credentials:  credentials,
+	})
+}
+
+// callJSON calls an AWS JSON protocol operation of the fake, signed for a region
+func callJSON(t *testing.T, url, target, region string, input, output any) int {
+	body, err := json.Marshal(input)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-Amz-Target", target)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=test/20240101/"+region+"/tagging/aws4_request, SignedHeaders=host, Signature=fake")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(output))
+	return resp.StatusCode
+}
+
+func TestSearch(t *testing.T) {
+	fake := &ResourceExplorer{Resources: []*Resource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Service: "ec2", Account: "123456789012", Region: "us-east-1", Tags: map[string]string{"env": "prod"}},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-2", Type: "ec2:instance", Service: "ec2", Account: "123456789012", Region: "us-east-1"},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-3", Type: "ec2:instance", Service: "ec2", Account: "123456789012", Region: "us-east-1"},
+		{ARN: "arn:aws:s3:::bucket-1", Type: "s3:bucket", Service: "s3", Account: "123456789012", Region: "us-east-1"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newClient(server.URL)
+
+	first, err := client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+	require.NoError(t, err)
+	require.Len(t, first.Resources, DefaultPageSize)
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-1", aws.ToString(first.Resources[0].Arn))
+	assert.Equal(t, "tags", aws.ToString(first.Resources[0].Properties[0].Name))
+	assert.Equal(t, int64(3), aws.ToInt64(first.Count.TotalResources))
+	assert.True(t, aws.ToBool(first.Count.Complete))
+
+	var tags []map[string]string
+	require.NoError(t, first.Resources[0].Properties[0].Data.UnmarshalSmithyDocument(&tags))
+	assert.Equal(t, []map[string]string{{"Key": "env", "Value": "prod"}}, tags)
+
+	second, err := client.Search(context.Background(), &resourceexplorer2.SearchInput{
+		QueryString: aws.String("resourcetype:ec2:instance"),
+		NextToken:   first.NextToken,
+	})
+	require.NoError(t, err)
+	require.Len(t, second.Resources, 1)
+	assert.Nil(t, second.NextToken)
+	assert.Equal(t, 2, fake.Requests())
+}
+
+func TestThrottle(t *testing.T) {
+	fake := &ResourceExplorer{Throttle: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newClient(server.URL)
+
+	_, err := client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")}, func(o *resourceexplorer2.Options) {
+		o.RetryMaxAttempts = 1
+	})
+	var throttled *types.ThrottlingException
+	require.True(t, errors.As(err, &throttled))
+	assert.Equal(t, "Rate exceeded", throttled.ErrorMessage())
+
+	_, err = client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.Requests())
+}
+
+func TestRawTags(t *testing.T) {
+	fake := &ResourceExplorer{Resources: []*Resource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", RawTags: json.RawMessage(`{"env": "prod"}`)},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	out, err := newClient(server.URL).Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+	require.NoError(t, err)
+	require.Len(t, out.Resources, 1)
+
+	var tags map[string]string
+	require.NoError(t, out.Resources[0].Properties[0].Data.UnmarshalSmithyDocument(&tags))
+	assert.Equal(t, map[string]string{"env": "prod"}, tags)
+}
+
+func TestMaxResults(t *testing.T) {
+	fake := &ResourceExplorer{MaxResults: 2, PageSize: 10, Resources: []*Resource{
+		{ARN: "arn:aws:ec2:us-east-1:111111111111:instance/i-1", Type: "ec2:instance", Account: "111111111111", Region: "us-east-1"},
+		{ARN: "arn:aws:ec2:us-east-1:222222222222:instance/i-2", Type: "ec2:instance", Account: "222222222222", Region: "us-east-1"},
+		{ARN: "arn:aws:ec2:eu-west-1:111111111111:instance/i-3", Type: "ec2:instance", Account: "111111111111", Region: "eu-west-1"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newClient(server.URL)
+	search := func(query string) *resourceexplorer2.SearchOutput {
+		out, err := client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String(query)})
+		require.NoError(t, err)
+		return out
+	}
+
+	truncated := search("resourcetype:ec2:instance")
+	assert.Len(t, truncated.Resources, 2)
+	assert.False(t, aws.ToBool(truncated.Count.Complete))
+	assert.Nil(t, truncated.NextToken)
+
+	region := search("resourcetype:ec2:instance region:us-east-1")
+	assert.Len(t, region.Resources, 2)
+	assert.True(t, aws.ToBool(region.Count.Complete))
+
+	rest := search("resourcetype:ec2:instance -region:us-east-1 accountid:111111111111")
+	require.Len(t, rest.Resources, 1)
+	assert.Equal(t, "arn:aws:ec2:eu-west-1:111111111111:instance/i-3", aws.ToString(rest.Resources[0].Arn))
+}
+
+func TestIndexes(t *testing.T) {
+	fake := &ResourceExplorer{
+		Indexes:          []string{"us-east-1", "eu-west-1"},
+		AggregatorRegion: "us-east-1",
+		Resources: []*Resource{
+			{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Region: "us-east-1"},
+			{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-2", Type: "ec2:instance", Region: "eu-west-1"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	indexes, err := newClient(server.URL).ListIndexes(context.Background(), &resourceexplorer2.ListIndexesInput{})
+	require.NoError(t, err)
+	require.Len(t, indexes.Indexes, 2)
+	assert.Equal(t, types.IndexTypeAggregator, indexes.Indexes[0].Type)
+	assert.Equal(t, "eu-west-1", aws.ToString(indexes.Indexes[1].Region))
+	assert.Equal(t, types.IndexTypeLocal, indexes.Indexes[1].Type)
+
+	// the aggregator region returns every region, other regions their own resources
+	for region, expected := range map[string]int{"us-east-1": 2, "eu-west-1": 1} {
+		out, err := newRegionalClient(server.URL, region, testCredentials).Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+		require.NoError(t, err)
+		assert.Len(t, out.Resources, expected, region)
+	}
+}
+
+func TestDefaultView(t *testing.T) {
+	fake := &ResourceExplorer{
+		Indexes:       []string{"us-east-1", "eu-west-1"},
+		NoDefaultView: []string{"eu-west-1"},
+		Resources: []*Resource{
+			{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-1", Type: "ec2:instance", Region: "eu-west-1"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	view, err := newClient(server.URL).GetDefaultView(context.Background(), &resourceexplorer2.GetDefaultViewInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:resource-explorer-2:us-east-1:123456789012:view/fake/default", aws.ToString(view.ViewArn))
+
+	client := newRegionalClient(server.URL, "eu-west-1", testCredentials)
+	view, err = client.GetDefaultView(context.Background(), &resourceexplorer2.GetDefaultViewInput{})
+	require.NoError(t, err)
+	assert.Nil(t, view.ViewArn)
+
+	_, err = client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+	var unauthorized *types.UnauthorizedException
+	assert.ErrorAs(t, err, &unauthorized)
+}
+
+func TestGetResources(t *testing.T) {
+	fake := &ResourceExplorer{PageSize: 1, Resources: []*Resource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Type: "ec2:instance", Region: "us-east-1", Tags: map[string]string{"env": "prod"}},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-2", Type: "ec2:instance", Region: "us-east-1"},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-3", Type: "ec2:instance", Region: "us-east-1", Tags: map[string]string{"env": "dev"}},
+		{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-4", Type: "ec2:instance", Region: "eu-west-1", Tags: map[string]string{"env": "dev"}},
+		{ARN: "arn:aws:s3:::bucket-1", Type: "s3:bucket", Region: "us-east-1", Tags: map[string]string{"env": "dev"}},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var arns []string
+	input := getResourcesInput{ResourceTypeFilters: []string{"ec2:instance"}}
+	for {
+		var out getResourcesOutput
+		status := callJSON(t, server.URL, "ResourceGroupsTaggingAPI_20170126.GetResources", "us-east-1", input, &out)
+		require.Equal(t, http.StatusOK, status)
+
+		for _, mapping := range out.ResourceTagMappingList {
+			arns = append(arns, mapping.ResourceARN)
+			assert.Len(t, mapping.Tags, 1)
+		}
+		if out.PaginationToken == "" {
+			break
+		}
+		input.PaginationToken = out.PaginationToken
+	}
+
+	// resources without tags and of other regions are not returned
+	assert.Equal(t, []string{"arn:aws:ec2:us-east-1:123456789012:instance/i-1", "arn:aws:ec2:us-east-1:123456789012:instance/i-3"}, arns)
+}
+
+func TestAccounts(t *testing.T) {
+	fake := &ResourceExplorer{
+		Accounts:       []string{"111111111111", "222222222222", "333333333333"},
+		DeniedAccounts: []string{"333333333333"},
+		Resources: []*Resource{
+			{ARN: "arn:aws:ec2:us-east-1:111111111111:instance/i-1", Type: "ec2:instance", Account: "111111111111", Region: "us-east-1"},
+			{ARN: "arn:aws:ec2:us-east-1:222222222222:instance/i-2", Type: "ec2:instance", Account: "222222222222", Region: "us-east-1"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var accounts listAccountsOutput
+	require.Equal(t, http.StatusOK, callJSON(t, server.URL, "AWSOrganizationsV20161128.ListAccounts", "us-east-1", listAccountsInput{}, &accounts))
+	require.Len(t, accounts.Accounts, DefaultPageSize)
+	assert.Equal(t, "111111111111", accounts.Accounts[0].ID)
+	assert.Equal(t, "ACTIVE", accounts.Accounts[0].Status)
+	assert.NotEmpty(t, accounts.NextToken)
+
+	stsClient := sts.New(sts.Options{Region: "us-east-1", BaseEndpoint: aws.String(server.URL), Credentials: testCredentials})
+
+	role, err := stsClient.AssumeRole(context.Background(), &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::222222222222:role/TagPatrol"),
+		RoleSessionName: aws.String("tagpatrol"),
+	})
+	require.NoError(t, err)
+
+	// the credentials of the role only see the resources of its account
+	credentials := aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     aws.ToString(role.Credentials.AccessKeyId),
+			SecretAccessKey: aws.ToString(role.Credentials.SecretAccessKey),
+			SessionToken:    aws.ToString(role.Credentials.SessionToken),
+		}, nil
+	}))
+	out, err := newRegionalClient(server.URL, "us-east-1", credentials).Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String("resourcetype:ec2:instance")})
+	require.NoError(t, err)
+	require.Len(t, out.Resources, 1)
+	assert.Equal(t, "222222222222", aws.ToString(out.Resources[0].OwningAccountId))
+
+	_, err = stsClient.AssumeRole(context.Background(), &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::333333333333:role/TagPatrol"),
+		RoleSessionName: aws.String("tagpatrol"),
+	})
+	assert.ErrorContains(t, err, "AccessDenied")
+}
+
+func TestTagFilters(t *testing.T) {
+	fake := &ResourceExplorer{PageSize: 10, Resources: []*Resource{
+		{ARN: "i-1", Type: "ec2:instance", Tags: map[string]string{"env": "prod", "team": "a"}},
+		{ARN: "i-2", Type: "ec2:instance", Tags: map[string]string{"env": "dev"}},
+		{ARN: "i-3", Type: "ec2:instance"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newClient(server.URL)
+	search := func(query string) []string {
+		out, err := client.Search(context.Background(), &resourceexplorer2.SearchInput{QueryString: aws.String(query)})
+		require.NoError(t, err)
+
+		var arns []string
+		for _, resource := range out.Resources {
+			arns = append(arns, aws.ToString(resource.Arn))
+		}
+		return arns
+	}
+
+	assert.Equal(t, []string{"i-1", "i-2"}, search("resourcetype:ec2:instance tag.key:env"))
+	assert.Equal(t, []string{"i-2", "i-3"}, search("resourcetype:ec2:instance -tag.key:team"))
+	assert.Equal(t, []string{"i-2"}, search("resourcetype:ec2:instance tag:env=dev"))
+	assert.Equal(t, []string{"i-3"}, search("resourcetype:ec2:instance tag:none"))
+	assert.Equal(t, []string{"i-1", "i-2"}, search("resourcetype:ec2:instance -tag:none"))
+}
@@ -0,0 +1,58 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+type getResourcesInput struct {
+	PaginationToken     string   `json:"PaginationToken"`
+	ResourceTypeFilters []string `json:"ResourceTypeFilters"`
+}
+
+type getResourcesOutput struct {
+	PaginationToken        string               `json:"PaginationToken"`
+	ResourceTagMappingList []resourceTagMapping `json:"ResourceTagMappingList"`
+}
+
+type resourceTagMapping struct {
+	ResourceARN string `json:"ResourceARN"`
+	Tags        []tag  `json:"Tags"`
+}
+
+// serveGetResources serves the tagged resources of the region a request is signed for. Like
+// the Resource Groups Tagging API, resources without tags are not returned.
+func (f *ResourceExplorer) serveGetResources(w http.ResponseWriter, r *http.Request) {
+	var input getResourcesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidParameterException", err.Error())
+		return
+	}
+
+	caller := newCaller(r)
+
+	var matches []*Resource
+	for _, resource := range f.Resources {
+		if len(resource.Tags) == 0 || !caller.sees(resource) {
+			continue
+		}
+		if len(input.ResourceTypeFilters) > 0 && !slices.Contains(input.ResourceTypeFilters, resource.Type) {
+			continue
+		}
+		matches = append(matches, resource)
+	}
+
+	start, end, next, ok := f.page(len(matches), input.PaginationToken)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "PaginationTokenExpiredException", "invalid PaginationToken")
+		return
+	}
+
+	out := getResourcesOutput{PaginationToken: next, ResourceTagMappingList: make([]resourceTagMapping, 0, end-start)}
+	for _, resource := range matches[start:end] {
+		out.ResourceTagMappingList = append(out.ResourceTagMappingList, resourceTagMapping{ResourceARN: resource.ARN, Tags: resource.tags()})
+	}
+
+	writeJSON(w, out)
+}
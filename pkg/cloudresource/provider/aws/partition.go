@@ -0,0 +1,158 @@
+package aws
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// maxQueryLength is the maximum length of a Resource Explorer query string
+const maxQueryLength = 1280
+
+// partitionFilter is a Resource Explorer filter a truncated search can be split by
+type partitionFilter struct {
+	name string
+	// terms returns the query terms of the filter matching a resource, e.g. `region:us-east-1`
+	terms func(*AWSResource) []string
+}
+
+// partitionFilters are the filters truncated searches are split by, in order. Resources
+// with many tags match several tag partitions, they are deduplicated by ARN.
+var partitionFilters = []partitionFilter{
+	{name: "region", terms: func(r *AWSResource) []string { return filterTerms("region", r.ResourceRegion) }},
+	{name: "accountid", terms: func(r *AWSResource) []string { return filterTerms("accountid", r.AccountID) }},
+	{name: "tag key", terms: tagKeyTerms},
+	{name: "tag value", terms: tagValueTerms},
+}
+
+func filterTerms(name, value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{name + ":" + value}
+}
+
+// tagKeyTerms returns a `tag.key:` term per tag key of a resource, or `tag:none` when the
+// resource has no tags
+func tagKeyTerms(r *AWSResource) []string {
+	if len(r.ResourceTags) == 0 {
+		return []string{"tag:none"}
+	}
+
+	var terms []string
+	for key := range r.ResourceTags {
+		if queryable(key) {
+			terms = append(terms, "tag.key:"+key)
+		}
+	}
+	return terms
+}
+
+// tagValueTerms returns a `tag:key=value` term per tag of a resource
+func tagValueTerms(r *AWSResource) []string {
+	var terms []string
+	for key, value := range r.ResourceTags {
+		if queryable(key) && value != "" && queryable(value) {
+			terms = append(terms, "tag:"+key+"="+value)
+		}
+	}
+	return terms
+}
+
+// queryable reports whether a tag key or value can be used in a query filter as is. Keys
+// and values with spaces, quotes or `=` are left out of the partitions.
+func queryable(s string) bool {
+	return !strings.ContainsAny(s, " \t\n\"=")
+}
+
+// searchPartitioned searches a query and, when Resource Explorer truncates the results,
+// splits it by the terms of the next partition filter found in the results, e.g. a search
+// per region, then per account, tag key and tag value. The resources matching none of those
+// terms are searched by excluding them, and split again until the search is complete.
+// Partitions that are still truncated after every filter are reported as warnings of the
+// search.
+func (p *Provider) searchPartitioned(ctx context.Context, client ResourceExplorerClient, query string, depth int) ([]*AWSResource, error) {
+	resources, complete, err := p.searchQuery(ctx, client, query)
+	if err != nil || complete {
+		return resources, err
+	}
+
+	if depth >= len(partitionFilters) {
+		recordWarning(ctx, "Resource Explorer truncated the results of `%s` and it can't be partitioned further, some resources may be missing", query)
+		return resources, nil
+	}
+
+	filter := partitionFilters[depth]
+	merged := newResourceSet()
+	var excluded []string
+
+	for {
+		terms := partitionTerms(resources, filter, excluded)
+		if len(terms) == 0 {
+			recordWarning(ctx, "Resource Explorer truncated the results of `%s` and it can't be partitioned by %s, some resources may be missing", query, filter.name)
+			merged.add(resources...)
+			return merged.resources, nil
+		}
+
+		for _, term := range terms {
+			partition, err := p.searchPartitioned(ctx, client, query+" "+term, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			merged.add(partition...)
+		}
+
+		excluded = append(excluded, terms...)
+		rest := query + " -" + strings.Join(excluded, " -")
+		if len(rest) > maxQueryLength {
+			recordWarning(ctx, "Resource Explorer truncated the results of `%s` and partitioning it by %s exceeds the maximum query length, some resources may be missing", query, filter.name)
+			merged.add(resources...)
+			return merged.resources, nil
+		}
+
+		resources, complete, err = p.searchQuery(ctx, client, rest)
+		if err != nil {
+			return nil, err
+		}
+		if complete {
+			merged.add(resources...)
+			return merged.resources, nil
+		}
+	}
+}
+
+// partitionTerms returns the sorted distinct terms of a filter matching the resources,
+// skipping excluded terms
+func partitionTerms(resources []*AWSResource, filter partitionFilter, excluded []string) []string {
+	var terms []string
+	for _, resource := range resources {
+		for _, term := range filter.terms(resource) {
+			if !slices.Contains(excluded, term) {
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	slices.Sort(terms)
+	return slices.Compact(terms)
+}
+
+// resourceSet is a list of resources, deduplicated by ARN
+type resourceSet struct {
+	resources []*AWSResource
+	seen      map[string]bool
+}
+
+func newResourceSet() *resourceSet {
+	return &resourceSet{seen: make(map[string]bool)}
+}
+
+func (s *resourceSet) add(resources ...*AWSResource) {
+	for _, resource := range resources {
+		if s.seen[resource.ResourceARN] {
+			continue
+		}
+		s.seen[resource.ResourceARN] = true
+		s.resources = append(s.resources, resource)
+	}
+}
@@ -0,0 +1,206 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2"
+	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2/types"
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// regionConcurrency is the number of regions searched at the same time per resource definition
+const regionConcurrency = 5
+
+// IndexLister defines the interface for listing the Resource Explorer indexes of an account
+type IndexLister interface {
+	ListIndexes(ctx context.Context, params *resourceexplorer2.ListIndexesInput, optFns ...func(*resourceexplorer2.Options)) (*resourceexplorer2.ListIndexesOutput, error)
+}
+
+// RegionalExplorerClient defines the interface for searching a region with its default
+// Resource Explorer view
+type RegionalExplorerClient interface {
+	ResourceExplorerClient
+	GetDefaultView(ctx context.Context, params *resourceexplorer2.GetDefaultViewInput, optFns ...func(*resourceexplorer2.Options)) (*resourceexplorer2.GetDefaultViewOutput, error)
+}
+
+// listIndexRegions returns the regions with a Resource Explorer index
+func listIndexRegions(ctx context.Context, retryer *retryer, client IndexLister) ([]string, error) {
+	var regions []string
+	var nextToken *string
+
+	for {
+		var resp *resourceexplorer2.ListIndexesOutput
+		err := retryer.do(ctx, func() (err error) {
+			resp, err = client.ListIndexes(ctx, &resourceexplorer2.ListIndexesInput{NextToken: nextToken})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing Resource Explorer indexes: %w", err)
+		}
+
+		for _, index := range resp.Indexes {
+			regions = append(regions, awssdk.ToString(index.Region))
+		}
+
+		if resp.NextToken == nil {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no Resource Explorer index found in any region")
+	}
+
+	return regions, nil
+}
+
+func newRegionalClients(regions []string, newClient func(region string) RegionalExplorerClient) map[string]RegionalExplorerClient {
+	clients := make(map[string]RegionalExplorerClient, len(regions))
+	for _, region := range regions {
+		clients[region] = newClient(region)
+	}
+	return clients
+}
+
+// splitByDefaultView returns the clients of the regions with a default view, which searches
+// without a view ARN use, and the regions without one. Resource Explorer rejects searches in
+// a region without a default view, e.g. a region holding a local index only.
+func splitByDefaultView(ctx context.Context, retryer *retryer, clients map[string]RegionalExplorerClient) (map[string]ResourceExplorerClient, []string, error) {
+	regions := sortedKeys(clients)
+	hasView := make([]bool, len(regions))
+	errs := make([]error, len(regions))
+
+	fanOut(len(regions), regionConcurrency, func(i int) {
+		hasView[i], errs[i] = hasDefaultView(ctx, retryer, clients[regions[i]])
+	})
+
+	withView := make(map[string]ResourceExplorerClient)
+	var withoutView []string
+
+	for i, region := range regions {
+		if errs[i] != nil {
+			return nil, nil, fmt.Errorf("error getting the default Resource Explorer view of %s: %w", region, errs[i])
+		}
+
+		if hasView[i] {
+			withView[region] = clients[region]
+		} else {
+			withoutView = append(withoutView, region)
+		}
+	}
+
+	if len(withView) == 0 {
+		return nil, nil, fmt.Errorf("no default Resource Explorer view found in any region")
+	}
+
+	return withView, withoutView, nil
+}
+
+func hasDefaultView(ctx context.Context, retryer *retryer, client RegionalExplorerClient) (bool, error) {
+	var resp *resourceexplorer2.GetDefaultViewOutput
+	err := retryer.do(ctx, func() (err error) {
+		resp, err = client.GetDefaultView(ctx, &resourceexplorer2.GetDefaultViewInput{}, withoutSDKRetries)
+		return err
+	})
+
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return awssdk.ToString(resp.ViewArn) != "", nil
+}
+
+// regionalClients returns the regional Resource Explorer clients of the regions with a
+// default view and the regions without one, resolving them on first use. A failed
+// resolution, e.g. when the search of a definition timed out, is not cached and runs
+// again for the next definition.
+func (p *Provider) regionalClients(ctx context.Context) (map[string]ResourceExplorerClient, []string, error) {
+	p.regionsMu.Lock()
+	defer p.regionsMu.Unlock()
+
+	if p.explorerClients != nil || p.discoverRegions == nil {
+		return p.explorerClients, p.viewlessRegions, nil
+	}
+
+	discovered, err := p.discoverRegions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clients, viewless, err := splitByDefaultView(ctx, p.retryer, discovered)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.explorerClients, p.viewlessRegions = clients, viewless
+	return clients, viewless, nil
+}
+
+// findRegionalResources searches every region concurrently and merges the results. A region
+// holding the aggregator index also returns the resources of other regions, so resources
+// are deduplicated by ARN.
+func (p *Provider) findRegionalResources(ctx context.Context, clients map[string]ResourceExplorerClient, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	regions := sortedKeys(clients)
+	results := make([][]cr.CloudResource, len(regions))
+	errs := make([]error, len(regions))
+
+	fanOut(len(regions), regionConcurrency, func(i int) {
+		results[i], errs[i] = p.search(ctx, clients[regions[i]], serviceName, resourceName)
+	})
+
+	var resources []cr.CloudResource
+	seen := make(map[string]bool)
+
+	for i, region := range regions {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error searching %s: %w", region, errs[i])
+		}
+
+		for _, resource := range results[i] {
+			if seen[resource.ID()] {
+				continue
+			}
+			seen[resource.ID()] = true
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
+// fanOut calls fn for every index below n, running at most limit calls at the same time
+func fanOut(n, limit int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
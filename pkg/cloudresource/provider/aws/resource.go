@@ -56,14 +56,14 @@ func (r *AWSResource) IsCompliant() bool {
 	return len(r.Errors) == 0
 }
 
-// AddComplianceError adds a new compliance error with the given message
-func (r *AWSResource) AddComplianceError(msg string) {
-	r.Errors = append(r.Errors, &cr.ComplianceError{Message: msg})
+// AddComplianceError adds a new compliance error to the resource
+func (r *AWSResource) AddComplianceError(err *cr.ComplianceError) {
+	r.Errors = append(r.Errors, err)
 }
 
-// AddComplianceWarning adds a new compliance warning with the given message
-func (r *AWSResource) AddComplianceWarning(msg string) {
-	r.Warnings = append(r.Warnings, &cr.ComplianceWarning{Message: msg})
+// AddComplianceWarning adds a new compliance warning to the resource
+func (r *AWSResource) AddComplianceWarning(warning *cr.ComplianceWarning) {
+	r.Warnings = append(r.Warnings, warning)
 }
 
 // ComplianceErrors returns all compliance errors for this resource
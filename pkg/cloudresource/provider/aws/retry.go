@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a throttled or failed API call is retried
+	DefaultMaxRetries = 5
+	// DefaultRateLimit is the number of API calls per second shared by all the searches of a provider
+	DefaultRateLimit = 10.0
+
+	// retryBaseDelay and retryMaxDelay bound the jittered exponential backoff between attempts
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 20 * time.Second
+
+	// minRateLimit is the lowest rate the limiter backs off to when calls are throttled
+	minRateLimit = 0.5
+)
+
+var (
+	throttleChecks = retry.IsErrorThrottles{retry.ThrottleErrorCode{Codes: retry.DefaultThrottleErrorCodes}}
+	retryChecks    = retry.IsErrorRetryables(retry.DefaultRetryables)
+)
+
+// isThrottle reports whether an API call failed because it was throttled
+func isThrottle(err error) bool {
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusTooManyRequests {
+		return true
+	}
+	return throttleChecks.IsErrorThrottle(err).Bool()
+}
+
+// isRetryable reports whether a failed API call may succeed when retried, i.e. it was
+// throttled or failed with a server or connection error
+func isRetryable(err error) bool {
+	return isThrottle(err) || retryChecks.IsErrorRetryable(err).Bool()
+}
+
+// retryer retries throttled and failed API calls with jittered exponential backoff, pacing
+// every attempt through a rate limiter shared by all the clients of a provider
+type retryer struct {
+	maxRetries int
+	limiter    *rateLimiter
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryer(maxRetries int, rateLimit float64) *retryer {
+	return &retryer{
+		maxRetries: maxRetries,
+		limiter:    newRateLimiter(rateLimit),
+		sleep:      sleep,
+	}
+}
+
+// do calls fn until it succeeds, fails with an error that can't be retried, or runs out of
+// retries. Retries are recorded in the search stats of the context.
+func (r *retryer) do(ctx context.Context, fn func() error) error {
+	if r == nil {
+		return fn()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := r.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil {
+			r.limiter.succeeded()
+			return nil
+		}
+
+		if isThrottle(err) {
+			r.limiter.throttled()
+		}
+		if attempt >= r.maxRetries || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		recordRetry(ctx)
+		if err := r.sleep(ctx, backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns a random delay up to an exponentially growing cap ("full jitter")
+func backoff(attempt int) time.Duration {
+	limit := retryMaxDelay
+	if attempt < 16 {
+		limit = min(retryMaxDelay, retryBaseDelay<<attempt)
+	}
+	return time.Duration(rand.Int64N(int64(limit)) + 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiter spaces API calls evenly at an adaptive rate. The rate is halved whenever a
+// call is throttled and recovers gradually, up to the configured limit, as calls succeed.
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit float64 // calls per second
+	rate  float64
+	next  time.Time
+}
+
+// newRateLimiter creates a limiter allowing limit calls per second, nil when limit isn't positive
+func newRateLimiter(limit float64) *rateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &rateLimiter{limit: limit, rate: limit}
+}
+
+// wait blocks until the next call is allowed
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(time.Duration(float64(time.Second) / l.rate))
+	l.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		return sleep(ctx, delay)
+	}
+	return nil
+}
+
+func (l *rateLimiter) throttled() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = max(min(minRateLimit, l.limit), l.rate/2)
+}
+
+func (l *rateLimiter) succeeded() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = min(l.limit, l.rate+l.limit/20)
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// searchStats collects the retries and warnings of the search of a resource type
+type searchStats struct {
+	mu       sync.Mutex
+	retries  int
+	warnings []string
+}
+
+type searchStatsKey struct{}
+
+// withSearchStats returns a context collecting the stats recorded during a search
+func withSearchStats(ctx context.Context) (context.Context, *searchStats) {
+	stats := &searchStats{}
+	return context.WithValue(ctx, searchStatsKey{}, stats), stats
+}
+
+func recordRetry(ctx context.Context) {
+	if stats, ok := ctx.Value(searchStatsKey{}).(*searchStats); ok {
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		stats.retries++
+	}
+}
+
+func recordWarning(ctx context.Context, format string, args ...any) {
+	if stats, ok := ctx.Value(searchStatsKey{}).(*searchStats); ok {
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		stats.warnings = append(stats.warnings, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,113 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// taggingResourcesPerPage is the maximum page size of GetResources
+const taggingResourcesPerPage = 100
+
+// TaggingClient defines the interface for regional Resource Groups Tagging API interactions
+type TaggingClient interface {
+	GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error)
+}
+
+// newTaggingClient creates a Resource Groups Tagging API client for a region
+func newTaggingClient(awsCfg awssdk.Config, region string) *resourcegroupstaggingapi.Client {
+	return resourcegroupstaggingapi.NewFromConfig(awsCfg, func(o *resourcegroupstaggingapi.Options) {
+		o.Region = region
+		// the provider retryer retries the calls
+		o.Retryer = awssdk.NopRetryer{}
+	})
+}
+
+// findTaggedResources searches every configured region concurrently with the Resource Groups
+// Tagging API. The API only returns resources that have, or once had, tags.
+func (p *Provider) findTaggedResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	resourceType := fmt.Sprintf("%s:%s", serviceName, resourceName)
+
+	regions := sortedKeys(p.taggingClients)
+	results := make([][]cr.CloudResource, len(regions))
+	errs := make([]error, len(regions))
+
+	fanOut(len(regions), regionConcurrency, func(i int) {
+		results[i], errs[i] = getTaggedResources(ctx, p.retryer, p.taggingClients[regions[i]], regions[i], serviceName, resourceType)
+	})
+
+	var resources []cr.CloudResource
+	seen := make(map[string]bool)
+
+	for i, region := range regions {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error getting resources in %s: %w", region, errs[i])
+		}
+
+		// global resources may be returned by several regions
+		for _, resource := range results[i] {
+			if seen[resource.ID()] {
+				continue
+			}
+			seen[resource.ID()] = true
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
+func getTaggedResources(ctx context.Context, retryer *retryer, client TaggingClient, region, serviceName, resourceType string) ([]cr.CloudResource, error) {
+	var resources []cr.CloudResource
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(client, &resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: []string{resourceType},
+		ResourcesPerPage:    awssdk.Int32(taggingResourcesPerPage),
+	})
+
+	for paginator.HasMorePages() {
+		// a failed page doesn't advance the paginator, so it is requested again when retried
+		var resp *resourcegroupstaggingapi.GetResourcesOutput
+		err := retryer.do(ctx, func() (err error) {
+			resp, err = paginator.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mapping := range resp.ResourceTagMappingList {
+			resources = append(resources, newTaggedResource(mapping, serviceName, resourceType, region))
+		}
+	}
+
+	return resources, nil
+}
+
+func newTaggedResource(mapping types.ResourceTagMapping, serviceName, resourceType, region string) *AWSResource {
+	resource := &AWSResource{
+		ResourceARN:    awssdk.ToString(mapping.ResourceARN),
+		ResourceType:   resourceType,
+		ServiceName:    serviceName,
+		ResourceRegion: region,
+		ResourceTags:   make(map[string]string),
+	}
+
+	// ARNs of some resources, e.g. S3 buckets, have no region or account
+	if parsed, err := arn.Parse(resource.ResourceARN); err == nil {
+		resource.AccountID = parsed.AccountID
+		if parsed.Region != "" {
+			resource.ResourceRegion = parsed.Region
+		}
+	}
+
+	for _, tag := range mapping.Tags {
+		resource.ResourceTags[awssdk.ToString(tag.Key)] = awssdk.ToString(tag.Value)
+	}
+
+	return resource
+}
@@ -0,0 +1,315 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockResourceGraphClient struct {
+	mock.Mock
+}
+
+func (m *MockResourceGraphClient) Resources(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	// copy the request as the provider reuses it across pages
+	copied := *request
+	options := *request.Options
+	copied.Options = &options
+
+	args := m.Called(ctx, &copied)
+	return args.Get(0).(*QueryResponse), args.Error(1)
+}
+
+type staticCredential string
+
+func (c staticCredential) Token(ctx context.Context) (string, error) {
+	return string(c), nil
+}
+
+const vmQuery = "Resources | where type =~ 'Microsoft.Compute/virtualMachines' | project id, name, type, location, subscriptionId, resourceGroup, tags | order by id asc"
+
+func TestFindResources(t *testing.T) {
+	t.Run("Single Resource", func(t *testing.T) {
+		client := new(MockResourceGraphClient)
+		provider := &Provider{client: client, subscriptions: []string{"sub-1"}}
+
+		client.On("Resources", mock.Anything, &QueryRequest{
+			Subscriptions: []string{"sub-1"},
+			Query:         vmQuery,
+			Options:       &QueryOptions{Top: pageSize, ResultFormat: "objectArray"},
+		}).Return(&QueryResponse{Data: []*GraphResource{
+			{
+				ID:             "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1",
+				Name:           "vm-1",
+				Type:           "microsoft.compute/virtualmachines",
+				Location:       "westeurope",
+				SubscriptionID: "sub-1",
+				Tags:           map[string]string{"owner": "team-a"},
+			},
+		}}, nil)
+
+		resources, err := provider.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		assert.Equal(t, "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1", resources[0].ID())
+		assert.Equal(t, "microsoft.compute/virtualmachines", resources[0].Type())
+		assert.Equal(t, "Microsoft.Compute", resources[0].Service())
+		assert.Equal(t, "azure", resources[0].Provider())
+		assert.Equal(t, "westeurope", resources[0].Region())
+		assert.Equal(t, "sub-1", resources[0].OwnerID())
+		assert.Equal(t, map[string]string{"owner": "team-a"}, resources[0].Tags())
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		client := new(MockResourceGraphClient)
+		provider := &Provider{client: client, managementGroups: []string{"root"}}
+
+		client.On("Resources", mock.Anything, &QueryRequest{
+			ManagementGroups: []string{"root"},
+			Query:            vmQuery,
+			Options:          &QueryOptions{Top: pageSize, ResultFormat: "objectArray"},
+		}).Return(&QueryResponse{Data: []*GraphResource{{ID: "vm-1"}}, SkipToken: "next"}, nil)
+		client.On("Resources", mock.Anything, &QueryRequest{
+			ManagementGroups: []string{"root"},
+			Query:            vmQuery,
+			Options:          &QueryOptions{Top: pageSize, SkipToken: "next", ResultFormat: "objectArray"},
+		}).Return(&QueryResponse{Data: []*GraphResource{{ID: "vm-2"}}}, nil)
+
+		resources, err := provider.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Equal(t, "vm-2", resources[1].ID())
+		assert.NotNil(t, resources[1].Tags())
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Resource Containers", func(t *testing.T) {
+		client := new(MockResourceGraphClient)
+		provider := &Provider{client: client}
+
+		client.On("Resources", mock.Anything, mock.MatchedBy(func(r *QueryRequest) bool {
+			return r.Query == "ResourceContainers | where type =~ 'Microsoft.Resources/subscriptions/resourceGroups' | project id, name, type, location, subscriptionId, resourceGroup, tags | order by id asc"
+		})).Return(&QueryResponse{}, nil)
+
+		_, err := provider.FindResources(context.Background(), "Microsoft.Resources", "subscriptions/resourceGroups")
+		require.NoError(t, err)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Error Response", func(t *testing.T) {
+		client := new(MockResourceGraphClient)
+		provider := &Provider{client: client}
+
+		client.On("Resources", mock.Anything, mock.Anything).Return((*QueryResponse)(nil), errors.New("throttled"))
+
+		_, err := provider.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines")
+		assert.EqualError(t, err, "throttled")
+	})
+
+	t.Run("Invalid Type", func(t *testing.T) {
+		provider := &Provider{client: new(MockResourceGraphClient)}
+
+		_, err := provider.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines' | take 1")
+		assert.ErrorContains(t, err, "invalid Azure resource type")
+	})
+}
+
+func TestHTTPResourceGraphClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/providers/Microsoft.ResourceGraph/resources", r.URL.Path)
+		assert.Equal(t, resourceGraphAPIVersion, r.URL.Query().Get("api-version"))
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"code": "InvalidAuthenticationToken", "message": "The access token is invalid."}}`))
+			return
+		}
+
+		var request QueryRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, []string{"sub-1"}, request.Subscriptions)
+		assert.Equal(t, "objectArray", request.Options.ResultFormat)
+
+		_, _ = w.Write([]byte(`{"totalRecords": 1, "count": 1, "data": [{"id": "vm-1", "location": "eastus", "subscriptionId": "sub-1", "tags": {"env": "prod"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(context.Background(),
+		WithEndpoint(server.URL+"/"),
+		WithCredential(staticCredential("token")),
+		WithSubscriptions("sub-1"),
+	)
+	require.NoError(t, err)
+
+	resources, err := provider.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines")
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "eastus", resources[0].Region())
+	assert.Equal(t, map[string]string{"env": "prod"}, resources[0].Tags())
+
+	unauthorized, err := NewProvider(context.Background(),
+		WithEndpoint(server.URL),
+		WithCredential(staticCredential("expired")),
+		WithSubscriptions("sub-1"),
+	)
+	require.NoError(t, err)
+
+	_, err = unauthorized.FindResources(context.Background(), "Microsoft.Compute", "virtualMachines")
+	assert.ErrorContains(t, err, "InvalidAuthenticationToken: The access token is invalid.")
+}
+
+// funcCredential is an azcore.TokenCredential backed by a function
+type funcCredential func(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error)
+
+func (f funcCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return f(ctx, options)
+}
+
+func TestCredentials(t *testing.T) {
+	t.Run("Cached Token", func(t *testing.T) {
+		calls := 0
+		now := time.Unix(1700000000, 0)
+
+		credential := newCachedCredential(funcCredential(func(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+			calls++
+			assert.Equal(t, []string{"https://management.core.windows.net/.default"}, options.Scopes)
+			return azcore.AccessToken{Token: "token", ExpiresOn: now.Add(time.Hour)}, nil
+		}), resourceManagerScope(cloud.AzurePublic))
+		credential.now = func() time.Time { return now }
+
+		for range 2 {
+			token, err := credential.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "token", token)
+		}
+		assert.Equal(t, 1, calls)
+
+		now = now.Add(56 * time.Minute)
+		_, err := credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Token Error", func(t *testing.T) {
+		credential := newCachedCredential(funcCredential(func(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+			return azcore.AccessToken{}, errors.New("run `az login` first")
+		}), "scope")
+
+		_, err := credential.Token(context.Background())
+		assert.ErrorContains(t, err, "error getting Azure access token: run `az login` first")
+	})
+
+	t.Run("Cloud Scopes", func(t *testing.T) {
+		for azureCloud, scope := range map[Cloud]string{
+			CloudPublic:       "https://management.core.windows.net/.default",
+			CloudChina:        "https://management.core.chinacloudapi.cn/.default",
+			CloudUSGovernment: "https://management.core.usgovcloudapi.net/.default",
+		} {
+			cfg, err := azureCloud.configuration()
+			require.NoError(t, err)
+			assert.Equal(t, scope, resourceManagerScope(cfg))
+		}
+
+		_, err := Cloud("moon").configuration()
+		assert.ErrorContains(t, err, "unsupported cloud `moon`")
+	})
+
+	t.Run("New Credential", func(t *testing.T) {
+		t.Setenv("AZURE_TENANT_ID", "")
+		t.Setenv("AZURE_CLIENT_ID", "")
+		t.Setenv("AZURE_CLIENT_SECRET", "")
+
+		_, err := NewCredential(AuthEnvironment, CloudPublic)
+		assert.ErrorContains(t, err, "AZURE_TENANT_ID")
+
+		for _, method := range []AuthMethod{AuthAuto, AuthCLI, AuthManagedIdentity} {
+			credential, err := NewCredential(method, CloudChina)
+			require.NoError(t, err)
+			assert.Equal(t, "https://management.core.chinacloudapi.cn/.default", credential.(*cachedCredential).scope)
+		}
+
+		t.Setenv("AZURE_TENANT_ID", "tenant")
+		t.Setenv("AZURE_CLIENT_ID", "client")
+		t.Setenv("AZURE_CLIENT_SECRET", "secret")
+
+		credential, err := NewCredential(AuthEnvironment, CloudPublic)
+		require.NoError(t, err)
+		assert.IsType(t, &azidentity.EnvironmentCredential{}, credential.(*cachedCredential).credential)
+
+		_, err = NewCredential("password", CloudPublic)
+		assert.ErrorContains(t, err, "unsupported auth method `password`")
+	})
+}
+
+func TestProviderCloud(t *testing.T) {
+	provider, err := NewProvider(context.Background(), WithCloud(CloudUSGovernment), WithCredential(staticCredential("token")))
+	require.NoError(t, err)
+	assert.Equal(t, "https://management.usgovcloudapi.net", provider.client.(*HTTPResourceGraphClient).endpoint)
+
+	_, err = NewProvider(context.Background(), WithCloud("moon"), WithCredential(staticCredential("token")))
+	assert.ErrorContains(t, err, "unsupported cloud `moon`")
+}
+
+func TestThrottledQuery(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.Header().Set("x-ms-user-quota-resets-after", "00:00:03")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error": {"code": "RateLimiting", "message": "Please provide below info when asking for support"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [{"id": "vm-1"}]}`))
+	}))
+	defer server.Close()
+
+	newClient := func(maxRetries int) (*HTTPResourceGraphClient, *[]time.Duration) {
+		var delays []time.Duration
+		return &HTTPResourceGraphClient{
+			endpoint:   server.URL,
+			credential: staticCredential("token"),
+			http:       server.Client(),
+			maxRetries: maxRetries,
+			sleep: func(ctx context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			},
+		}, &delays
+	}
+
+	client, delays := newClient(DefaultMaxRetries)
+	resp, err := client.Resources(context.Background(), &QueryRequest{Query: "Resources"})
+	require.NoError(t, err)
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, []time.Duration{3 * time.Second, 3 * time.Second}, *delays)
+
+	requests = 0
+	client, _ = newClient(1)
+	_, err = client.Resources(context.Background(), &QueryRequest{Query: "Resources"})
+	assert.ErrorContains(t, err, "429 Too Many Requests: RateLimiting")
+	assert.Equal(t, 2, requests)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, retryMaxDelay, retryDelay(http.Header{"X-Ms-User-Quota-Resets-After": {"01:00:00"}}, 0))
+	assert.Equal(t, 7*time.Second, retryDelay(http.Header{"Retry-After": {"7"}}, 0))
+	assert.Equal(t, time.Second, retryDelay(http.Header{}, 0))
+	assert.Equal(t, 8*time.Second, retryDelay(http.Header{}, 3))
+	assert.Equal(t, retryMaxDelay, retryDelay(http.Header{}, 10))
+}
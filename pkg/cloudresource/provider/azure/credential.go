@@ -0,0 +1,137 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	// registers the Resource Manager endpoint and audience of every cloud
+	_ "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is refreshed
+const tokenRefreshMargin = 5 * time.Minute
+
+// Credential defines the interface for obtaining Azure Resource Manager access tokens
+type Credential interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// AuthMethod selects how the provider authenticates
+type AuthMethod string
+
+const (
+	// AuthAuto tries the environment, workload identity, managed identity and the Azure CLI in turn
+	AuthAuto AuthMethod = "auto"
+	// AuthEnvironment uses a service principal from AZURE_TENANT_ID, AZURE_CLIENT_ID and
+	// AZURE_CLIENT_SECRET or AZURE_CLIENT_CERTIFICATE_PATH
+	AuthEnvironment AuthMethod = "env"
+	// AuthCLI uses the account logged in with `az login`
+	AuthCLI AuthMethod = "cli"
+	// AuthManagedIdentity uses the managed identity of the Azure host
+	AuthManagedIdentity AuthMethod = "managed-identity"
+)
+
+// Cloud selects the Azure cloud the provider authenticates against and queries
+type Cloud string
+
+const (
+	// CloudPublic is the Azure public cloud
+	CloudPublic Cloud = "public"
+	// CloudChina is Azure operated by 21Vianet
+	CloudChina Cloud = "china"
+	// CloudUSGovernment is Azure Government
+	CloudUSGovernment Cloud = "usgovernment"
+)
+
+// configuration returns the authority host and Resource Manager settings of the cloud
+func (c Cloud) configuration() (cloud.Configuration, error) {
+	switch c {
+	case CloudPublic, "":
+		return cloud.AzurePublic, nil
+	case CloudChina:
+		return cloud.AzureChina, nil
+	case CloudUSGovernment:
+		return cloud.AzureGovernment, nil
+	default:
+		return cloud.Configuration{}, fmt.Errorf("unsupported cloud `%s`, must be one of: %s, %s, %s", c, CloudPublic, CloudChina, CloudUSGovernment)
+	}
+}
+
+// resourceManagerScope returns the OAuth scope of the Resource Manager API of a cloud
+func resourceManagerScope(cfg cloud.Configuration) string {
+	return strings.TrimSuffix(cfg.Services[cloud.ResourceManager].Audience, "/") + "/.default"
+}
+
+// NewCredential creates the credential for the given authentication method, requesting
+// Resource Manager tokens from the authority of the given cloud
+func NewCredential(method AuthMethod, azureCloud Cloud) (Credential, error) {
+	cfg, err := azureCloud.configuration()
+	if err != nil {
+		return nil, err
+	}
+	options := azcore.ClientOptions{Cloud: cfg}
+
+	var credential azcore.TokenCredential
+	switch method {
+	case AuthAuto, "":
+		credential, err = azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: options})
+	case AuthEnvironment:
+		credential, err = azidentity.NewEnvironmentCredential(&azidentity.EnvironmentCredentialOptions{ClientOptions: options})
+	case AuthCLI:
+		credential, err = azidentity.NewAzureCLICredential(nil)
+	case AuthManagedIdentity:
+		miOptions := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: options}
+		if clientID := os.Getenv("AZURE_CLIENT_ID"); clientID != "" {
+			miOptions.ID = azidentity.ClientID(clientID)
+		}
+		credential, err = azidentity.NewManagedIdentityCredential(miOptions)
+	default:
+		return nil, fmt.Errorf("unsupported auth method `%s`, must be one of: %s, %s, %s, %s", method, AuthAuto, AuthEnvironment, AuthCLI, AuthManagedIdentity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newCachedCredential(credential, resourceManagerScope(cfg)), nil
+}
+
+// cachedCredential requests tokens for a single scope and reuses them until shortly
+// before they expire
+type cachedCredential struct {
+	credential azcore.TokenCredential
+	scope      string
+	now        func() time.Time
+
+	mu    sync.Mutex
+	token *azcore.AccessToken
+}
+
+func newCachedCredential(credential azcore.TokenCredential, scope string) *cachedCredential {
+	return &cachedCredential{credential: credential, scope: scope, now: time.Now}
+}
+
+// Token returns a valid access token, requesting a new one when needed
+func (c *cachedCredential) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && c.now().Add(tokenRefreshMargin).Before(c.token.ExpiresOn) {
+		return c.token.Token, nil
+	}
+
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{c.scope}})
+	if err != nil {
+		return "", fmt.Errorf("error getting Azure access token: %w", err)
+	}
+
+	c.token = &token
+	return token.Token, nil
+}
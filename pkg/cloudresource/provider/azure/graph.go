@@ -0,0 +1,371 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// ProviderName is reported as the provider of Azure resources
+const ProviderName = "azure"
+
+// DefaultMaxRetries is the number of times a throttled Resource Graph query is retried
+const DefaultMaxRetries = 5
+
+// resourceGraphAPIVersion is the Resource Graph REST API version used by the client
+const resourceGraphAPIVersion = "2022-10-01"
+
+// pageSize is the number of resources requested per query page
+const pageSize = 1000
+
+// retryBaseDelay and retryMaxDelay bound the delay between throttled attempts when
+// Resource Graph doesn't say when the quota resets
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = time.Minute
+)
+
+// validTypeName guards the resource type interpolated into Resource Graph queries
+var validTypeName = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+// QueryRequest is a Resource Graph query request
+type QueryRequest struct {
+	Subscriptions    []string      `json:"subscriptions,omitempty"`
+	ManagementGroups []string      `json:"managementGroups,omitempty"`
+	Query            string        `json:"query"`
+	Options          *QueryOptions `json:"options,omitempty"`
+}
+
+// QueryOptions holds the paging options of a Resource Graph query
+type QueryOptions struct {
+	Top          int    `json:"$top,omitempty"`
+	SkipToken    string `json:"$skipToken,omitempty"`
+	ResultFormat string `json:"resultFormat,omitempty"`
+}
+
+// QueryResponse is a page of Resource Graph query results
+type QueryResponse struct {
+	TotalRecords int64            `json:"totalRecords"`
+	Count        int64            `json:"count"`
+	Data         []*GraphResource `json:"data"`
+	SkipToken    string           `json:"$skipToken"`
+}
+
+// GraphResource is a single resource row returned by the provider queries
+type GraphResource struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	Location       string            `json:"location"`
+	SubscriptionID string            `json:"subscriptionId"`
+	ResourceGroup  string            `json:"resourceGroup"`
+	Tags           map[string]string `json:"tags"`
+}
+
+// ResourceGraphClient defines the interface for Azure Resource Graph API interactions
+type ResourceGraphClient interface {
+	Resources(ctx context.Context, request *QueryRequest) (*QueryResponse, error)
+}
+
+// Provider implements the CloudResource Finder interface for Azure
+type Provider struct {
+	client           ResourceGraphClient
+	subscriptions    []string
+	managementGroups []string
+}
+
+type providerConfig struct {
+	subscriptions    []string
+	managementGroups []string
+	authMethod       AuthMethod
+	cloud            Cloud
+	credential       Credential
+	endpoint         string
+	httpClient       *http.Client
+	maxRetries       int
+}
+
+// Option is a function that configures the Azure provider
+type Option func(*providerConfig)
+
+// WithSubscriptions limits the search to the given subscription IDs
+func WithSubscriptions(subscriptions ...string) Option {
+	return func(c *providerConfig) {
+		c.subscriptions = append(c.subscriptions, subscriptions...)
+	}
+}
+
+// WithManagementGroups limits the search to the given management groups
+func WithManagementGroups(groups ...string) Option {
+	return func(c *providerConfig) {
+		c.managementGroups = append(c.managementGroups, groups...)
+	}
+}
+
+// WithAuthMethod sets how the provider authenticates, AuthAuto by default
+func WithAuthMethod(method AuthMethod) Option {
+	return func(c *providerConfig) {
+		c.authMethod = method
+	}
+}
+
+// WithCredential sets the credential to use instead of the auth method
+func WithCredential(credential Credential) Option {
+	return func(c *providerConfig) {
+		c.credential = credential
+	}
+}
+
+// WithCloud sets the Azure cloud to authenticate against and query, CloudPublic by default
+func WithCloud(azureCloud Cloud) Option {
+	return func(c *providerConfig) {
+		c.cloud = azureCloud
+	}
+}
+
+// WithEndpoint overrides the Azure Resource Manager endpoint of the cloud, e.g. for a proxy
+func WithEndpoint(endpoint string) Option {
+	return func(c *providerConfig) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for Resource Graph requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *providerConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithMaxRetries sets how many times a throttled query is retried, DefaultMaxRetries by default
+func WithMaxRetries(maxRetries int) Option {
+	return func(c *providerConfig) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// NewProvider creates a new Azure provider with the specified options. Without
+// subscriptions or management groups, all subscriptions the identity can read are searched.
+func NewProvider(ctx context.Context, opts ...Option) (*Provider, error) {
+	cfg := &providerConfig{
+		authMethod: AuthAuto,
+		cloud:      CloudPublic,
+		httpClient: http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.maxRetries < 0 {
+		return nil, fmt.Errorf("max retries must not be negative")
+	}
+
+	cloudConfig, err := cfg.cloud.configuration()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.endpoint == "" {
+		cfg.endpoint = cloudConfig.Services[cloud.ResourceManager].Endpoint
+	}
+
+	if cfg.credential == nil {
+		credential, err := NewCredential(cfg.authMethod, cfg.cloud)
+		if err != nil {
+			return nil, fmt.Errorf("error creating Azure credential: %w", err)
+		}
+		cfg.credential = credential
+	}
+
+	return &Provider{
+		client: &HTTPResourceGraphClient{
+			endpoint:   strings.TrimSuffix(cfg.endpoint, "/"),
+			credential: cfg.credential,
+			http:       cfg.httpClient,
+			maxRetries: cfg.maxRetries,
+			sleep:      sleep,
+		},
+		subscriptions:    cfg.subscriptions,
+		managementGroups: cfg.managementGroups,
+	}, nil
+}
+
+// FindResources searches for Azure resources of the specified resource provider namespace
+// and resource type, e.g. `Microsoft.Compute` and `virtualMachines`
+func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	fullType := serviceName + "/" + resourceName
+	if !validTypeName.MatchString(fullType) {
+		return nil, fmt.Errorf("invalid Azure resource type `%s`", fullType)
+	}
+
+	// resource groups and subscriptions live in a separate table
+	table := "Resources"
+	if strings.EqualFold(serviceName, "Microsoft.Resources") {
+		table = "ResourceContainers"
+	}
+
+	request := &QueryRequest{
+		Subscriptions:    p.subscriptions,
+		ManagementGroups: p.managementGroups,
+		Query: fmt.Sprintf("%s | where type =~ '%s' | project id, name, type, location, subscriptionId, resourceGroup, tags | order by id asc",
+			table, fullType),
+		Options: &QueryOptions{Top: pageSize, ResultFormat: "objectArray"},
+	}
+
+	var resources []cr.CloudResource
+
+	for {
+		resp, err := p.client.Resources(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range resp.Data {
+			resource := &cr.GenericResource{
+				ResourceID:     r.ID,
+				ResourceType:   r.Type,
+				ServiceName:    serviceName,
+				ProviderName:   ProviderName,
+				ResourceRegion: r.Location,
+				Owner:          r.SubscriptionID,
+				ResourceTags:   r.Tags,
+			}
+			if resource.ResourceTags == nil {
+				resource.ResourceTags = make(map[string]string)
+			}
+
+			resources = append(resources, resource)
+		}
+
+		if resp.SkipToken == "" {
+			break
+		}
+		request.Options.SkipToken = resp.SkipToken
+	}
+
+	return resources, nil
+}
+
+// HTTPResourceGraphClient implements the ResourceGraphClient interface over the REST API
+type HTTPResourceGraphClient struct {
+	endpoint   string
+	credential Credential
+	http       *http.Client
+	maxRetries int
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// Resources runs a Resource Graph query, retrying it when throttled
+func (c *HTTPResourceGraphClient) Resources(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		out, delay, err := c.query(ctx, body, attempt)
+		if delay == 0 || attempt >= c.maxRetries {
+			return out, err
+		}
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// query sends a single query request. A throttled request returns the delay before it
+// may be retried along with the error.
+func (c *HTTPResourceGraphClient) query(ctx context.Context, body []byte, attempt int) (*QueryResponse, time.Duration, error) {
+	token, err := c.credential.Token(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	url := c.endpoint + "/providers/Microsoft.ResourceGraph/resources?api-version=" + resourceGraphAPIVersion
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var delay time.Duration
+		if resp.StatusCode == http.StatusTooManyRequests {
+			delay = retryDelay(resp.Header, attempt)
+		}
+
+		var apiError struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		content, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(content, &apiError) == nil && apiError.Error.Message != "" {
+			return nil, delay, fmt.Errorf("resource graph query failed: %s: %s: %s", resp.Status, apiError.Error.Code, apiError.Error.Message)
+		}
+		return nil, delay, fmt.Errorf("resource graph query failed: %s", resp.Status)
+	}
+
+	var out QueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, 0, fmt.Errorf("error decoding resource graph response: %w", err)
+	}
+
+	return &out, 0, nil
+}
+
+// retryDelay returns how long to wait before retrying a throttled query: until the user
+// quota resets when Resource Graph reports it, otherwise the Retry-After header or an
+// exponential backoff
+func retryDelay(header http.Header, attempt int) time.Duration {
+	// x-ms-user-quota-resets-after is formatted as hh:mm:ss
+	if resets := header.Get("x-ms-user-quota-resets-after"); resets != "" {
+		var h, m, sec int
+		if _, err := fmt.Sscanf(resets, "%d:%d:%d", &h, &m, &sec); err == nil {
+			if d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second; d > 0 {
+				return min(d, retryMaxDelay)
+			}
+		}
+	}
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, retryMaxDelay)
+	}
+
+	if attempt >= 6 {
+		return retryMaxDelay
+	}
+	return min(retryBaseDelay<<attempt, retryMaxDelay)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package cloudformation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAMLTemplate = `AWSTemplateFormatVersion: 2010-09-09
+Parameters:
+  Env:
+    Type: String
+Resources:
+  WebServer:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-123
+      Tags:
+        - Key: Name
+          Value: web
+        - Key: env
+          Value: !Ref Env
+        - Key: !Sub "${Env}-key"
+          Value: skipped
+        - Key: cost-center
+          Value: 1234
+  WebSecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      Tags: !If
+        - IsProd
+        - [{Key: env, Value: prod}]
+        - !Ref AWS::NoValue
+  Parameter:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Tags:
+        owner: team-a
+        env:
+          Fn::Sub: "${Env}"
+  Database:
+    Type: AWS::RDS::DBInstance
+  Custom:
+    Type: Custom::Thing
+`
+
+const testJSONTemplate = `{
+	"Resources": {
+		"Bucket": {
+			"Type": "AWS::S3::Bucket",
+			"Properties": {
+				"Tags": [
+					{"Key": "owner", "Value": "team-b"},
+					{"Key": "env", "Value": {"Fn::Sub": "${Env}"}}
+				]
+			}
+		}
+	}
+}`
+
+func writeTemplate(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLookupType(t *testing.T) {
+	tests := []struct {
+		cfnType      string
+		service      string
+		resourceType string
+		ok           bool
+	}{
+		{cfnType: "AWS::EC2::Instance", service: "ec2", resourceType: "instance", ok: true},
+		{cfnType: "AWS::EC2::SecurityGroup", service: "ec2", resourceType: "security-group", ok: true},
+		{cfnType: "AWS::Logs::LogGroup", service: "logs", resourceType: "log-group", ok: true},
+		{cfnType: "AWS::RDS::DBProxy", service: "rds", resourceType: "db-proxy", ok: true},
+		{cfnType: "AWS::RDS::DBInstance", service: "rds", resourceType: "db", ok: true},
+		{cfnType: "AWS::Serverless::Function", service: "lambda", resourceType: "function", ok: true},
+		{cfnType: "Custom::Thing"},
+		{cfnType: "AWS::Invalid"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.cfnType, func(t *testing.T) {
+			service, resourceType, ok := lookupType(tc.cfnType, nil)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.service, service)
+			assert.Equal(t, tc.resourceType, resourceType)
+		})
+	}
+
+	service, resourceType, ok := lookupType("AWS::EC2::Instance", map[string]string{"AWS::EC2::Instance": "custom:server"})
+	assert.True(t, ok)
+	assert.Equal(t, "custom", service)
+	assert.Equal(t, "server", resourceType)
+}
+
+func TestYAMLTemplate(t *testing.T) {
+	provider, err := NewProvider(writeTemplate(t, "template.yaml", testYAMLTemplate))
+	require.NoError(t, err)
+	assert.Len(t, provider.records, 4)
+
+	t.Run("List Tags", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		assert.Equal(t, "WebServer", resources[0].ID())
+		assert.Equal(t, "AWS::EC2::Instance", resources[0].Type())
+		assert.Equal(t, "ec2", resources[0].Service())
+		assert.Equal(t, "aws", resources[0].Provider())
+		assert.Equal(t, map[string]string{"Name": "web", "env": UnresolvedValue, "cost-center": "1234"}, resources[0].Tags())
+		assert.Equal(t, []string{"env"}, resources[0].(*cr.GenericResource).UnknownValues)
+	})
+
+	t.Run("Intrinsic Tags", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "ec2", "security-group")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Empty(t, resources[0].Tags())
+	})
+
+	t.Run("Map Tags", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "ssm", "parameter")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, map[string]string{"owner": "team-a", "env": UnresolvedValue}, resources[0].Tags())
+	})
+
+	t.Run("No Tags", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "rds", "db")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Empty(t, resources[0].Tags())
+	})
+}
+
+func TestJSONTemplate(t *testing.T) {
+	provider, err := NewProvider(writeTemplate(t, "template.json", testJSONTemplate))
+	require.NoError(t, err)
+
+	resources, err := provider.FindResources(context.Background(), "s3", "bucket")
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "Bucket", resources[0].ID())
+	assert.Equal(t, map[string]string{"owner": "team-b", "env": UnresolvedValue}, resources[0].Tags())
+}
+
+func TestStackSetTemplate(t *testing.T) {
+	provider, err := NewProvider(filepath.Join("..", "..", "..", "..", "cfn", "stackset.yaml"))
+	require.NoError(t, err)
+
+	resources, err := provider.FindResources(context.Background(), "resource-explorer-2", "view")
+	require.NoError(t, err)
+	assert.Len(t, resources, 2)
+}
+
+func TestLoadTemplateErrors(t *testing.T) {
+	cfg := &providerConfig{}
+
+	_, err := loadTemplate(strings.NewReader("Description: empty\n"), cfg)
+	assert.ErrorContains(t, err, "template has no Resources section")
+
+	_, err = loadTemplate(strings.NewReader("Resources:\n  Thing:\n    Properties: {}\n"), cfg)
+	assert.ErrorContains(t, err, "resource `Thing` has no Type")
+
+	_, err = loadTemplate(strings.NewReader("{"), cfg)
+	assert.ErrorContains(t, err, "error decoding JSON template")
+
+	_, err = loadTemplate(strings.NewReader("Resources: [\n"), cfg)
+	assert.ErrorContains(t, err, "error decoding YAML template")
+
+	_, err = NewProvider(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "error opening template file")
+}
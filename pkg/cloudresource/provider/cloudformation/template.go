@@ -0,0 +1,244 @@
+package cloudformation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderName is reported as the provider of CloudFormation template resources
+const ProviderName = "aws"
+
+// UnresolvedValue is the value of tags set by intrinsic functions, e.g. `!Ref` or `!Sub`
+const UnresolvedValue = "(unresolved)"
+
+// record is a single resource declared in a template
+type record struct {
+	logicalID    string
+	cfnType      string
+	service      string
+	resourceType string
+	tags         map[string]string
+}
+
+// Provider implements the CloudResource Finder interface for CloudFormation templates
+type Provider struct {
+	records []*record
+}
+
+type providerConfig struct {
+	typeMapping map[string]string
+}
+
+// Option is a function that configures the CloudFormation provider
+type Option func(*providerConfig)
+
+// WithTypeMapping adds or overrides CloudFormation resource type to `service:type` mappings
+func WithTypeMapping(mapping map[string]string) Option {
+	return func(c *providerConfig) {
+		maps.Copy(c.typeMapping, mapping)
+	}
+}
+
+// NewProvider creates a new provider with the resources declared in a CloudFormation
+// template in YAML or JSON format
+func NewProvider(path string, opts ...Option) (*Provider, error) {
+	cfg := &providerConfig{typeMapping: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening template file: %w", err)
+	}
+	defer f.Close()
+
+	records, err := loadTemplate(f, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error loading template file %s: %w", path, err)
+	}
+
+	return &Provider{records: records}, nil
+}
+
+// FindResources returns the template resources of the specified service and resource type,
+// identified by their logical ID. Unresolved tag values are reported as unknown values.
+func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	var resources []cr.CloudResource
+
+	for _, rec := range p.records {
+		if rec.service != serviceName || rec.resourceType != resourceName {
+			continue
+		}
+
+		resource := &cr.GenericResource{
+			ResourceID:   rec.logicalID,
+			ResourceType: rec.cfnType,
+			ServiceName:  rec.service,
+			ProviderName: ProviderName,
+			ResourceTags: make(map[string]string, len(rec.tags)),
+		}
+
+		maps.Copy(resource.ResourceTags, rec.tags)
+		for key, value := range rec.tags {
+			if value == UnresolvedValue {
+				resource.UnknownValues = append(resource.UnknownValues, key)
+			}
+		}
+		slices.Sort(resource.UnknownValues)
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func loadTemplate(r io.Reader, cfg *providerConfig) ([]*record, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseTemplate(content)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := mappingValue(root, "Resources")
+	if resources == nil || resources.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("template has no Resources section")
+	}
+
+	var records []*record
+
+	for i := 0; i+1 < len(resources.Content); i += 2 {
+		logicalID, definition := resources.Content[i].Value, resources.Content[i+1]
+
+		typeNode := mappingValue(definition, "Type")
+		if typeNode == nil || !isLiteral(typeNode) {
+			return nil, fmt.Errorf("resource `%s` has no Type", logicalID)
+		}
+
+		service, resourceType, ok := lookupType(typeNode.Value, cfg.typeMapping)
+		if !ok {
+			continue
+		}
+
+		records = append(records, &record{
+			logicalID:    logicalID,
+			cfnType:      typeNode.Value,
+			service:      service,
+			resourceType: resourceType,
+			tags:         resolveTags(mappingValue(mappingValue(definition, "Properties"), "Tags")),
+		})
+	}
+
+	return records, nil
+}
+
+// parseTemplate parses a YAML or JSON template into a YAML node tree. JSON templates
+// are decoded with encoding/json first as they are not always valid YAML.
+func parseTemplate(content []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		var v any
+		if err := json.Unmarshal(trimmed, &v); err != nil {
+			return nil, fmt.Errorf("error decoding JSON template: %w", err)
+		}
+		if err := doc.Encode(v); err != nil {
+			return nil, fmt.Errorf("error decoding JSON template: %w", err)
+		}
+		return &doc, nil
+	}
+
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding YAML template: %w", err)
+	}
+
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0], nil
+	}
+
+	return &doc, nil
+}
+
+// resolveTags reads the literal tags of a `Tags` property, either a list of Key/Value
+// pairs or a map of keys to values. Tags with an intrinsic key are skipped and tags
+// with an intrinsic value are set to UnresolvedValue.
+func resolveTags(node *yaml.Node) map[string]string {
+	tags := make(map[string]string)
+	if node == nil {
+		return tags
+	}
+
+	switch {
+	case node.Kind == yaml.SequenceNode && node.Tag == "!!seq":
+		for _, item := range node.Content {
+			key, value := mappingValue(item, "Key"), mappingValue(item, "Value")
+			if key == nil || !isLiteral(key) {
+				continue
+			}
+			tags[key.Value] = tagValue(value)
+		}
+	case node.Kind == yaml.MappingNode && node.Tag == "!!map" && !isIntrinsic(node):
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; isLiteral(key) {
+				tags[key.Value] = tagValue(node.Content[i+1])
+			}
+		}
+	}
+
+	return tags
+}
+
+func tagValue(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	if isLiteral(node) {
+		return node.Value
+	}
+	return UnresolvedValue
+}
+
+// isLiteral returns true for plain scalar values, i.e. not short form intrinsic functions like `!Ref`
+func isLiteral(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && strings.HasPrefix(node.Tag, "!!") && node.Tag != "!!null"
+}
+
+// isIntrinsic returns true for long form intrinsic functions, e.g. `{"Fn::If": [...]}` or `{"Ref": "Name"}`
+func isIntrinsic(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return false
+	}
+
+	key := node.Content[0].Value
+	return key == "Ref" || key == "Condition" || strings.HasPrefix(key, "Fn::")
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
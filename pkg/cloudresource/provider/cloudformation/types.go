@@ -0,0 +1,81 @@
+package cloudformation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// resourceTypes maps CloudFormation resource types whose Resource Explorer name does not
+// follow the generic conversion to Resource Explorer style `service:type` names
+var resourceTypes = map[string]string{
+	"AWS::ApiGateway::RestApi":                  "apigateway:restapis",
+	"AWS::AutoScaling::AutoScalingGroup":        "autoscaling:autoScalingGroup",
+	"AWS::CertificateManager::Certificate":      "acm:certificate",
+	"AWS::CloudWatch::Alarm":                    "cloudwatch:alarm",
+	"AWS::EC2::EIP":                             "ec2:elastic-ip",
+	"AWS::EC2::NatGateway":                      "ec2:natgateway",
+	"AWS::EC2::VPC":                             "ec2:vpc",
+	"AWS::EC2::VPCEndpoint":                     "ec2:vpc-endpoint",
+	"AWS::EFS::FileSystem":                      "elasticfilesystem:file-system",
+	"AWS::EKS::Nodegroup":                       "eks:nodegroup",
+	"AWS::ElastiCache::CacheCluster":            "elasticache:cluster",
+	"AWS::ElasticLoadBalancing::LoadBalancer":   "elasticloadbalancing:loadbalancer",
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": "elasticloadbalancing:loadbalancer",
+	"AWS::ElasticLoadBalancingV2::TargetGroup":  "elasticloadbalancing:targetgroup",
+	"AWS::Elasticsearch::Domain":                "es:domain",
+	"AWS::KinesisFirehose::DeliveryStream":      "firehose:deliverystream",
+	"AWS::OpenSearchService::Domain":            "es:domain",
+	"AWS::RDS::DBCluster":                       "rds:cluster",
+	"AWS::RDS::DBInstance":                      "rds:db",
+	"AWS::RDS::DBSubnetGroup":                   "rds:subgrp",
+	"AWS::ResourceExplorer2::Index":             "resource-explorer-2:index",
+	"AWS::ResourceExplorer2::View":              "resource-explorer-2:view",
+	"AWS::Route53::HostedZone":                  "route53:hostedzone",
+	"AWS::SageMaker::NotebookInstance":          "sagemaker:notebook-instance",
+	"AWS::Serverless::Api":                      "apigateway:restapis",
+	"AWS::Serverless::Function":                 "lambda:function",
+	"AWS::Serverless::SimpleTable":              "dynamodb:table",
+	"AWS::Serverless::StateMachine":             "states:stateMachine",
+	"AWS::StepFunctions::StateMachine":          "states:stateMachine",
+}
+
+// lookupType returns the Resource Explorer style service and resource type for a
+// CloudFormation resource type, using the custom mapping, then the built-in one and
+// finally the generic conversion, e.g. `AWS::EC2::SecurityGroup` to `ec2:security-group`
+func lookupType(cfnType string, custom map[string]string) (service, resourceType string, ok bool) {
+	name, ok := custom[cfnType]
+	if !ok {
+		name, ok = resourceTypes[cfnType]
+	}
+	if ok {
+		service, resourceType, ok = strings.Cut(name, ":")
+		return service, resourceType, ok
+	}
+
+	parts := strings.Split(cfnType, "::")
+	if len(parts) != 3 || parts[0] != "AWS" || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+
+	return strings.ToLower(parts[1]), kebabCase(parts[2]), true
+}
+
+// kebabCase converts a PascalCase name to kebab case, keeping acronyms together,
+// e.g. `SecurityGroup` to `security-group` and `DBProxy` to `db-proxy`
+func kebabCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteRune('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
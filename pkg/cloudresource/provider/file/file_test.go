@@ -0,0 +1,141 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeInventory(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("JSON Lines", func(t *testing.T) {
+		path := writeInventory(t, "inventory.jsonl", `{"id": "i-1", "type": "instance", "service": "ec2", "region": "us-east-1", "owner": "123", "tags": {"env": "prod"}}
+
+{"id": "bucket-1", "type": "s3:bucket", "service": "s3", "provider": "cmdb"}
+`)
+
+		provider, err := NewProvider(path)
+		require.NoError(t, err)
+		assert.Len(t, provider.records, 2)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		path := writeInventory(t, "inventory.csv", "\ufeffid,type,service,region,owner,tags,tag:team,notes\n"+
+			"i-1,instance,ec2,us-east-1,123,env=prod;owner=alice,platform,ignored\n"+
+			"i-2,instance,ec2,us-east-1,123,,,\n")
+
+		provider, err := NewProvider(path)
+		require.NoError(t, err)
+		require.Len(t, provider.records, 2)
+		assert.Equal(t, map[string]string{"env": "prod", "owner": "alice", "team": "platform"}, provider.records[0].Tags)
+		assert.Empty(t, provider.records[1].Tags)
+	})
+
+	t.Run("Explicit Format", func(t *testing.T) {
+		path := writeInventory(t, "inventory.txt", "id,type,service\ni-1,instance,ec2\n")
+
+		_, err := NewProvider(path)
+		assert.Error(t, err)
+
+		provider, err := NewProvider(path, WithFormat(FormatCSV))
+		require.NoError(t, err)
+		assert.Len(t, provider.records, 1)
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := NewProvider(filepath.Join(t.TempDir(), "missing.jsonl"))
+		assert.ErrorContains(t, err, "error opening inventory file")
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		err     string
+	}{
+		{
+			name:    "Invalid JSON",
+			format:  FormatJSONLines,
+			content: `{"id": "i-1", "type": "instance", "service": "ec2"}` + "\n{",
+			err:     "line 2",
+		},
+		{
+			name:    "Missing ID",
+			format:  FormatJSONLines,
+			content: `{"type": "instance", "service": "ec2"}`,
+			err:     "line 1: missing required field `id`",
+		},
+		{
+			name:    "Missing Service Column",
+			format:  FormatCSV,
+			content: "id,type\ni-1,instance\n",
+			err:     "line 2: missing required field `service`",
+		},
+		{
+			name:    "Invalid Tags",
+			format:  FormatCSV,
+			content: "id,type,service,tags\ni-1,instance,ec2,env\n",
+			err:     "invalid tag `env`",
+		},
+		{
+			name:    "Unsupported Format",
+			format:  "xml",
+			content: "",
+			err:     "unsupported inventory format `xml`",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(strings.NewReader(tc.content), tc.format)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestFindResources(t *testing.T) {
+	provider := &Provider{records: []*Record{
+		{ID: "i-1", Type: "instance", Service: "ec2", Region: "us-east-1", Owner: "123", Tags: map[string]string{"env": "prod"}},
+		{ID: "i-2", Type: "ec2:instance", Service: "ec2", Provider: "cmdb"},
+		{ID: "vol-1", Type: "volume", Service: "ec2"},
+		{ID: "bucket-1", Type: "bucket", Service: "s3"},
+	}}
+
+	resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+
+	assert.Equal(t, "i-1", resources[0].ID())
+	assert.Equal(t, "instance", resources[0].Type())
+	assert.Equal(t, "ec2", resources[0].Service())
+	assert.Equal(t, DefaultProviderName, resources[0].Provider())
+	assert.Equal(t, "us-east-1", resources[0].Region())
+	assert.Equal(t, "123", resources[0].OwnerID())
+	assert.Equal(t, map[string]string{"env": "prod"}, resources[0].Tags())
+	assert.Equal(t, "cmdb", resources[1].Provider())
+
+	// every search returns fresh resources without findings from previous runs
+	resources[0].AddComplianceError(&cr.ComplianceError{Message: "error"})
+	again, err := provider.FindResources(context.Background(), "ec2", "instance")
+	require.NoError(t, err)
+	assert.True(t, again[0].IsCompliant())
+
+	none, err := provider.FindResources(context.Background(), "rds", "db")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
@@ -0,0 +1,280 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// Format is the format of an inventory file
+type Format string
+
+const (
+	// FormatJSONLines is one JSON object per line
+	FormatJSONLines Format = "jsonl"
+	// FormatCSV is a CSV file with a header row
+	FormatCSV Format = "csv"
+)
+
+// DefaultProviderName is reported as the provider of resources without an explicit provider
+const DefaultProviderName = "file"
+
+// tagColumnPrefix marks CSV columns that hold the value of a single tag, e.g. `tag:owner`
+const tagColumnPrefix = "tag:"
+
+// Record is a single resource in an inventory file
+type Record struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Service  string            `json:"service"`
+	Region   string            `json:"region"`
+	Owner    string            `json:"owner"`
+	Provider string            `json:"provider"`
+	Tags     map[string]string `json:"tags"`
+}
+
+// Provider implements the CloudResource Finder interface for inventory files
+type Provider struct {
+	records []*Record
+}
+
+type providerConfig struct {
+	format Format
+}
+
+// Option is a function that configures the file provider
+type Option func(*providerConfig)
+
+// WithFormat sets the inventory format instead of detecting it from the file extension
+func WithFormat(format Format) Option {
+	return func(c *providerConfig) {
+		c.format = format
+	}
+}
+
+// NewProvider creates a new file provider with the resources loaded from the inventory file
+func NewProvider(path string, opts ...Option) (*Provider, error) {
+	cfg := &providerConfig{}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.format == "" {
+		cfg.format = detectFormat(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening inventory file: %w", err)
+	}
+	defer f.Close()
+
+	records, err := load(f, cfg.format)
+	if err != nil {
+		return nil, fmt.Errorf("error loading inventory file %s: %w", path, err)
+	}
+
+	return &Provider{records: records}, nil
+}
+
+// FindResources returns the inventory resources of the specified service and resource type.
+// The record type may be either the bare resource type or prefixed with the service, e.g. `ec2:instance`.
+func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	var resources []cr.CloudResource
+
+	for _, record := range p.records {
+		if record.Service != serviceName {
+			continue
+		}
+		if record.Type != resourceName && record.Type != serviceName+":"+resourceName {
+			continue
+		}
+
+		resource := &cr.GenericResource{
+			ResourceID:     record.ID,
+			ResourceType:   record.Type,
+			ServiceName:    record.Service,
+			ProviderName:   record.Provider,
+			ResourceRegion: record.Region,
+			Owner:          record.Owner,
+			ResourceTags:   make(map[string]string, len(record.Tags)),
+		}
+
+		if resource.ProviderName == "" {
+			resource.ProviderName = DefaultProviderName
+		}
+
+		maps.Copy(resource.ResourceTags, record.Tags)
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func detectFormat(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSONLines
+}
+
+func load(r io.Reader, format Format) ([]*Record, error) {
+	switch format {
+	case FormatJSONLines:
+		return loadJSONLines(r)
+	case FormatCSV:
+		return loadCSV(r)
+	default:
+		return nil, fmt.Errorf("unsupported inventory format `%s`, must be one of: %s, %s", format, FormatJSONLines, FormatCSV)
+	}
+}
+
+func loadJSONLines(r io.Reader) ([]*Record, error) {
+	var records []*Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record := &Record{}
+		if err := json.Unmarshal([]byte(text), record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := record.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func loadCSV(r io.Reader) ([]*Record, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	var records []*Record
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		record, err := newCSVRecord(header, row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := record.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// newCSVRecord maps a CSV row to a record. Tags are read from a `tags` column of
+// `key=value` pairs separated by semicolons and from `tag:<key>` columns, empty
+// `tag:<key>` cells are skipped. Unknown columns are ignored.
+func newCSVRecord(header, row []string) (*Record, error) {
+	record := &Record{Tags: make(map[string]string)}
+
+	for i, column := range header {
+		value := strings.TrimSpace(row[i])
+
+		switch {
+		case column == "id":
+			record.ID = value
+		case column == "type":
+			record.Type = value
+		case column == "service":
+			record.Service = value
+		case column == "region":
+			record.Region = value
+		case column == "owner":
+			record.Owner = value
+		case column == "provider":
+			record.Provider = value
+		case column == "tags":
+			if err := parseTags(value, record.Tags); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(column, tagColumnPrefix):
+			if value != "" {
+				record.Tags[strings.TrimPrefix(column, tagColumnPrefix)] = value
+			}
+		}
+	}
+
+	return record, nil
+}
+
+func parseTags(value string, tags map[string]string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, pair := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid tag `%s`, must be in key=value format", pair)
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return nil
+}
+
+func (r *Record) validate() error {
+	switch {
+	case r.ID == "":
+		return fmt.Errorf("missing required field `id`")
+	case r.Type == "":
+		return fmt.Errorf("missing required field `type`")
+	case r.Service == "":
+		return fmt.Errorf("missing required field `service`")
+	}
+
+	return nil
+}
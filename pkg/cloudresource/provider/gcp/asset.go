@@ -0,0 +1,321 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// ProviderName is reported as the provider of Google Cloud resources
+const ProviderName = "gcp"
+
+// DefaultEndpoint is the Cloud Asset Inventory API endpoint
+const DefaultEndpoint = "https://cloudasset.googleapis.com"
+
+// pageSize is the number of resources requested per search page
+const pageSize = 500
+
+// maxLabelLength is the maximum length of label keys and values
+const maxLabelLength = 63
+
+// readMask selects the resource fields returned by the search
+const readMask = "name,assetType,project,location,labels"
+
+// validScope guards the scopes interpolated into the request path
+var validScope = regexp.MustCompile(`^(organizations|folders|projects)/[A-Za-z0-9._:-]+$`)
+
+// SearchRequest is a Cloud Asset Inventory SearchAllResources request
+type SearchRequest struct {
+	Scope      string
+	AssetTypes []string
+	PageSize   int
+	PageToken  string
+}
+
+// SearchResponse is a page of SearchAllResources results
+type SearchResponse struct {
+	Results       []*ResourceSearchResult `json:"results"`
+	NextPageToken string                  `json:"nextPageToken"`
+}
+
+// ResourceSearchResult is a single resource returned by SearchAllResources
+type ResourceSearchResult struct {
+	Name      string            `json:"name"`
+	AssetType string            `json:"assetType"`
+	Project   string            `json:"project"`
+	Location  string            `json:"location"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// AssetClient defines the interface for Cloud Asset Inventory API interactions
+type AssetClient interface {
+	SearchAllResources(ctx context.Context, request *SearchRequest) (*SearchResponse, error)
+}
+
+// Resource is a Google Cloud resource. Labels only allow lowercase keys and values
+// of a limited charset, so policy keys and values are normalized before matching.
+type Resource struct {
+	cr.GenericResource
+}
+
+// NormalizeKey maps a policy tag key to a label key
+func (r *Resource) NormalizeKey(key string) string {
+	return NormalizeLabel(key)
+}
+
+// NormalizeValue maps a policy tag value to a label value
+func (r *Resource) NormalizeValue(value string) string {
+	return NormalizeLabel(value)
+}
+
+// NormalizeLabel lowercases s, replaces characters labels do not allow with
+// underscores and truncates it to the maximum label length
+func NormalizeLabel(s string) string {
+	runes := []rune(strings.ToLower(s))
+	if len(runes) > maxLabelLength {
+		runes = runes[:maxLabelLength]
+	}
+
+	for i, c := range runes {
+		// labels allow international lowercase characters
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c >= utf8.RuneSelf {
+			continue
+		}
+		runes[i] = '_'
+	}
+	return string(runes)
+}
+
+// Provider implements the CloudResource Finder interface for Google Cloud
+type Provider struct {
+	client AssetClient
+	scopes []string
+}
+
+type providerConfig struct {
+	scopes       []string
+	authMethod   AuthMethod
+	credential   Credential
+	endpoint     string
+	quotaProject string
+	httpClient   *http.Client
+}
+
+// Option is a function that configures the Google Cloud provider
+type Option func(*providerConfig)
+
+// WithScopes sets the organizations, folders or projects to search, e.g.
+// `organizations/123`, `folders/456` or `projects/my-project`
+func WithScopes(scopes ...string) Option {
+	return func(c *providerConfig) {
+		c.scopes = append(c.scopes, scopes...)
+	}
+}
+
+// WithAuthMethod sets how the provider authenticates, AuthAuto by default
+func WithAuthMethod(method AuthMethod) Option {
+	return func(c *providerConfig) {
+		c.authMethod = method
+	}
+}
+
+// WithCredential sets the credential to use instead of the auth method
+func WithCredential(credential Credential) Option {
+	return func(c *providerConfig) {
+		c.credential = credential
+	}
+}
+
+// WithEndpoint sets the Cloud Asset Inventory endpoint, e.g. for private service connect
+func WithEndpoint(endpoint string) Option {
+	return func(c *providerConfig) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithQuotaProject sets the project billed for the API requests
+func WithQuotaProject(project string) Option {
+	return func(c *providerConfig) {
+		c.quotaProject = project
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for Cloud Asset Inventory requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *providerConfig) {
+		c.httpClient = client
+	}
+}
+
+// NewProvider creates a new Google Cloud provider with the specified options.
+// At least one organization, folder or project scope is required.
+func NewProvider(ctx context.Context, opts ...Option) (*Provider, error) {
+	cfg := &providerConfig{
+		authMethod: AuthAuto,
+		endpoint:   DefaultEndpoint,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if len(cfg.scopes) == 0 {
+		return nil, fmt.Errorf("at least one organization, folder or project scope is required")
+	}
+	for _, scope := range cfg.scopes {
+		if !validScope.MatchString(scope) {
+			return nil, fmt.Errorf("invalid scope `%s`, must be organizations/<id>, folders/<id> or projects/<id>", scope)
+		}
+	}
+
+	if cfg.credential == nil {
+		credential, err := NewCredential(ctx, cfg.authMethod)
+		if err != nil {
+			return nil, fmt.Errorf("error creating Google Cloud credential: %w", err)
+		}
+		cfg.credential = credential
+	}
+
+	return &Provider{
+		client: &HTTPAssetClient{
+			endpoint:     strings.TrimSuffix(cfg.endpoint, "/"),
+			credential:   cfg.credential,
+			quotaProject: cfg.quotaProject,
+			http:         cfg.httpClient,
+		},
+		scopes: cfg.scopes,
+	}, nil
+}
+
+// FindResources searches for Google Cloud resources of the specified service and
+// resource type, e.g. `compute` and `Instance`. A service containing a dot is used
+// as the full service name, e.g. `sqladmin.googleapis.com`.
+func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	service := serviceName
+	if !strings.Contains(service, ".") {
+		service += ".googleapis.com"
+	}
+	assetType := service + "/" + resourceName
+
+	var resources []cr.CloudResource
+	seen := make(map[string]bool)
+
+	// overlapping scopes, e.g. a folder and one of its projects, return the same resources
+	for _, scope := range p.scopes {
+		request := &SearchRequest{
+			Scope:      scope,
+			AssetTypes: []string{assetType},
+			PageSize:   pageSize,
+		}
+
+		for {
+			resp, err := p.client.SearchAllResources(ctx, request)
+			if err != nil {
+				return nil, fmt.Errorf("error searching %s: %w", scope, err)
+			}
+
+			for _, r := range resp.Results {
+				if seen[r.Name] {
+					continue
+				}
+				seen[r.Name] = true
+
+				resource := &Resource{GenericResource: cr.GenericResource{
+					ResourceID:     r.Name,
+					ResourceType:   r.AssetType,
+					ServiceName:    serviceName,
+					ProviderName:   ProviderName,
+					ResourceRegion: r.Location,
+					Owner:          strings.TrimPrefix(r.Project, "projects/"),
+					ResourceTags:   r.Labels,
+				}}
+				if resource.ResourceTags == nil {
+					resource.ResourceTags = make(map[string]string)
+				}
+
+				resources = append(resources, resource)
+			}
+
+			if resp.NextPageToken == "" {
+				break
+			}
+			request.PageToken = resp.NextPageToken
+		}
+	}
+
+	return resources, nil
+}
+
+// HTTPAssetClient implements the AssetClient interface over the REST API
+type HTTPAssetClient struct {
+	endpoint     string
+	credential   Credential
+	quotaProject string
+	http         *http.Client
+}
+
+// SearchAllResources runs a single page of a resource search
+func (c *HTTPAssetClient) SearchAllResources(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	token, err := c.credential.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"assetTypes": request.AssetTypes,
+		"readMask":   {readMask},
+	}
+	if request.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(request.PageSize))
+	}
+	if request.PageToken != "" {
+		query.Set("pageToken", request.PageToken)
+	}
+
+	url := c.endpoint + "/v1/" + request.Scope + ":searchAllResources?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if c.quotaProject != "" {
+		req.Header.Set("X-Goog-User-Project", c.quotaProject)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		content, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(content, &apiError) == nil && apiError.Error.Message != "" {
+			return nil, fmt.Errorf("asset search failed: %s: %s: %s", resp.Status, apiError.Error.Status, apiError.Error.Message)
+		}
+		return nil, fmt.Errorf("asset search failed: %s", resp.Status)
+	}
+
+	var out SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("error decoding asset search response: %w", err)
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// cloudPlatformScope is the OAuth scope requested for service account tokens
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// tokenRefreshMargin is how long before expiry a cached token is refreshed
+const tokenRefreshMargin = 5 * time.Minute
+
+// findDefaultCredentials looks up the Application Default Credentials
+var findDefaultCredentials = google.FindDefaultCredentials
+
+// Credential defines the interface for obtaining Google Cloud access tokens
+type Credential interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// AuthMethod selects how the provider authenticates
+type AuthMethod string
+
+const (
+	// AuthAuto uses GOOGLE_OAUTH_ACCESS_TOKEN when set, then Application Default Credentials,
+	// then the gcloud CLI
+	AuthAuto AuthMethod = "auto"
+	// AuthADC uses Application Default Credentials: the GOOGLE_APPLICATION_CREDENTIALS file
+	// (service account key or workload identity federation), `gcloud auth application-default
+	// login`, or the metadata server of a Google Cloud host
+	AuthADC AuthMethod = "adc"
+	// AuthGcloud uses the account logged in with `gcloud auth login`
+	AuthGcloud AuthMethod = "gcloud"
+	// AuthMetadata uses the service account attached to the GCE, GKE or Cloud Run host
+	AuthMetadata AuthMethod = "metadata"
+)
+
+// NewCredential creates the credential for the given authentication method. Tokens are
+// requested with ctx.
+func NewCredential(ctx context.Context, method AuthMethod) (Credential, error) {
+	switch method {
+	case AuthAuto, "":
+		if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+			return staticCredential(token), nil
+		}
+		credential, err := NewCredential(ctx, AuthADC)
+		// an explicitly set credentials file must be valid
+		if err == nil || os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+			return credential, err
+		}
+		return NewCredential(ctx, AuthGcloud)
+	case AuthADC:
+		credentials, err := findDefaultCredentials(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("error finding application default credentials, run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+		}
+		return newTokenSourceCredential(credentials.TokenSource), nil
+	case AuthGcloud:
+		return newTokenSourceCredential(&gcloudTokenSource{ctx: ctx}), nil
+	case AuthMetadata:
+		return newTokenSourceCredential(google.ComputeTokenSource("", cloudPlatformScope)), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth method `%s`, must be one of: %s, %s, %s, %s", method, AuthAuto, AuthADC, AuthGcloud, AuthMetadata)
+	}
+}
+
+// staticCredential is a pre-issued access token
+type staticCredential string
+
+// Token returns the access token as is
+func (c staticCredential) Token(context.Context) (string, error) {
+	return string(c), nil
+}
+
+// tokenSourceCredential gets tokens from an OAuth token source, reusing them until
+// shortly before they expire
+type tokenSourceCredential struct {
+	source oauth2.TokenSource
+}
+
+func newTokenSourceCredential(source oauth2.TokenSource) *tokenSourceCredential {
+	return &tokenSourceCredential{source: oauth2.ReuseTokenSourceWithExpiry(nil, source, tokenRefreshMargin)}
+}
+
+// Token returns a valid access token, requesting a new one when needed
+func (c *tokenSourceCredential) Token(context.Context) (string, error) {
+	token, err := c.source.Token()
+	if err != nil {
+		return "", fmt.Errorf("error getting Google Cloud access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// gcloudTokenSource uses the gcloud CLI
+type gcloudTokenSource struct {
+	ctx context.Context
+	run func(ctx context.Context) ([]byte, error)
+}
+
+func (s *gcloudTokenSource) Token() (*oauth2.Token, error) {
+	run := s.run
+	if run == nil {
+		run = func(ctx context.Context) ([]byte, error) {
+			return exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+		}
+	}
+
+	out, err := run(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error running `gcloud auth print-access-token`, run `gcloud auth login` first: %w", err)
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return nil, errors.New("gcloud returned an empty access token")
+	}
+
+	// gcloud does not report the expiry, its tokens are valid for an hour
+	return &oauth2.Token{AccessToken: token, TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+}
@@ -0,0 +1,419 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+type MockAssetClient struct {
+	mock.Mock
+}
+
+func (m *MockAssetClient) SearchAllResources(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	// copy the request as the provider reuses it across pages
+	copied := *request
+
+	args := m.Called(ctx, &copied)
+	return args.Get(0).(*SearchResponse), args.Error(1)
+}
+
+func TestFindResources(t *testing.T) {
+	t.Run("Single Resource", func(t *testing.T) {
+		client := new(MockAssetClient)
+		provider := &Provider{client: client, scopes: []string{"projects/my-project"}}
+
+		client.On("SearchAllResources", mock.Anything, &SearchRequest{
+			Scope:      "projects/my-project",
+			AssetTypes: []string{"compute.googleapis.com/Instance"},
+			PageSize:   pageSize,
+		}).Return(&SearchResponse{Results: []*ResourceSearchResult{
+			{
+				Name:      "//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/vm-1",
+				AssetType: "compute.googleapis.com/Instance",
+				Project:   "projects/123456789",
+				Location:  "us-central1-a",
+				Labels:    map[string]string{"owner": "team-a"},
+			},
+		}}, nil)
+
+		resources, err := provider.FindResources(context.Background(), "compute", "Instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		assert.Equal(t, "//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/vm-1", resources[0].ID())
+		assert.Equal(t, "compute.googleapis.com/Instance", resources[0].Type())
+		assert.Equal(t, "compute", resources[0].Service())
+		assert.Equal(t, "gcp", resources[0].Provider())
+		assert.Equal(t, "us-central1-a", resources[0].Region())
+		assert.Equal(t, "123456789", resources[0].OwnerID())
+		assert.Equal(t, map[string]string{"owner": "team-a"}, resources[0].Tags())
+		assert.Implements(t, (*cr.TagNormalizer)(nil), resources[0])
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Pagination And Overlapping Scopes", func(t *testing.T) {
+		client := new(MockAssetClient)
+		provider := &Provider{client: client, scopes: []string{"folders/1", "projects/my-project"}}
+
+		client.On("SearchAllResources", mock.Anything, &SearchRequest{
+			Scope:      "folders/1",
+			AssetTypes: []string{"storage.googleapis.com/Bucket"},
+			PageSize:   pageSize,
+		}).Return(&SearchResponse{Results: []*ResourceSearchResult{{Name: "bucket-1"}}, NextPageToken: "next"}, nil)
+		client.On("SearchAllResources", mock.Anything, &SearchRequest{
+			Scope:      "folders/1",
+			AssetTypes: []string{"storage.googleapis.com/Bucket"},
+			PageSize:   pageSize,
+			PageToken:  "next",
+		}).Return(&SearchResponse{Results: []*ResourceSearchResult{{Name: "bucket-2"}}}, nil)
+		client.On("SearchAllResources", mock.Anything, &SearchRequest{
+			Scope:      "projects/my-project",
+			AssetTypes: []string{"storage.googleapis.com/Bucket"},
+			PageSize:   pageSize,
+		}).Return(&SearchResponse{Results: []*ResourceSearchResult{{Name: "bucket-2"}, {Name: "bucket-3"}}}, nil)
+
+		resources, err := provider.FindResources(context.Background(), "storage", "Bucket")
+		require.NoError(t, err)
+		require.Len(t, resources, 3)
+		assert.Equal(t, "bucket-3", resources[2].ID())
+		assert.NotNil(t, resources[2].Tags())
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Full Service Name", func(t *testing.T) {
+		client := new(MockAssetClient)
+		provider := &Provider{client: client, scopes: []string{"organizations/1"}}
+
+		client.On("SearchAllResources", mock.Anything, mock.MatchedBy(func(r *SearchRequest) bool {
+			return r.AssetTypes[0] == "sqladmin.googleapis.com/Instance"
+		})).Return(&SearchResponse{}, nil)
+
+		_, err := provider.FindResources(context.Background(), "sqladmin.googleapis.com", "Instance")
+		require.NoError(t, err)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Error Response", func(t *testing.T) {
+		client := new(MockAssetClient)
+		provider := &Provider{client: client, scopes: []string{"projects/my-project"}}
+
+		client.On("SearchAllResources", mock.Anything, mock.Anything).Return((*SearchResponse)(nil), errors.New("quota exceeded"))
+
+		_, err := provider.FindResources(context.Background(), "compute", "Instance")
+		assert.EqualError(t, err, "error searching projects/my-project: quota exceeded")
+	})
+}
+
+func TestNormalizeLabel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"environment", "environment"},
+		{"CostCenter", "costcenter"},
+		{"cost-center_2", "cost-center_2"},
+		{"team:owner", "team_owner"},
+		{"Data Classification", "data_classification"},
+		{"équipe", "équipe"},
+		{strings.Repeat("a", 70), strings.Repeat("a", maxLabelLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeLabel(tt.input))
+		})
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider(context.Background(), WithCredential(staticCredential("token")))
+	assert.ErrorContains(t, err, "at least one organization, folder or project scope is required")
+
+	_, err = NewProvider(context.Background(), WithCredential(staticCredential("token")), WithScopes("projects/a/zones/b"))
+	assert.ErrorContains(t, err, "invalid scope `projects/a/zones/b`")
+}
+
+func TestHTTPAssetClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/organizations/42:searchAllResources", r.URL.Path)
+		assert.Equal(t, "compute.googleapis.com/Instance", r.URL.Query().Get("assetTypes"))
+		assert.Equal(t, readMask, r.URL.Query().Get("readMask"))
+		assert.Equal(t, "500", r.URL.Query().Get("pageSize"))
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"code": 401, "status": "UNAUTHENTICATED", "message": "Request had invalid authentication credentials."}}`))
+			return
+		}
+		assert.Equal(t, "billing-project", r.Header.Get("X-Goog-User-Project"))
+
+		_, _ = w.Write([]byte(`{"results": [{"name": "vm-1", "assetType": "compute.googleapis.com/Instance", "project": "projects/42", "location": "europe-west1-b", "labels": {"env": "prod"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(context.Background(),
+		WithEndpoint(server.URL+"/"),
+		WithCredential(staticCredential("token")),
+		WithQuotaProject("billing-project"),
+		WithScopes("organizations/42"),
+	)
+	require.NoError(t, err)
+
+	resources, err := provider.FindResources(context.Background(), "compute", "Instance")
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "europe-west1-b", resources[0].Region())
+	assert.Equal(t, "42", resources[0].OwnerID())
+	assert.Equal(t, map[string]string{"env": "prod"}, resources[0].Tags())
+
+	unauthorized, err := NewProvider(context.Background(),
+		WithEndpoint(server.URL),
+		WithCredential(staticCredential("expired")),
+		WithScopes("organizations/42"),
+	)
+	require.NoError(t, err)
+
+	_, err = unauthorized.FindResources(context.Background(), "compute", "Instance")
+	assert.ErrorContains(t, err, "UNAUTHENTICATED: Request had invalid authentication credentials.")
+}
+
+// adcCredential writes an Application Default Credentials file and creates its credential
+func adcCredential(t *testing.T, content []byte) (Credential, error) {
+	path := filepath.Join(t.TempDir(), "adc.json")
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	return NewCredential(context.Background(), AuthADC)
+}
+
+// countingTokenSource returns tokens expiring after ttl and counts the requests
+type countingTokenSource struct {
+	ttl   time.Duration
+	calls int
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	s.calls++
+	return &oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(s.ttl)}, nil
+}
+
+func TestCredentials(t *testing.T) {
+	t.Run("Service Account", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			require.Len(t, parts, 3)
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			require.NoError(t, err)
+
+			var claims map[string]any
+			require.NoError(t, json.Unmarshal(payload, &claims))
+			assert.Equal(t, "patrol@my-project.iam.gserviceaccount.com", claims["iss"])
+			assert.Equal(t, cloudPlatformScope, claims["scope"])
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "sa-token", "token_type": "Bearer", "expires_in": 3599}`))
+		}))
+		defer server.Close()
+
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		content, err := json.Marshal(map[string]string{
+			"type":         "service_account",
+			"client_email": "patrol@my-project.iam.gserviceaccount.com",
+			"private_key":  string(keyPEM),
+			"token_uri":    server.URL,
+		})
+		require.NoError(t, err)
+
+		credential, err := adcCredential(t, content)
+		require.NoError(t, err)
+
+		token, err := credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "sa-token", token)
+	})
+
+	t.Run("Authorized User", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "refresh", r.PostForm.Get("refresh_token"))
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "user-token", "token_type": "Bearer", "expires_in": 3599}`))
+		}))
+		defer server.Close()
+
+		credential, err := adcCredential(t, []byte(`{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "refresh", "token_uri": "`+server.URL+`"}`))
+		require.NoError(t, err)
+
+		token, err := credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "user-token", token)
+	})
+
+	t.Run("Token Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`))
+		}))
+		defer server.Close()
+
+		credential, err := adcCredential(t, []byte(`{"type": "authorized_user", "refresh_token": "refresh", "token_uri": "`+server.URL+`"}`))
+		require.NoError(t, err)
+
+		_, err = credential.Token(context.Background())
+		assert.ErrorContains(t, err, "error getting Google Cloud access token")
+		assert.ErrorContains(t, err, "Token has been expired or revoked.")
+	})
+
+	t.Run("Unsupported Credentials File", func(t *testing.T) {
+		_, err := adcCredential(t, []byte(`{"type": "unknown"}`))
+		assert.ErrorContains(t, err, "error finding application default credentials")
+	})
+
+	t.Run("Metadata Server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			assert.Equal(t, "/computeMetadata/v1/instance/service-accounts/default/token", r.URL.Path)
+
+			_, _ = w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3599, "token_type": "Bearer"}`))
+		}))
+		defer server.Close()
+		t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+		credential, err := NewCredential(context.Background(), AuthMetadata)
+		require.NoError(t, err)
+
+		token, err := credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "metadata-token", token)
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		source := &countingTokenSource{ttl: time.Hour}
+		credential := newTokenSourceCredential(source)
+		for range 2 {
+			_, err := credential.Token(context.Background())
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, source.calls)
+
+		// tokens are refreshed ahead of their expiry
+		source = &countingTokenSource{ttl: 4 * time.Minute}
+		credential = newTokenSourceCredential(source)
+		for range 2 {
+			_, err := credential.Token(context.Background())
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, source.calls)
+	})
+
+	t.Run("Gcloud", func(t *testing.T) {
+		calls := 0
+		credential := newTokenSourceCredential(&gcloudTokenSource{ctx: context.Background(), run: func(ctx context.Context) ([]byte, error) {
+			calls++
+			return []byte("gcloud-token\n"), nil
+		}})
+
+		for range 2 {
+			token, err := credential.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "gcloud-token", token)
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Gcloud Error", func(t *testing.T) {
+		credential := newTokenSourceCredential(&gcloudTokenSource{ctx: context.Background(), run: func(ctx context.Context) ([]byte, error) {
+			return nil, errors.New("exit status 1")
+		}})
+
+		_, err := credential.Token(context.Background())
+		assert.ErrorContains(t, err, "run `gcloud auth login` first")
+	})
+
+	t.Run("New Credential", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("HOME", dir)
+		t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(dir, "missing.json"))
+
+		_, err := NewCredential(context.Background(), AuthADC)
+		assert.ErrorContains(t, err, "error finding application default credentials")
+
+		// an explicitly set credentials file must exist
+		_, err = NewCredential(context.Background(), AuthAuto)
+		assert.ErrorContains(t, err, "error finding application default credentials")
+
+		// without application default credentials gcloud is used
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+		t.Setenv("PATH", dir)
+		findDefaultCredentials = func(context.Context, ...string) (*google.Credentials, error) {
+			return nil, errors.New("google: could not find default credentials")
+		}
+		credential, err := NewCredential(context.Background(), AuthAuto)
+		findDefaultCredentials = google.FindDefaultCredentials
+		require.NoError(t, err)
+		_, err = credential.Token(context.Background())
+		assert.ErrorContains(t, err, "error running `gcloud auth print-access-token`")
+
+		// on a Google Cloud host the application default credentials use the metadata server
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3599, "token_type": "Bearer"}`))
+		}))
+		defer server.Close()
+		t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+		credential, err = NewCredential(context.Background(), AuthAuto)
+		require.NoError(t, err)
+		token, err := credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "metadata-token", token)
+
+		path := filepath.Join(dir, "adc.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"type": "authorized_user", "refresh_token": "refresh"}`), 0o600))
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+		credential, err = NewCredential(context.Background(), AuthAuto)
+		require.NoError(t, err)
+		assert.IsType(t, &tokenSourceCredential{}, credential)
+
+		t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "static")
+		credential, err = NewCredential(context.Background(), AuthAuto)
+		require.NoError(t, err)
+		token, err = credential.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "static", token)
+
+		_, err = NewCredential(context.Background(), "password")
+		assert.ErrorContains(t, err, "unsupported auth method `password`")
+	})
+}
@@ -0,0 +1,155 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// listPageSize is the number of objects requested per list call
+const listPageSize = 500
+
+// loadConfig loads the API server settings with the kubeconfig loading rules of kubectl:
+// the given path, otherwise the files in $KUBECONFIG merged or ~/.kube/config, falling back
+// to the in-cluster service account. It returns the config and the name of the cluster.
+func loadConfig(kubeconfigPath, contextName string) (*rest.Config, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: contextName})
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return config, clusterName(clientConfig, contextName), nil
+}
+
+// clusterName returns the cluster of the given or current kubeconfig context, `in-cluster`
+// when the config was loaded from the service account
+func clusterName(clientConfig clientcmd.ClientConfig, contextName string) string {
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return ""
+	}
+
+	if contextName == "" {
+		contextName = raw.CurrentContext
+	}
+	if c, ok := raw.Contexts[contextName]; ok {
+		return c.Cluster
+	}
+	return "in-cluster"
+}
+
+// APIClient implements the Client interface over the Kubernetes API, finding the resource
+// of a kind through API discovery and listing it with the dynamic client
+type APIClient struct {
+	mapper  meta.RESTMapper
+	dynamic dynamic.Interface
+}
+
+// NewAPIClient creates a new client for the Kubernetes API server, authenticating with
+// the tokens, client certificates or exec credential plugins of the config
+func NewAPIClient(config *rest.Config) (*APIClient, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("API server address is not set")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery client: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
+	}
+
+	return &APIClient{
+		// discovery results are cached for the lifetime of the client
+		mapper:  restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		dynamic: dynamicClient,
+	}, nil
+}
+
+// ListObjects lists the objects of the given API group and kind in all namespaces,
+// or in a single namespace when set
+func (c *APIClient) ListObjects(ctx context.Context, group, kind, namespace string) ([]*Object, error) {
+	mapping, err := c.restMapping(group, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource dynamic.ResourceInterface = c.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		resource = c.dynamic.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	var objects []*Object
+	opts := metav1.ListOptions{Limit: listPageSize}
+
+	for {
+		list, err := resource.List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s: %w", mapping.Resource.Resource, err)
+		}
+
+		for _, item := range list.Items {
+			objects = append(objects, &Object{
+				APIVersion: mapping.GroupVersionKind.GroupVersion().String(),
+				Kind:       mapping.GroupVersionKind.Kind,
+				Metadata: ObjectMetadata{
+					Name:         item.GetName(),
+					GenerateName: item.GetGenerateName(),
+					Namespace:    item.GetNamespace(),
+					Labels:       item.GetLabels(),
+					Annotations:  item.GetAnnotations(),
+				},
+			})
+		}
+
+		if list.GetContinue() == "" {
+			break
+		}
+		opts.Continue = list.GetContinue()
+	}
+
+	return objects, nil
+}
+
+// restMapping finds the resource of a kind in the preferred version of its API group,
+// the kind is matched case-insensitively
+func (c *APIClient) restMapping(group, kind string) (*meta.RESTMapping, error) {
+	apiGroup := group
+	if group == CoreGroup {
+		apiGroup = ""
+	}
+
+	// the lowercase kind is registered as the singular resource name of every kind
+	gvk, err := c.mapper.KindFor(schema.GroupVersionResource{Group: apiGroup, Resource: strings.ToLower(kind)})
+	if meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("kind `%s` not found in API group `%s`", kind, group)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error discovering kind `%s`: %w", kind, err)
+	}
+
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering kind `%s`: %w", kind, err)
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,387 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func (m *MockClient) ListObjects(ctx context.Context, group, kind, namespace string) ([]*Object, error) {
+	args := m.Called(ctx, group, kind, namespace)
+	return args.Get(0).([]*Object), args.Error(1)
+}
+
+const testManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    team: checkout
+    version: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+---
+# empty document
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+`
+
+const testDump = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "apps/v1",
+      "kind": "Deployment",
+      "metadata": {"name": "api", "namespace": "backend", "labels": {"team": "platform"}}
+    },
+    {
+      "apiVersion": "apps/v1",
+      "kind": "DeploymentList",
+      "items": [
+        {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "worker", "namespace": "backend"}}
+      ]
+    }
+  ]
+}`
+
+func TestObjectGroup(t *testing.T) {
+	assert.Equal(t, "apps", (&Object{APIVersion: "apps/v1"}).Group())
+	assert.Equal(t, "networking.k8s.io", (&Object{APIVersion: "networking.k8s.io/v1"}).Group())
+	assert.Equal(t, CoreGroup, (&Object{APIVersion: "v1"}).Group())
+}
+
+func TestManifestClient(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(testManifests), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dumps"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dumps", "deployments.json"), []byte(testDump), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# not a manifest"), 0o600))
+
+	client, err := NewManifestClient(dir)
+	require.NoError(t, err)
+	assert.Len(t, client.objects, 5)
+
+	deployments, err := client.ListObjects(context.Background(), "apps", "Deployment", "")
+	require.NoError(t, err)
+	require.Len(t, deployments, 3)
+	assert.Equal(t, map[string]string{"team": "checkout", "version": "2"}, deployments[0].Metadata.Labels)
+
+	namespaced, err := client.ListObjects(context.Background(), "apps", "deployment", "shop")
+	require.NoError(t, err)
+	require.Len(t, namespaced, 1)
+	assert.Equal(t, "web", namespaced[0].Metadata.Name)
+
+	namespaces, err := client.ListObjects(context.Background(), CoreGroup, "Namespace", "")
+	require.NoError(t, err)
+	assert.Len(t, namespaces, 1)
+
+	_, err = NewManifestClient(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorContains(t, err, "error reading manifests")
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("kind: [\n"), 0o600))
+	_, err = NewManifestClient(invalid)
+	assert.ErrorContains(t, err, "error parsing manifest")
+}
+
+func TestFindResources(t *testing.T) {
+	objects := []*Object{
+		{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Metadata: ObjectMetadata{
+				Name:        "web",
+				Namespace:   "shop",
+				Labels:      map[string]string{"team": "checkout"},
+				Annotations: map[string]string{"team": "ignored", "cost-center": "42"},
+			},
+		},
+	}
+
+	t.Run("Labels", func(t *testing.T) {
+		client := new(MockClient)
+		client.On("ListObjects", mock.Anything, "apps", "Deployment", "shop").Return(objects, nil)
+
+		provider := &Provider{client: client, cluster: "prod", namespace: "shop"}
+		resources, err := provider.FindResources(context.Background(), "apps", "Deployment")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		assert.Equal(t, "shop/web", resources[0].ID())
+		assert.Equal(t, "Deployment", resources[0].Type())
+		assert.Equal(t, "apps", resources[0].Service())
+		assert.Equal(t, "kubernetes", resources[0].Provider())
+		assert.Equal(t, "shop", resources[0].Region())
+		assert.Equal(t, "prod", resources[0].OwnerID())
+		assert.Equal(t, map[string]string{"team": "checkout"}, resources[0].Tags())
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		client := new(MockClient)
+		client.On("ListObjects", mock.Anything, "apps", "Deployment", "").Return(objects, nil)
+
+		provider := &Provider{client: client, annotations: true}
+		resources, err := provider.FindResources(context.Background(), "apps", "Deployment")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "checkout", "cost-center": "42"}, resources[0].Tags())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		client := new(MockClient)
+		client.On("ListObjects", mock.Anything, "apps", "Deployment", "").Return([]*Object(nil), errors.New("forbidden"))
+
+		provider := &Provider{client: client}
+		_, err := provider.FindResources(context.Background(), "apps", "Deployment")
+		assert.EqualError(t, err, "forbidden")
+	})
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+  - name: dev-cluster
+    cluster:
+      server: https://dev.example.com/
+      insecure-skip-tls-verify: true
+users:
+  - name: dev-user
+    user:
+      token: dev-token
+  - name: file-user
+    user:
+      tokenFile: token
+contexts:
+  - name: dev
+    context: {cluster: dev-cluster, user: dev-user, namespace: team-a}
+  - name: file
+    context: {cluster: dev-cluster, user: file-user}
+`
+
+const testExecKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: eks-cluster
+    cluster:
+      server: https://eks.example.com
+users:
+  - name: exec-user
+    user:
+      exec:
+        apiVersion: client.authentication.k8s.io/v1
+        command: aws
+        args: [eks, get-token]
+        interactiveMode: Never
+contexts:
+  - name: eks
+    context: {cluster: eks-cluster, user: exec-user}
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	devPath := filepath.Join(dir, "dev")
+	execPath := filepath.Join(dir, "exec")
+	require.NoError(t, os.WriteFile(devPath, []byte(testKubeconfig), 0o600))
+	require.NoError(t, os.WriteFile(execPath, []byte(testExecKubeconfig), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0o600))
+
+	t.Run("Current Context", func(t *testing.T) {
+		cfg, cluster, err := loadConfig(devPath, "")
+		require.NoError(t, err)
+		assert.Equal(t, "https://dev.example.com/", cfg.Host)
+		assert.Equal(t, "dev-cluster", cluster)
+		assert.Equal(t, "dev-token", cfg.BearerToken)
+		assert.True(t, cfg.Insecure)
+	})
+
+	t.Run("Relative Token File", func(t *testing.T) {
+		cfg, _, err := loadConfig(devPath, "file")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "token"), cfg.BearerTokenFile)
+	})
+
+	t.Run("Exec Plugin From Merged KUBECONFIG", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", devPath+string(os.PathListSeparator)+execPath)
+
+		cfg, cluster, err := loadConfig("", "eks")
+		require.NoError(t, err)
+		assert.Equal(t, "eks-cluster", cluster)
+		require.NotNil(t, cfg.ExecProvider)
+		assert.Equal(t, "aws", cfg.ExecProvider.Command)
+	})
+
+	t.Run("Missing Context", func(t *testing.T) {
+		_, _, err := loadConfig(devPath, "missing")
+		assert.ErrorContains(t, err, "missing")
+	})
+
+	t.Run("All Namespaces By Default", func(t *testing.T) {
+		provider, err := NewProvider(context.Background(), WithKubeconfig(devPath))
+		require.NoError(t, err)
+		assert.Empty(t, provider.namespace)
+		assert.Equal(t, "dev-cluster", provider.cluster)
+	})
+}
+
+// TestExecCredentialHelper is run by the API client tests as an exec credential plugin
+func TestExecCredentialHelper(t *testing.T) {
+	if os.Getenv("TAGPATROL_EXEC_CREDENTIAL") != "1" {
+		t.Skip("only run as an exec credential plugin")
+	}
+
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
+		"apiVersion": "client.authentication.k8s.io/v1",
+		"kind":       "ExecCredential",
+		"status":     map[string]string{"token": "secret"},
+	})
+	os.Exit(0)
+}
+
+func TestAPIClient(t *testing.T) {
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{"kind": "Status", "message": "Unauthorized"})
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api":
+			writeJSON(w, map[string]any{"kind": "APIVersions", "versions": []string{"v1"}})
+		case "/apis":
+			apps := map[string]any{"groupVersion": "apps/v1", "version": "v1"}
+			writeJSON(w, map[string]any{"kind": "APIGroupList", "apiVersion": "v1", "groups": []any{
+				map[string]any{"name": "apps", "versions": []any{apps}, "preferredVersion": apps},
+			}})
+		case "/apis/apps/v1":
+			writeJSON(w, map[string]any{"kind": "APIResourceList", "apiVersion": "v1", "groupVersion": "apps/v1", "resources": []any{
+				map[string]any{"name": "deployments", "singularName": "deployment", "kind": "Deployment", "namespaced": true, "verbs": []string{"list"}},
+				map[string]any{"name": "deployments/status", "singularName": "", "kind": "Deployment", "namespaced": true, "verbs": []string{"get"}},
+			}})
+		case "/api/v1":
+			writeJSON(w, map[string]any{"kind": "APIResourceList", "groupVersion": "v1", "resources": []any{
+				map[string]any{"name": "namespaces", "singularName": "namespace", "kind": "Namespace", "namespaced": false, "verbs": []string{"list"}},
+			}})
+		case "/apis/apps/v1/deployments":
+			assert.Equal(t, "500", r.URL.Query().Get("limit"))
+			if r.URL.Query().Get("continue") == "" {
+				writeJSON(w, map[string]any{
+					"kind": "DeploymentList", "apiVersion": "apps/v1",
+					"items":    []any{map[string]any{"metadata": map[string]any{"name": "web", "namespace": "shop", "labels": map[string]string{"team": "a"}}}},
+					"metadata": map[string]string{"continue": "page-2"},
+				})
+				return
+			}
+			writeJSON(w, map[string]any{
+				"kind": "DeploymentList", "apiVersion": "apps/v1",
+				"items": []any{map[string]any{"metadata": map[string]any{"name": "api", "namespace": "backend"}}},
+			})
+		case "/apis/apps/v1/namespaces/shop/deployments":
+			writeJSON(w, map[string]any{
+				"kind": "DeploymentList", "apiVersion": "apps/v1",
+				"items": []any{map[string]any{"metadata": map[string]any{"name": "web", "namespace": "shop"}}},
+			})
+		case "/api/v1/namespaces":
+			writeJSON(w, map[string]any{
+				"kind": "NamespaceList", "apiVersion": "v1",
+				"items": []any{map[string]any{"metadata": map[string]any{"name": "shop"}}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	client, err := NewAPIClient(&rest.Config{Host: server.URL, BearerToken: "secret", TLSClientConfig: rest.TLSClientConfig{CAData: ca}})
+	require.NoError(t, err)
+
+	t.Run("All Namespaces With Pagination", func(t *testing.T) {
+		objects, err := client.ListObjects(context.Background(), "apps", "Deployment", "")
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		assert.Equal(t, "apps/v1", objects[0].APIVersion)
+		assert.Equal(t, "Deployment", objects[0].Kind)
+		assert.Equal(t, map[string]string{"team": "a"}, objects[0].Metadata.Labels)
+		assert.Equal(t, "api", objects[1].Metadata.Name)
+	})
+
+	t.Run("Single Namespace", func(t *testing.T) {
+		objects, err := client.ListObjects(context.Background(), "apps", "Deployment", "shop")
+		require.NoError(t, err)
+		assert.Len(t, objects, 1)
+	})
+
+	t.Run("Cluster Scoped Core Kind", func(t *testing.T) {
+		objects, err := client.ListObjects(context.Background(), CoreGroup, "Namespace", "shop")
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		assert.Equal(t, CoreGroup, objects[0].Group())
+	})
+
+	t.Run("Unknown Group And Kind", func(t *testing.T) {
+		_, err := client.ListObjects(context.Background(), "batch", "Job", "")
+		assert.ErrorContains(t, err, "kind `Job` not found in API group `batch`")
+
+		_, err = client.ListObjects(context.Background(), "apps", "StatefulSet", "")
+		assert.ErrorContains(t, err, "kind `StatefulSet` not found in API group `apps`")
+	})
+
+	t.Run("Exec Plugin", func(t *testing.T) {
+		execClient, err := NewAPIClient(&rest.Config{
+			Host:            server.URL,
+			TLSClientConfig: rest.TLSClientConfig{CAData: ca},
+			ExecProvider: &clientcmdapi.ExecConfig{
+				APIVersion:      "client.authentication.k8s.io/v1",
+				Command:         os.Args[0],
+				Args:            []string{"-test.run=^TestExecCredentialHelper$"},
+				Env:             []clientcmdapi.ExecEnvVar{{Name: "TAGPATROL_EXEC_CREDENTIAL", Value: "1"}},
+				InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+			},
+		})
+		require.NoError(t, err)
+
+		objects, err := execClient.ListObjects(context.Background(), "apps", "Deployment", "shop")
+		require.NoError(t, err)
+		assert.Len(t, objects, 1)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		unauthorized, err := NewAPIClient(&rest.Config{Host: server.URL, TLSClientConfig: rest.TLSClientConfig{CAData: ca}})
+		require.NoError(t, err)
+
+		_, err = unauthorized.ListObjects(context.Background(), "apps", "Deployment", "")
+		assert.ErrorContains(t, err, "the server has asked for the client to provide credentials")
+	})
+}
@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestDocument is a manifest or a list of objects, e.g. a `kubectl get -o json` dump
+type manifestDocument struct {
+	Object `yaml:",inline"`
+	Items  []*manifestDocument `yaml:"items"`
+}
+
+// ManifestClient implements the Client interface over objects read from manifest files
+type ManifestClient struct {
+	objects []*Object
+}
+
+// NewManifestClient creates a client with the objects of the given manifest files,
+// `kubectl get -o json` dumps or directories of `.yaml`, `.yml` and `.json` files
+func NewManifestClient(paths ...string) (*ManifestClient, error) {
+	client := &ManifestClient{}
+
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (file != path && !isManifestFile(file)) {
+				return nil
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			objects, err := parseManifests(content)
+			if err != nil {
+				return fmt.Errorf("error parsing manifest %s: %w", file, err)
+			}
+
+			client.objects = append(client.objects, objects...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifests: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// ListObjects returns the objects of the given API group and kind, the namespace is optional
+func (c *ManifestClient) ListObjects(ctx context.Context, group, kind, namespace string) ([]*Object, error) {
+	var objects []*Object
+
+	for _, object := range c.objects {
+		if object.Group() != group || !strings.EqualFold(object.Kind, kind) {
+			continue
+		}
+		if namespace != "" && object.Metadata.Namespace != namespace {
+			continue
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// parseManifests parses a stream of YAML documents, JSON is parsed as YAML
+func parseManifests(content []byte) ([]*Object, error) {
+	var objects []*Object
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		doc := &manifestDocument{}
+		err := decoder.Decode(doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, doc.objects()...)
+	}
+
+	return objects, nil
+}
+
+// objects flattens a document to its objects, skipping empty documents
+func (d *manifestDocument) objects() []*Object {
+	if strings.HasSuffix(d.Kind, "List") && d.Items != nil {
+		var objects []*Object
+		for _, item := range d.Items {
+			objects = append(objects, item.objects()...)
+		}
+		return objects
+	}
+
+	if d.Kind == "" || d.Metadata.Name == "" {
+		return nil
+	}
+
+	object := d.Object
+	return []*Object{&object}
+}
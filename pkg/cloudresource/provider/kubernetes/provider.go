@@ -0,0 +1,181 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// ProviderName is reported as the provider of Kubernetes objects
+const ProviderName = "kubernetes"
+
+// CoreGroup is the service name used for objects of the core API group (apiVersion `v1`)
+const CoreGroup = "core"
+
+// Object holds the metadata of a Kubernetes object
+type Object struct {
+	APIVersion string         `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Metadata   ObjectMetadata `json:"metadata" yaml:"metadata"`
+}
+
+// ObjectMetadata holds the object metadata fields used by the provider
+type ObjectMetadata struct {
+	Name         string            `json:"name" yaml:"name"`
+	GenerateName string            `json:"generateName" yaml:"generateName"`
+	Namespace    string            `json:"namespace" yaml:"namespace"`
+	Labels       map[string]string `json:"labels" yaml:"labels"`
+	Annotations  map[string]string `json:"annotations" yaml:"annotations"`
+}
+
+// Group returns the API group of the object, CoreGroup for the core API group
+func (o *Object) Group() string {
+	group, _, found := strings.Cut(o.APIVersion, "/")
+	if !found || group == "" {
+		return CoreGroup
+	}
+	return group
+}
+
+// Client defines the interface for listing Kubernetes objects of a kind
+type Client interface {
+	ListObjects(ctx context.Context, group, kind, namespace string) ([]*Object, error)
+}
+
+// Provider implements the CloudResource Finder interface for Kubernetes objects.
+// Labels are treated as tags, the API group as service and the kind as resource type.
+type Provider struct {
+	client      Client
+	cluster     string
+	namespace   string
+	annotations bool
+}
+
+type providerConfig struct {
+	kubeconfig  string
+	context     string
+	namespace   string
+	manifests   []string
+	annotations bool
+}
+
+// Option is a function that configures the Kubernetes provider
+type Option func(*providerConfig)
+
+// WithKubeconfig sets the kubeconfig file to use instead of $KUBECONFIG or ~/.kube/config
+func WithKubeconfig(path string) Option {
+	return func(c *providerConfig) {
+		c.kubeconfig = path
+	}
+}
+
+// WithContext sets the kubeconfig context to use instead of the current context
+func WithContext(name string) Option {
+	return func(c *providerConfig) {
+		c.context = name
+	}
+}
+
+// WithNamespace limits the search to a single namespace, all namespaces are searched by default
+func WithNamespace(namespace string) Option {
+	return func(c *providerConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithManifests reads the objects from manifest files, `kubectl get -o json` dumps
+// or directories of those instead of the Kubernetes API
+func WithManifests(paths ...string) Option {
+	return func(c *providerConfig) {
+		c.manifests = append(c.manifests, paths...)
+	}
+}
+
+// WithAnnotations treats annotations as tags in addition to labels, labels take precedence
+func WithAnnotations() Option {
+	return func(c *providerConfig) {
+		c.annotations = true
+	}
+}
+
+// NewProvider creates a new Kubernetes provider with the specified options
+func NewProvider(ctx context.Context, opts ...Option) (*Provider, error) {
+	cfg := &providerConfig{}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	provider := &Provider{
+		namespace:   cfg.namespace,
+		annotations: cfg.annotations,
+	}
+
+	if len(cfg.manifests) > 0 {
+		client, err := NewManifestClient(cfg.manifests...)
+		if err != nil {
+			return nil, err
+		}
+		provider.client = client
+		return provider, nil
+	}
+
+	restConfig, cluster, err := loadConfig(cfg.kubeconfig, cfg.context)
+	if err != nil {
+		return nil, fmt.Errorf("error loading Kubernetes config: %w", err)
+	}
+
+	client, err := NewAPIClient(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.client = client
+	provider.cluster = cluster
+
+	return provider, nil
+}
+
+// FindResources returns the Kubernetes objects of the specified API group and kind
+func (p *Provider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	objects, err := p.client.ListObjects(ctx, serviceName, resourceName, p.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]cr.CloudResource, 0, len(objects))
+	for _, object := range objects {
+		resources = append(resources, NewResource(object, p.cluster, p.annotations))
+	}
+
+	return resources, nil
+}
+
+// NewResource converts an object to a CloudResource. The namespace is reported as
+// region and the cluster as owner, annotations are only used as tags when enabled.
+func NewResource(object *Object, cluster string, annotations bool) *cr.GenericResource {
+	id := object.Metadata.Name
+	if object.Metadata.Namespace != "" {
+		id = object.Metadata.Namespace + "/" + id
+	}
+
+	resource := &cr.GenericResource{
+		ResourceID:     id,
+		ResourceType:   object.Kind,
+		ServiceName:    object.Group(),
+		ProviderName:   ProviderName,
+		ResourceRegion: object.Metadata.Namespace,
+		Owner:          cluster,
+		ResourceTags:   make(map[string]string, len(object.Metadata.Labels)),
+	}
+
+	if annotations {
+		maps.Copy(resource.ResourceTags, object.Metadata.Annotations)
+	}
+	maps.Copy(resource.ResourceTags, object.Metadata.Labels)
+
+	return resource
+}
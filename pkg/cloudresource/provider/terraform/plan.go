@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// UnknownValue is the value of tags that are only known after apply
+const UnknownValue = "(known after apply)"
+
+// plan is the subset of the `terraform show -json` plan format used by the provider
+type plan struct {
+	FormatVersion   string            `json:"format_version"`
+	ResourceChanges []*resourceChange `json:"resource_changes"`
+}
+
+type resourceChange struct {
+	Address string  `json:"address"`
+	Mode    string  `json:"mode"`
+	Type    string  `json:"type"`
+	Change  *change `json:"change"`
+}
+
+type change struct {
+	Actions      []string       `json:"actions"`
+	After        map[string]any `json:"after"`
+	AfterUnknown map[string]any `json:"after_unknown"`
+}
+
+// PlanProvider implements the CloudResource Finder interface for Terraform plans. It
+// yields the planned state of resources that are created or updated, identified
+// by their Terraform address.
+type PlanProvider struct {
+	records []*record
+}
+
+// NewPlanProvider creates a new provider with the AWS resource changes of a Terraform plan
+// in JSON format, as produced by `terraform show -json`
+func NewPlanProvider(path string, opts ...Option) (*PlanProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plan file: %w", err)
+	}
+	defer f.Close()
+
+	records, err := loadPlan(f, newConfig(opts))
+	if err != nil {
+		return nil, fmt.Errorf("error loading plan file %s: %w", path, err)
+	}
+
+	return &PlanProvider{records: records}, nil
+}
+
+// FindResources returns the planned resources of the specified service and resource type
+func (p *PlanProvider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	var resources []cr.CloudResource
+
+	for _, rec := range find(p.records, serviceName, resourceName) {
+		resource := rec.resource()
+		resource.ResourceID = rec.address
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func loadPlan(r io.Reader, cfg *providerConfig) ([]*record, error) {
+	var pl plan
+	if err := json.NewDecoder(r).Decode(&pl); err != nil {
+		return nil, fmt.Errorf("error decoding plan: %w", err)
+	}
+
+	if pl.FormatVersion == "" {
+		return nil, fmt.Errorf("missing format_version, the plan must be converted with `terraform show -json`")
+	}
+
+	var records []*record
+
+	for _, rc := range pl.ResourceChanges {
+		if rc.Mode != "managed" || rc.Change == nil || !isCreateOrUpdate(rc.Change.Actions) {
+			continue
+		}
+
+		service, resourceType, ok := lookupType(rc.Type, cfg.typeMapping)
+		if !ok {
+			continue
+		}
+
+		attributes, unknownTags := plannedAttributes(rc.Change)
+		records = append(records, &record{
+			address:      rc.Address,
+			service:      service,
+			resourceType: resourceType,
+			attributes:   attributes,
+			unknownTags:  unknownTags,
+		})
+	}
+
+	return records, nil
+}
+
+// isCreateOrUpdate returns true for changes that leave a resource behind, including replacements
+func isCreateOrUpdate(actions []string) bool {
+	return slices.Contains(actions, "create") || slices.Contains(actions, "update")
+}
+
+// plannedAttributes returns the planned attributes with tags whose value is only
+// known after apply set to UnknownValue. A tag map that is only known after apply is
+// dropped and reported as unknown: an unknown `tags_all` falls back to the tags configured
+// on the resource, as the provider default tags are unknown.
+func plannedAttributes(c *change) (map[string]any, bool) {
+	attributes := make(map[string]any, len(c.After))
+	maps.Copy(attributes, c.After)
+
+	unknownMaps := make(map[string]bool)
+
+	for _, key := range []string{"tags", "tags_all"} {
+		switch unknown := c.AfterUnknown[key].(type) {
+		case bool:
+			if unknown {
+				delete(attributes, key)
+				unknownMaps[key] = true
+			}
+		case map[string]any:
+			tags, _ := attributes[key].(map[string]any)
+			if tags == nil {
+				tags = make(map[string]any)
+			}
+			for tag, isUnknown := range unknown {
+				if b, ok := isUnknown.(bool); ok && b {
+					tags[tag] = UnknownValue
+				}
+			}
+			attributes[key] = tags
+		}
+	}
+
+	// an unknown `tags` map doesn't matter when `tags_all` is known, as it includes them
+	tagsAll, _ := attributes["tags_all"].(map[string]any)
+	unknownTags := unknownMaps["tags_all"] || (unknownMaps["tags"] && len(tagsAll) == 0)
+
+	return attributes, unknownTags
+}
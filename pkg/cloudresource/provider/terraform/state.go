@@ -0,0 +1,225 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// ProviderName is reported as the provider of Terraform managed AWS resources
+const ProviderName = "aws"
+
+// supportedStateVersion is the Terraform state format version this provider understands
+const supportedStateVersion = 4
+
+// state is the subset of the Terraform state v4 JSON format used by the provider
+type state struct {
+	Version   int              `json:"version"`
+	Resources []*stateResource `json:"resources"`
+}
+
+type stateResource struct {
+	Module    string           `json:"module"`
+	Mode      string           `json:"mode"`
+	Type      string           `json:"type"`
+	Name      string           `json:"name"`
+	Provider  string           `json:"provider"`
+	Instances []*stateInstance `json:"instances"`
+}
+
+type stateInstance struct {
+	IndexKey   any            `json:"index_key"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// record is a single Terraform managed resource instance
+type record struct {
+	address      string
+	service      string
+	resourceType string
+	attributes   map[string]any
+	// unknownTags is set when the tag map of a planned resource is only known after apply
+	unknownTags bool
+}
+
+// StateProvider implements the CloudResource Finder interface for Terraform state files
+type StateProvider struct {
+	records []*record
+}
+
+type providerConfig struct {
+	typeMapping map[string]string
+}
+
+// Option is a function that configures the Terraform providers
+type Option func(*providerConfig)
+
+// WithTypeMapping adds or overrides Terraform resource type to `service:type` mappings
+func WithTypeMapping(mapping map[string]string) Option {
+	return func(c *providerConfig) {
+		maps.Copy(c.typeMapping, mapping)
+	}
+}
+
+func newConfig(opts []Option) *providerConfig {
+	cfg := &providerConfig{typeMapping: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// NewStateProvider creates a new provider with the AWS resources of a Terraform state file
+func NewStateProvider(path string, opts ...Option) (*StateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening state file: %w", err)
+	}
+	defer f.Close()
+
+	records, err := loadState(f, newConfig(opts))
+	if err != nil {
+		return nil, fmt.Errorf("error loading state file %s: %w", path, err)
+	}
+
+	return &StateProvider{records: records}, nil
+}
+
+// FindResources returns the managed resources of the specified service and resource type
+func (p *StateProvider) FindResources(ctx context.Context, serviceName, resourceName string) ([]cr.CloudResource, error) {
+	var resources []cr.CloudResource
+
+	for _, rec := range find(p.records, serviceName, resourceName) {
+		resources = append(resources, rec.resource())
+	}
+
+	return resources, nil
+}
+
+func find(records []*record, serviceName, resourceName string) []*record {
+	var found []*record
+
+	for _, rec := range records {
+		if rec.service == serviceName && rec.resourceType == resourceName {
+			found = append(found, rec)
+		}
+	}
+
+	return found
+}
+
+func loadState(r io.Reader, cfg *providerConfig) ([]*record, error) {
+	var st state
+	if err := json.NewDecoder(r).Decode(&st); err != nil {
+		return nil, fmt.Errorf("error decoding state: %w", err)
+	}
+
+	if st.Version != supportedStateVersion {
+		return nil, fmt.Errorf("unsupported state version %d, expected %d", st.Version, supportedStateVersion)
+	}
+
+	var records []*record
+
+	for _, res := range st.Resources {
+		if res.Mode != "managed" {
+			continue
+		}
+
+		service, resourceType, ok := lookupType(res.Type, cfg.typeMapping)
+		if !ok {
+			continue
+		}
+
+		for _, instance := range res.Instances {
+			records = append(records, &record{
+				address:      address(res.Module, res.Type, res.Name, instance.IndexKey),
+				service:      service,
+				resourceType: resourceType,
+				attributes:   instance.Attributes,
+			})
+		}
+	}
+
+	return records, nil
+}
+
+// address builds the Terraform resource address, e.g. `module.network.aws_subnet.private["a"]`
+func address(module, tfType, name string, indexKey any) string {
+	addr := tfType + "." + name
+	if module != "" {
+		addr = module + "." + addr
+	}
+
+	switch key := indexKey.(type) {
+	case string:
+		addr += fmt.Sprintf("[%q]", key)
+	case float64:
+		addr += fmt.Sprintf("[%d]", int(key))
+	}
+
+	return addr
+}
+
+// resource converts the record to a CloudResource. The ID is the ARN when known,
+// otherwise the Terraform address. Region and owner are taken from the ARN.
+func (r *record) resource() *cr.GenericResource {
+	resource := &cr.GenericResource{
+		ResourceID:   r.address,
+		ResourceType: r.service + ":" + r.resourceType,
+		ServiceName:  r.service,
+		ProviderName: ProviderName,
+		ResourceTags: tags(r.attributes),
+		UnknownTags:  r.unknownTags,
+	}
+
+	if arn, ok := r.attributes["arn"].(string); ok && arn != "" {
+		resource.ResourceID = arn
+
+		// arn:partition:service:region:account-id:resource
+		if parts := strings.SplitN(arn, ":", 6); len(parts) == 6 {
+			resource.ResourceRegion = parts[3]
+			resource.Owner = parts[4]
+		}
+	}
+
+	if region, ok := r.attributes["region"].(string); ok && resource.ResourceRegion == "" {
+		resource.ResourceRegion = region
+	}
+
+	for key, value := range resource.ResourceTags {
+		if value == UnknownValue {
+			resource.UnknownValues = append(resource.UnknownValues, key)
+		}
+	}
+	slices.Sort(resource.UnknownValues)
+
+	return resource
+}
+
+// tags returns the effective tags of a resource, `tags_all` includes the provider
+// default tags and is preferred over `tags`
+func tags(attributes map[string]any) map[string]string {
+	result := make(map[string]string)
+
+	raw, ok := attributes["tags_all"].(map[string]any)
+	if !ok || len(raw) == 0 {
+		raw, _ = attributes["tags"].(map[string]any)
+	}
+
+	for key, value := range raw {
+		if s, ok := value.(string); ok {
+			result[key] = s
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,292 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testState = `{
+  "version": 4,
+  "terraform_version": "1.7.0",
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "index_key": 0,
+          "attributes": {
+            "arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+            "id": "i-1",
+            "tags": {"Name": "web-0"},
+            "tags_all": {"Name": "web-0", "env": "prod"}
+          }
+        },
+        {
+          "index_key": 1,
+          "attributes": {
+            "arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-2",
+            "id": "i-2",
+            "tags": {"Name": "web-1"},
+            "tags_all": null
+          }
+        }
+      ]
+    },
+    {
+      "module": "module.storage",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "logs",
+      "instances": [
+        {
+          "index_key": "eu",
+          "attributes": {
+            "arn": "arn:aws:s3:::logs-eu",
+            "region": "eu-west-1",
+            "tags": null
+          }
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_example_widget",
+      "name": "custom",
+      "instances": [{"attributes": {"tags": {"team": "a"}}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_route",
+      "name": "unmapped",
+      "instances": [{"attributes": {}}]
+    },
+    {
+      "mode": "data",
+      "type": "aws_instance",
+      "name": "lookup",
+      "instances": [{"attributes": {"arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-9"}}]
+    }
+  ]
+}`
+
+func TestLookupType(t *testing.T) {
+	service, resourceType, ok := lookupType("aws_instance", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "ec2", service)
+	assert.Equal(t, "instance", resourceType)
+
+	service, resourceType, ok = lookupType("aws_instance", map[string]string{"aws_instance": "custom:server"})
+	assert.True(t, ok)
+	assert.Equal(t, "custom", service)
+	assert.Equal(t, "server", resourceType)
+
+	_, _, ok = lookupType("aws_route", nil)
+	assert.False(t, ok)
+
+	_, _, ok = lookupType("aws_bad", map[string]string{"aws_bad": "no-separator"})
+	assert.False(t, ok)
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, "aws_instance.web", address("", "aws_instance", "web", nil))
+	assert.Equal(t, "aws_instance.web[2]", address("", "aws_instance", "web", float64(2)))
+	assert.Equal(t, `module.a.module.b.aws_subnet.private["x"]`, address("module.a.module.b", "aws_subnet", "private", "x"))
+}
+
+func TestStateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+	require.NoError(t, os.WriteFile(path, []byte(testState), 0o600))
+
+	provider, err := NewStateProvider(path, WithTypeMapping(map[string]string{"aws_example_widget": "example:widget"}))
+	require.NoError(t, err)
+	assert.Len(t, provider.records, 4)
+
+	t.Run("Instances", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+
+		assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-1", resources[0].ID())
+		assert.Equal(t, "ec2:instance", resources[0].Type())
+		assert.Equal(t, "ec2", resources[0].Service())
+		assert.Equal(t, "aws", resources[0].Provider())
+		assert.Equal(t, "us-east-1", resources[0].Region())
+		assert.Equal(t, "123456789012", resources[0].OwnerID())
+		assert.Equal(t, map[string]string{"Name": "web-0", "env": "prod"}, resources[0].Tags())
+
+		// falls back to tags when tags_all is not set
+		assert.Equal(t, map[string]string{"Name": "web-1"}, resources[1].Tags())
+	})
+
+	t.Run("Module Resource", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "s3", "bucket")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+
+		assert.Equal(t, "arn:aws:s3:::logs-eu", resources[0].ID())
+		assert.Equal(t, "eu-west-1", resources[0].Region())
+		assert.Empty(t, resources[0].OwnerID())
+		assert.Empty(t, resources[0].Tags())
+	})
+
+	t.Run("Custom Mapping Without ARN", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "example", "widget")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "aws_example_widget.custom", resources[0].ID())
+	})
+
+	t.Run("No Match", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "rds", "db")
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+	})
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	_, err := loadState(strings.NewReader(`{"version": 3}`), newConfig(nil))
+	assert.ErrorContains(t, err, "unsupported state version 3, expected 4")
+
+	_, err = loadState(strings.NewReader(`{`), newConfig(nil))
+	assert.ErrorContains(t, err, "error decoding state")
+
+	_, err = NewStateProvider(filepath.Join(t.TempDir(), "missing.tfstate"))
+	assert.ErrorContains(t, err, "error opening state file")
+}
+
+const testPlan = `{
+  "format_version": "1.2",
+  "terraform_version": "1.7.0",
+  "resource_changes": [
+    {
+      "address": "aws_instance.web[0]",
+      "mode": "managed",
+      "type": "aws_instance",
+      "change": {
+        "actions": ["create"],
+        "after": {"tags": {"Name": "web-0", "env": "prod"}},
+        "after_unknown": {"arn": true, "tags_all": true, "tags": {"owner": true}}
+      }
+    },
+    {
+      "address": "aws_instance.legacy",
+      "mode": "managed",
+      "type": "aws_instance",
+      "change": {
+        "actions": ["update"],
+        "after": {
+          "arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+          "tags": {"Name": "legacy"},
+          "tags_all": {"Name": "legacy", "team": "platform"}
+        },
+        "after_unknown": {}
+      }
+    },
+    {
+      "address": "module.db.aws_db_instance.main",
+      "mode": "managed",
+      "type": "aws_db_instance",
+      "change": {
+        "actions": ["delete", "create"],
+        "after": {"tags": null},
+        "after_unknown": {"tags_all": true}
+      }
+    },
+    {
+      "address": "aws_instance.computed",
+      "mode": "managed",
+      "type": "aws_instance",
+      "change": {
+        "actions": ["create"],
+        "after": {},
+        "after_unknown": {"tags": true, "tags_all": true}
+      }
+    },
+    {
+      "address": "aws_instance.old",
+      "mode": "managed",
+      "type": "aws_instance",
+      "change": {"actions": ["delete"], "after": null}
+    },
+    {
+      "address": "aws_instance.same",
+      "mode": "managed",
+      "type": "aws_instance",
+      "change": {"actions": ["no-op"], "after": {"tags": {}}}
+    },
+    {
+      "address": "data.aws_instance.lookup",
+      "mode": "data",
+      "type": "aws_instance",
+      "change": {"actions": ["read"], "after": {"tags": {}}}
+    }
+  ]
+}`
+
+func TestPlanProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPlan), 0o600))
+
+	provider, err := NewPlanProvider(path)
+	require.NoError(t, err)
+	assert.Len(t, provider.records, 4)
+
+	t.Run("Created And Updated", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "ec2", "instance")
+		require.NoError(t, err)
+		require.Len(t, resources, 3)
+
+		created := resources[0]
+		assert.Equal(t, "aws_instance.web[0]", created.ID())
+		assert.Equal(t, "ec2:instance", created.Type())
+		assert.Equal(t, "aws", created.Provider())
+		assert.Empty(t, created.Region())
+		assert.Equal(t, map[string]string{"Name": "web-0", "env": "prod", "owner": UnknownValue}, created.Tags())
+		assert.True(t, created.(cr.UnknownValueReporter).IsUnknownValue("owner"))
+		assert.False(t, created.(cr.UnknownValueReporter).IsUnknownValue("env"))
+		// tags_all is unknown, so the default tags may add any other tag
+		assert.True(t, created.(cr.UnknownValueReporter).IsUnknownValue("team"))
+
+		updated := resources[1]
+		assert.Equal(t, "aws_instance.legacy", updated.ID())
+		assert.Equal(t, "us-east-1", updated.Region())
+		assert.Equal(t, "123456789012", updated.OwnerID())
+		assert.Equal(t, map[string]string{"Name": "legacy", "team": "platform"}, updated.Tags())
+		assert.False(t, updated.(cr.UnknownValueReporter).IsUnknownValue("owner"))
+
+		computed := resources[2]
+		assert.Equal(t, "aws_instance.computed", computed.ID())
+		assert.Empty(t, computed.Tags())
+		assert.True(t, computed.(cr.UnknownValueReporter).IsUnknownValue("owner"))
+	})
+
+	t.Run("Replaced", func(t *testing.T) {
+		resources, err := provider.FindResources(context.Background(), "rds", "db")
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "module.db.aws_db_instance.main", resources[0].ID())
+		assert.Empty(t, resources[0].Tags())
+		assert.True(t, resources[0].(cr.UnknownValueReporter).IsUnknownValue("owner"))
+	})
+}
+
+func TestLoadPlanErrors(t *testing.T) {
+	_, err := loadPlan(strings.NewReader(`{"resource_changes": []}`), newConfig(nil))
+	assert.ErrorContains(t, err, "missing format_version")
+
+	_, err = loadPlan(strings.NewReader(`not json`), newConfig(nil))
+	assert.ErrorContains(t, err, "error decoding plan")
+
+	_, err = NewPlanProvider(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "error opening plan file")
+}
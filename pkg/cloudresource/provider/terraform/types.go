@@ -0,0 +1,77 @@
+package terraform
+
+import "strings"
+
+// resourceTypes maps Terraform AWS resource types to Resource Explorer style `service:type` names
+var resourceTypes = map[string]string{
+	"aws_acm_certificate":             "acm:certificate",
+	"aws_alb":                         "elasticloadbalancing:loadbalancer",
+	"aws_alb_target_group":            "elasticloadbalancing:targetgroup",
+	"aws_ami":                         "ec2:image",
+	"aws_api_gateway_rest_api":        "apigateway:restapis",
+	"aws_autoscaling_group":           "autoscaling:autoScalingGroup",
+	"aws_cloudfront_distribution":     "cloudfront:distribution",
+	"aws_cloudtrail":                  "cloudtrail:trail",
+	"aws_cloudwatch_log_group":        "logs:log-group",
+	"aws_cloudwatch_metric_alarm":     "cloudwatch:alarm",
+	"aws_db_instance":                 "rds:db",
+	"aws_db_subnet_group":             "rds:subgrp",
+	"aws_dynamodb_table":              "dynamodb:table",
+	"aws_ebs_snapshot":                "ec2:snapshot",
+	"aws_ebs_volume":                  "ec2:volume",
+	"aws_ecr_repository":              "ecr:repository",
+	"aws_ecs_cluster":                 "ecs:cluster",
+	"aws_ecs_service":                 "ecs:service",
+	"aws_ecs_task_definition":         "ecs:task-definition",
+	"aws_efs_file_system":             "elasticfilesystem:file-system",
+	"aws_eip":                         "ec2:elastic-ip",
+	"aws_eks_cluster":                 "eks:cluster",
+	"aws_eks_node_group":              "eks:nodegroup",
+	"aws_elasticache_cluster":         "elasticache:cluster",
+	"aws_elasticsearch_domain":        "es:domain",
+	"aws_iam_policy":                  "iam:policy",
+	"aws_iam_role":                    "iam:role",
+	"aws_iam_user":                    "iam:user",
+	"aws_instance":                    "ec2:instance",
+	"aws_internet_gateway":            "ec2:internet-gateway",
+	"aws_key_pair":                    "ec2:key-pair",
+	"aws_kinesis_stream":              "kinesis:stream",
+	"aws_kms_key":                     "kms:key",
+	"aws_lambda_function":             "lambda:function",
+	"aws_launch_template":             "ec2:launch-template",
+	"aws_lb":                          "elasticloadbalancing:loadbalancer",
+	"aws_lb_target_group":             "elasticloadbalancing:targetgroup",
+	"aws_nat_gateway":                 "ec2:natgateway",
+	"aws_network_interface":           "ec2:network-interface",
+	"aws_opensearch_domain":           "es:domain",
+	"aws_rds_cluster":                 "rds:cluster",
+	"aws_redshift_cluster":            "redshift:cluster",
+	"aws_route53_zone":                "route53:hostedzone",
+	"aws_route_table":                 "ec2:route-table",
+	"aws_s3_bucket":                   "s3:bucket",
+	"aws_sagemaker_notebook_instance": "sagemaker:notebook-instance",
+	"aws_secretsmanager_secret":       "secretsmanager:secret",
+	"aws_security_group":              "ec2:security-group",
+	"aws_sfn_state_machine":           "states:stateMachine",
+	"aws_sns_topic":                   "sns:topic",
+	"aws_sqs_queue":                   "sqs:queue",
+	"aws_ssm_parameter":               "ssm:parameter",
+	"aws_subnet":                      "ec2:subnet",
+	"aws_vpc":                         "ec2:vpc",
+	"aws_vpc_endpoint":                "ec2:vpc-endpoint",
+}
+
+// lookupType returns the Resource Explorer style service and resource type for a
+// Terraform resource type, using the custom mapping before the built-in one
+func lookupType(tfType string, custom map[string]string) (service, resourceType string, ok bool) {
+	name, ok := custom[tfType]
+	if !ok {
+		name, ok = resourceTypes[tfType]
+	}
+	if !ok {
+		return "", "", false
+	}
+
+	service, resourceType, ok = strings.Cut(name, ":")
+	return service, resourceType, ok
+}
@@ -1,5 +1,9 @@
 package cloudresource
 
+import (
+	ptypes "github.com/eliran89c/tag-patrol/pkg/policy/types"
+)
+
 // CloudResource represents a generic cloud resource with tags
 type CloudResource interface {
 	// ID returns the unique identifier for the resource
@@ -27,10 +31,10 @@ type CloudResource interface {
 	IsCompliant() bool
 
 	// AddComplianceError adds a compliance error to the resource
-	AddComplianceError(msg string)
+	AddComplianceError(err *ComplianceError)
 
 	// AddComplianceWarning adds a compliance warning to the resource
-	AddComplianceWarning(msg string)
+	AddComplianceWarning(warning *ComplianceWarning)
 
 	// ComplianceError returns compliance validation errors
 	ComplianceErrors() []*ComplianceError
@@ -39,12 +43,82 @@ type CloudResource interface {
 	ComplianceWarnings() []*ComplianceWarning
 }
 
-// ComplianceError represents a warning encountered during tag validation.
+// TagNormalizer is implemented by resources of providers that restrict tag keys and
+// values (e.g. GCP labels), so policies written for other providers match them
+type TagNormalizer interface {
+	// NormalizeKey maps a policy tag key to the form the provider stores it in
+	NormalizeKey(key string) string
+
+	// NormalizeValue maps a policy tag value to the form the provider stores it in
+	NormalizeValue(value string) string
+}
+
+// UnknownValueReporter is implemented by resources whose tag values may only be known
+// at deploy time (e.g. planned Terraform changes), so value checks skip them
+type UnknownValueReporter interface {
+	// IsUnknownValue returns true if the value of the tag key is only known at deploy time,
+	// or the tag may only be set at deploy time
+	IsUnknownValue(key string) bool
+}
+
+// Code is a machine readable identifier of the check that produced a finding
+type Code string
+
+const (
+	// CodeMissingMandatoryTag indicates a mandatory tag is missing
+	CodeMissingMandatoryTag Code = "missing_mandatory_tag"
+	// CodeValueNotAllowed indicates a tag value is not in the allowed values
+	CodeValueNotAllowed Code = "value_not_allowed"
+	// CodeRegexMismatch indicates a tag value does not match the required regex
+	CodeRegexMismatch Code = "regex_mismatch"
+	// CodeInvalidBool indicates a tag value is not a valid boolean
+	CodeInvalidBool Code = "invalid_bool"
+	// CodeInvalidInt indicates a tag value is not a valid integer
+	CodeInvalidInt Code = "invalid_int"
+	// CodeBelowMinimum indicates a tag value is less than the minimum
+	CodeBelowMinimum Code = "below_minimum"
+	// CodeAboveMaximum indicates a tag value is greater than the maximum
+	CodeAboveMaximum Code = "above_maximum"
+	// CodeRuleMissingRequiredTag indicates a tag required by a rule is missing
+	CodeRuleMissingRequiredTag Code = "rule_missing_required_tag"
+	// CodeRuleMissingRecommendedTag indicates a tag recommended by a rule is missing
+	CodeRuleMissingRecommendedTag Code = "rule_missing_recommended_tag"
+	// CodeRuleError indicates a rule reported a custom error
+	CodeRuleError Code = "rule_error"
+	// CodeRuleWarning indicates a rule reported a custom warning
+	CodeRuleWarning Code = "rule_warning"
+	// CodeUnknownValue indicates a tag value is only known at deploy time and was not validated
+	CodeUnknownValue Code = "unknown_value"
+)
+
+// ComplianceError represents an error encountered during tag validation.
 type ComplianceError struct {
+	// Code identifies the check that failed
+	Code Code
+	// Message is the human readable description of the error
 	Message string
+	// Key is the offending tag key, if any
+	Key string
+	// Value is the actual tag value, if any
+	Value string
+	// Expected describes the constraint the value had to satisfy, if any
+	Expected string
+	// Origin is the policy location the check was defined in, if known
+	Origin *ptypes.Origin
 }
 
 // ComplianceWarning represents a warning encountered during tag validation.
 type ComplianceWarning struct {
+	// Code identifies the check that produced the warning
+	Code Code
+	// Message is the human readable description of the warning
 	Message string
+	// Key is the offending tag key, if any
+	Key string
+	// Value is the actual tag value, if any
+	Value string
+	// Expected describes the constraint the value had to satisfy, if any
+	Expected string
+	// Origin is the policy location the check was defined in, if known
+	Origin *ptypes.Origin
 }
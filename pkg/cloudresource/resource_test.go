@@ -0,0 +1,47 @@
+package cloudresource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenericResource(t *testing.T) {
+	t.Run("Basic Properties", func(t *testing.T) {
+		resource := &GenericResource{
+			ResourceID:     "i-1234567890abcdef0",
+			ResourceType:   "instance",
+			ServiceName:    "ec2",
+			ProviderName:   "cmdb",
+			ResourceRegion: "us-west-2",
+			Owner:          "123456789012",
+			ResourceTags:   map[string]string{"Name": "test-instance"},
+		}
+
+		assert.Equal(t, "i-1234567890abcdef0", resource.ID())
+		assert.Equal(t, "instance", resource.Type())
+		assert.Equal(t, "ec2", resource.Service())
+		assert.Equal(t, "cmdb", resource.Provider())
+		assert.Equal(t, "us-west-2", resource.Region())
+		assert.Equal(t, "123456789012", resource.OwnerID())
+		assert.Equal(t, map[string]string{"Name": "test-instance"}, resource.Tags())
+	})
+
+	t.Run("Compliance Status", func(t *testing.T) {
+		resource := &GenericResource{}
+		assert.True(t, resource.IsCompliant())
+
+		resource.AddComplianceWarning(&ComplianceWarning{Code: CodeRuleWarning, Message: "Warning"})
+		assert.True(t, resource.IsCompliant())
+		assert.Len(t, resource.ComplianceWarnings(), 1)
+
+		resource.AddComplianceError(&ComplianceError{Code: CodeMissingMandatoryTag, Message: "Error", Key: "owner"})
+		assert.False(t, resource.IsCompliant())
+		assert.Len(t, resource.ComplianceErrors(), 1)
+		assert.Equal(t, "owner", resource.ComplianceErrors()[0].Key)
+	})
+}
+
+func TestGenericResourceImplementsCloudResource(t *testing.T) {
+	var _ CloudResource = &GenericResource{}
+}
@@ -0,0 +1,223 @@
+package patrol
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// Diff holds the changes in non-compliant resources between a baseline run and the current run
+type Diff struct {
+	// New holds resources with compliance errors that were not in the baseline
+	New []*DiffEntry
+	// Fixed holds resources with compliance errors in the baseline that are gone now
+	Fixed []*DiffEntry
+	// Unchanged holds resources with compliance errors that are in both runs
+	Unchanged []*DiffEntry
+
+	results []Result
+}
+
+// DiffEntry is a single resource in a Diff along with its new, fixed or unchanged compliance errors
+type DiffEntry struct {
+	Definition string
+	Resource   cr.CloudResource
+	Errors     []*cr.ComplianceError
+}
+
+// Compare compares the current results against the baseline results. Compliance
+// errors are matched by resource definition, resource ID, code, tag key and value,
+// so a new violation on a resource that was already non-compliant is reported as
+// new. Resources of definitions that failed in the current run are neither new nor fixed.
+// Compliance warnings are matched the same way, and the warnings that are in the baseline
+// are left out of the results, so they don't fail the run either.
+func Compare(baseline, current []Result) *Diff {
+	known := make(map[string]*DiffEntry)
+	knownWarnings := make(map[string][]*cr.ComplianceWarning)
+	for _, result := range baseline {
+		for _, resource := range result.Resources {
+			entry := &DiffEntry{Definition: definitionName(result), Resource: resource, Errors: resource.ComplianceErrors()}
+			if !resource.IsCompliant() {
+				known[entry.key()] = entry
+			}
+			knownWarnings[entry.key()] = resource.ComplianceWarnings()
+		}
+	}
+
+	diff := &Diff{results: make([]Result, 0, len(current))}
+	failed := make(map[string]bool)
+
+	for _, result := range current {
+		if result.Error != nil {
+			failed[definitionName(result)] = true
+			diff.results = append(diff.results, result)
+			continue
+		}
+
+		narrowed := Result{
+			Definition:     result.Definition,
+			Resources:      make([]cr.CloudResource, 0, len(result.Resources)),
+			CompliantCount: result.CompliantCount,
+			Retries:        result.Retries,
+			Warnings:       result.Warnings,
+		}
+
+		for _, resource := range result.Resources {
+			resource := withoutKnownWarnings(resource, knownWarnings[resourceKey(definitionName(result), resource)])
+
+			if resource.IsCompliant() {
+				narrowed.Resources = append(narrowed.Resources, resource)
+				continue
+			}
+
+			added := &DiffEntry{Definition: definitionName(result), Resource: resource}
+			unchanged := &DiffEntry{Definition: added.Definition, Resource: resource}
+			base := known[added.key()]
+
+			for _, e := range resource.ComplianceErrors() {
+				if base.remove(e) {
+					unchanged.Errors = append(unchanged.Errors, e)
+				} else {
+					added.Errors = append(added.Errors, e)
+				}
+			}
+
+			if len(unchanged.Errors) > 0 {
+				diff.Unchanged = append(diff.Unchanged, unchanged)
+			}
+			if len(added.Errors) > 0 {
+				diff.New = append(diff.New, added)
+				narrowed.Resources = append(narrowed.Resources, resource)
+				narrowed.NonCompliantCount++
+			}
+		}
+
+		diff.results = append(diff.results, narrowed)
+	}
+
+	for _, entry := range known {
+		if len(entry.Errors) > 0 && !failed[entry.Definition] {
+			diff.Fixed = append(diff.Fixed, entry)
+		}
+	}
+
+	sortEntries(diff.New)
+	sortEntries(diff.Fixed)
+	sortEntries(diff.Unchanged)
+
+	return diff
+}
+
+// Results returns the current results without the unchanged non-compliant
+// resources, so reports and gates only account for new violations
+func (d *Diff) Results() []Result {
+	return d.results
+}
+
+// String returns a human readable baseline comparison report
+func (d *Diff) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Baseline comparison:\n")
+	fmt.Fprintf(&b, "  New violations: %d resources\n", len(d.New))
+	fmt.Fprintf(&b, "  Fixed: %d resources\n", len(d.Fixed))
+	fmt.Fprintf(&b, "  Unchanged: %d resources\n", len(d.Unchanged))
+
+	writeEntries(&b, "New violations", d.New)
+	writeEntries(&b, "Fixed", d.Fixed)
+
+	return b.String()
+}
+
+func (e *DiffEntry) key() string {
+	return resourceKey(e.Definition, e.Resource)
+}
+
+func resourceKey(definition string, resource cr.CloudResource) string {
+	return definition + "\x00" + resource.ID()
+}
+
+// remove removes a compliance error matching e by code, tag key and value, it reports
+// whether one was found
+func (e *DiffEntry) remove(err *cr.ComplianceError) bool {
+	if e == nil {
+		return false
+	}
+
+	for i, known := range e.Errors {
+		if known.Code == err.Code && known.Key == err.Key && known.Value == err.Value {
+			e.Errors = slices.Delete(slices.Clone(e.Errors), i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// baselineResource is a resource of the current run without the compliance warnings
+// that are in the baseline
+type baselineResource struct {
+	cr.CloudResource
+	warnings []*cr.ComplianceWarning
+}
+
+// ComplianceWarnings returns the compliance warnings that are not in the baseline
+func (r *baselineResource) ComplianceWarnings() []*cr.ComplianceWarning {
+	return r.warnings
+}
+
+// withoutKnownWarnings returns the resource without the compliance warnings matching the
+// known ones by code, tag key and value, the resource itself when none match
+func withoutKnownWarnings(resource cr.CloudResource, known []*cr.ComplianceWarning) cr.CloudResource {
+	if len(known) == 0 || len(resource.ComplianceWarnings()) == 0 {
+		return resource
+	}
+
+	known = slices.Clone(known)
+	var warnings []*cr.ComplianceWarning
+
+	for _, w := range resource.ComplianceWarnings() {
+		i := slices.IndexFunc(known, func(k *cr.ComplianceWarning) bool {
+			return k.Code == w.Code && k.Key == w.Key && k.Value == w.Value
+		})
+		if i < 0 {
+			warnings = append(warnings, w)
+			continue
+		}
+		known = slices.Delete(known, i, i+1)
+	}
+
+	if len(warnings) == len(resource.ComplianceWarnings()) {
+		return resource
+	}
+	return &baselineResource{CloudResource: resource, warnings: warnings}
+}
+
+func sortEntries(entries []*DiffEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Definition != entries[j].Definition {
+			return entries[i].Definition < entries[j].Definition
+		}
+		return entries[i].Resource.ID() < entries[j].Resource.ID()
+	})
+}
+
+func writeEntries(b *strings.Builder, title string, entries []*DiffEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, entry := range entries {
+		fmt.Fprintf(b, "  %s: %s\n", entry.Definition, entry.Resource.ID())
+		for _, e := range entry.Errors {
+			fmt.Fprintf(b, "    - %s\n", e.Message)
+		}
+	}
+}
+
+func definitionName(result Result) string {
+	return fmt.Sprintf("%s.%s", result.Definition.Service, result.Definition.ResourceType)
+}
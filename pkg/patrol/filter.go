@@ -0,0 +1,74 @@
+package patrol
+
+import (
+	"slices"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+)
+
+// Filter narrows down patrol results, empty fields match everything
+type Filter struct {
+	Services         []string
+	ResourceTypes    []string
+	Owners           []string
+	Regions          []string
+	NonCompliantOnly bool
+}
+
+// IsEmpty returns true if the filter matches every result
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Services) == 0 && len(f.ResourceTypes) == 0 &&
+		len(f.Owners) == 0 && len(f.Regions) == 0 && !f.NonCompliantOnly)
+}
+
+// Apply returns the results matching the filter with their counts recomputed
+func (f *Filter) Apply(results []Result) []Result {
+	if f.IsEmpty() {
+		return results
+	}
+
+	filtered := make([]Result, 0, len(results))
+
+	for _, result := range results {
+		if !matches(f.Services, result.Definition.Service) || !matches(f.ResourceTypes, result.Definition.ResourceType) {
+			continue
+		}
+
+		if result.Error != nil {
+			filtered = append(filtered, result)
+			continue
+		}
+
+		narrowed := Result{
+			Definition: result.Definition,
+			Resources:  make([]cr.CloudResource, 0, len(result.Resources)),
+			Retries:    result.Retries,
+			Warnings:   result.Warnings,
+		}
+
+		for _, resource := range result.Resources {
+			if !matches(f.Owners, resource.OwnerID()) || !matches(f.Regions, resource.Region()) {
+				continue
+			}
+
+			if resource.IsCompliant() {
+				if f.NonCompliantOnly {
+					continue
+				}
+				narrowed.CompliantCount++
+			} else {
+				narrowed.NonCompliantCount++
+			}
+
+			narrowed.Resources = append(narrowed.Resources, resource)
+		}
+
+		filtered = append(filtered, narrowed)
+	}
+
+	return filtered
+}
+
+func matches(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
@@ -0,0 +1,100 @@
+package patrol
+
+import (
+	"fmt"
+)
+
+// FailOn represents the lowest finding severity that fails a patrol run
+type FailOn string
+
+const (
+	// FailOnError fails the run when any resource is non-compliant
+	FailOnError FailOn = "error"
+	// FailOnWarning fails the run when any resource has a compliance error or warning
+	FailOnWarning FailOn = "warning"
+	// FailOnNone never fails the run based on findings
+	FailOnNone FailOn = "none"
+)
+
+// Gate defines the thresholds the results of a patrol run must meet
+type Gate struct {
+	// FailOn sets the lowest finding severity that fails the run
+	FailOn FailOn
+	// MinCompliance is the minimal percentage of compliant resources, 0 disables the check
+	MinCompliance float64
+	// MaxNonCompliant is the maximal number of non-compliant resources allowed
+	// per resource definition, a negative value disables the check
+	MaxNonCompliant int
+}
+
+// DefaultGate returns a gate that never fails the run
+func DefaultGate() *Gate {
+	return &Gate{
+		FailOn:          FailOnNone,
+		MinCompliance:   0,
+		MaxNonCompliant: -1,
+	}
+}
+
+// Validate checks the gate configuration
+func (g *Gate) Validate() error {
+	switch g.FailOn {
+	case FailOnError, FailOnWarning, FailOnNone:
+	default:
+		return fmt.Errorf("invalid fail-on value `%s`, must be one of: %s, %s, %s", g.FailOn, FailOnError, FailOnWarning, FailOnNone)
+	}
+
+	if g.MinCompliance < 0 || g.MinCompliance > 100 {
+		return fmt.Errorf("invalid minimum compliance %.1f%%, must be between 0 and 100", g.MinCompliance)
+	}
+
+	return nil
+}
+
+// Evaluate checks the results against the gate and returns the reasons the
+// run failed, an empty slice means the results pass the gate
+func (g *Gate) Evaluate(results []Result) []string {
+	var violations []string
+
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+
+		name := definitionName(result)
+
+		switch g.FailOn {
+		case FailOnError:
+			if result.NonCompliantCount > 0 {
+				violations = append(violations, fmt.Sprintf("%s has %d non-compliant resource(s)", name, result.NonCompliantCount))
+			}
+		case FailOnWarning:
+			if flagged := countFlagged(result); flagged > 0 {
+				violations = append(violations, fmt.Sprintf("%s has %d resource(s) with compliance errors or warnings", name, flagged))
+			}
+		}
+
+		if g.MaxNonCompliant >= 0 && result.NonCompliantCount > g.MaxNonCompliant {
+			violations = append(violations, fmt.Sprintf("%s has %d non-compliant resource(s), maximum allowed is %d", name, result.NonCompliantCount, g.MaxNonCompliant))
+		}
+	}
+
+	if g.MinCompliance > 0 {
+		summary := Summarize(results)
+		if summary.Resources > 0 && summary.CompliantPercentage() < g.MinCompliance {
+			violations = append(violations, fmt.Sprintf("compliance is %.1f%%, minimum required is %.1f%%", summary.CompliantPercentage(), g.MinCompliance))
+		}
+	}
+
+	return violations
+}
+
+func countFlagged(result Result) int {
+	count := 0
+	for _, resource := range result.Resources {
+		if !resource.IsCompliant() || len(resource.ComplianceWarnings()) > 0 {
+			count++
+		}
+	}
+	return count
+}
@@ -2,8 +2,10 @@ package patrol
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
 	"github.com/eliran89c/tag-patrol/pkg/policy"
@@ -18,12 +20,22 @@ type Result struct {
 	CompliantCount    int
 	NonCompliantCount int
 	Error             error
+
+	// Retries is the number of API calls the finder retried while finding the resources
+	Retries int
+
+	// Warnings are the problems the finder reported while finding the resources, e.g. when
+	// resources may be missing
+	Warnings []string
 }
 
 // Options configures the behavior of the Patrol
 type Options struct {
 	ConcurrentWorkers int
 	StopOnError       bool
+
+	// DefinitionTimeout bounds the time spent finding the resources of a definition, no limit when zero
+	DefinitionTimeout time.Duration
 }
 
 // DefaultOptions returns the default Patrol options
@@ -60,6 +72,18 @@ type Finder interface {
 	FindResources(ctx context.Context, service, resourceType string) ([]cr.CloudResource, error)
 }
 
+// RetryCounter is implemented by finders that retry failed API calls, it returns the
+// number of retries of the last search of a resource type
+type RetryCounter interface {
+	Retries(service, resourceType string) int
+}
+
+// WarningReporter is implemented by finders that report problems that don't fail a search,
+// it returns the warnings of the last search of a resource type
+type WarningReporter interface {
+	Warnings(service, resourceType string) []string
+}
+
 // New creates a new Patrol with the specified resource finder and options
 func New(resourceFinder Finder, options *Options) *Patrol {
 	if options == nil {
@@ -145,8 +169,24 @@ func (p *Patrol) Run(ctx context.Context, definitions []*ptypes.ResourceDefiniti
 				Definition: def,
 			}
 
-			resources, err := p.ResourceFinder.FindResources(ctx, def.Service, def.ResourceType)
+			findCtx := ctx
+			if p.Options.DefinitionTimeout > 0 {
+				var cancel context.CancelFunc
+				findCtx, cancel = context.WithTimeout(ctx, p.Options.DefinitionTimeout)
+				defer cancel()
+			}
+
+			resources, err := p.ResourceFinder.FindResources(findCtx, def.Service, def.ResourceType)
+			if counter, ok := p.ResourceFinder.(RetryCounter); ok {
+				result.Retries = counter.Retries(def.Service, def.ResourceType)
+			}
+			if reporter, ok := p.ResourceFinder.(WarningReporter); ok {
+				result.Warnings = reporter.Warnings(def.Service, def.ResourceType)
+			}
 			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) && findCtx.Err() != nil && ctx.Err() == nil {
+					err = fmt.Errorf("timed out after %s: %w", p.Options.DefinitionTimeout, err)
+				}
 				result.Error = fmt.Errorf("error finding resources for %s.%s: %w", def.Service, def.ResourceType, err)
 
 				resultsMutex.Lock()
@@ -185,46 +225,87 @@ func (p *Patrol) Run(ctx context.Context, definitions []*ptypes.ResourceDefiniti
 	return results, nil
 }
 
-// Summary generates a summary report of the patrol results
-func (p *Patrol) Summary(results []Result) string {
-	var (
-		totalResources        int
-		totalCompliant        int
-		totalNonCompliant     int
-		definitionsWithErrors int
-	)
+// Summary holds the aggregated totals of a patrol run
+type Summary struct {
+	Definitions             int
+	Resources               int
+	Compliant               int
+	NonCompliant            int
+	DefinitionsWithErrors   int
+	DefinitionsWithWarnings int
+	Retries                 int
+}
+
+// Summarize aggregates the totals of the given patrol results
+func Summarize(results []Result) *Summary {
+	summary := &Summary{Definitions: len(results)}
 
 	for _, result := range results {
+		summary.Retries += result.Retries
+		if len(result.Warnings) > 0 {
+			summary.DefinitionsWithWarnings++
+		}
+
 		if result.Error != nil {
-			definitionsWithErrors++
+			summary.DefinitionsWithErrors++
 			continue
 		}
 
-		totalResources += len(result.Resources)
-		totalCompliant += result.CompliantCount
-		totalNonCompliant += result.NonCompliantCount
+		summary.Resources += len(result.Resources)
+		summary.Compliant += result.CompliantCount
+		summary.NonCompliant += result.NonCompliantCount
 	}
 
-	return fmt.Sprintf(
+	return summary
+}
+
+// CompliantPercentage returns the percentage of compliant resources
+func (s *Summary) CompliantPercentage() float64 {
+	return Percentage(s.Compliant, s.Resources)
+}
+
+// NonCompliantPercentage returns the percentage of non-compliant resources
+func (s *Summary) NonCompliantPercentage() float64 {
+	return Percentage(s.NonCompliant, s.Resources)
+}
+
+// String returns a human readable summary report
+func (s *Summary) String() string {
+	report := fmt.Sprintf(
 		"Summary:\n"+
 			"  Processed %d resource definitions\n"+
 			"  Found %d resources\n"+
 			"  Compliant: %d resources (%.1f%%)\n"+
 			"  Non-compliant: %d resources (%.1f%%)\n"+
 			"  Errors: %d resource definitions had errors\n",
-		len(results),
-		totalResources,
-		totalCompliant,
-		percentage(totalCompliant, totalResources),
-		totalNonCompliant,
-		percentage(totalNonCompliant, totalResources),
-		definitionsWithErrors,
+		s.Definitions,
+		s.Resources,
+		s.Compliant,
+		s.CompliantPercentage(),
+		s.NonCompliant,
+		s.NonCompliantPercentage(),
+		s.DefinitionsWithErrors,
 	)
+
+	if s.DefinitionsWithWarnings > 0 {
+		report += fmt.Sprintf("  Warnings: %d resource definitions had warnings, results may be incomplete\n", s.DefinitionsWithWarnings)
+	}
+	if s.Retries > 0 {
+		report += fmt.Sprintf("  Retries: %d API calls were retried\n", s.Retries)
+	}
+
+	return report
+}
+
+// Summary generates a summary report of the patrol results
+func (p *Patrol) Summary(results []Result) string {
+	return Summarize(results).String()
 }
 
-func percentage(a, b int) float64 {
-	if b == 0 {
+// Percentage returns part as a percentage of total, 0 when total is 0
+func Percentage(part, total int) float64 {
+	if total == 0 {
 		return 0.0
 	}
-	return float64(a) * 100.0 / float64(b)
+	return float64(part) * 100.0 / float64(total)
 }
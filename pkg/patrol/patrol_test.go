@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
 	"github.com/eliran89c/tag-patrol/pkg/policy/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockResource struct {
@@ -69,12 +71,12 @@ func (m *MockResource) IsCompliant() bool {
 	return len(m.errors) == 0
 }
 
-func (m *MockResource) AddComplianceError(msg string) {
-	m.errors = append(m.errors, &cr.ComplianceError{Message: msg})
+func (m *MockResource) AddComplianceError(err *cr.ComplianceError) {
+	m.errors = append(m.errors, err)
 }
 
-func (m *MockResource) AddComplianceWarning(msg string) {
-	m.warnings = append(m.warnings, &cr.ComplianceWarning{Message: msg})
+func (m *MockResource) AddComplianceWarning(warning *cr.ComplianceWarning) {
+	m.warnings = append(m.warnings, warning)
 }
 
 func (m *MockResource) ComplianceErrors() []*cr.ComplianceError {
@@ -218,7 +220,7 @@ func TestRunFromFile(t *testing.T) {
 		map[string]string{"name": "test2"},
 	)
 
-	resource2.AddComplianceError("Missing mandatory tag: `environment`")
+	resource2.AddComplianceError(&cr.ComplianceError{Message: "Missing mandatory tag: `environment`"})
 
 	resources := []cr.CloudResource{resource1, resource2}
 
@@ -507,6 +509,51 @@ func TestRunWithContextCancelled(t *testing.T) {
 	assert.Empty(t, results)
 }
 
+// slowFinder blocks until the context is done and reports fixed retries and warnings
+type slowFinder struct {
+	retries int
+}
+
+func (f *slowFinder) FindResources(ctx context.Context, service, resourceType string) ([]cr.CloudResource, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *slowFinder) Retries(service, resourceType string) int {
+	return f.retries
+}
+
+func (f *slowFinder) Warnings(service, resourceType string) []string {
+	return []string{"results may be truncated"}
+}
+
+func TestRunWithDefinitionTimeout(t *testing.T) {
+	patrol := &Patrol{
+		ResourceFinder: &slowFinder{retries: 3},
+		Ruler:          new(MockRuler),
+		Options:        &Options{ConcurrentWorkers: 1, DefinitionTimeout: 10 * time.Millisecond},
+	}
+
+	resourceDef := &types.ResourceDefinition{
+		Service:      "ec2",
+		ResourceType: "instance",
+		TagPolicy:    &types.TagPolicy{},
+	}
+
+	results, err := patrol.Run(context.Background(), []*types.ResourceDefinition{resourceDef})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Error, context.DeadlineExceeded)
+	assert.Contains(t, results[0].Error.Error(), "timed out after 10ms")
+	assert.Equal(t, 3, results[0].Retries)
+	assert.Equal(t, 3, Summarize(results).Retries)
+	assert.Contains(t, Summarize(results).String(), "Retries: 3 API calls were retried")
+	assert.Equal(t, []string{"results may be truncated"}, results[0].Warnings)
+	assert.Equal(t, 1, Summarize(results).DefinitionsWithWarnings)
+	assert.Contains(t, Summarize(results).String(), "Warnings: 1 resource definitions had warnings")
+}
+
 func TestSummary(t *testing.T) {
 	patrol := &Patrol{
 		Options: DefaultOptions(),
@@ -549,3 +596,313 @@ func TestSummary(t *testing.T) {
 	assert.Contains(t, summary, "Non-compliant: 2 resources")
 	assert.Contains(t, summary, "Errors: 1 resource definitions had errors")
 }
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{
+			Definition:        &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+			Resources:         make([]cr.CloudResource, 4),
+			CompliantCount:    3,
+			NonCompliantCount: 1,
+		},
+		{
+			Definition: &types.ResourceDefinition{Service: "rds", ResourceType: "instance"},
+			Error:      errors.New("test error"),
+		},
+	}
+
+	summary := Summarize(results)
+
+	assert.Equal(t, 2, summary.Definitions)
+	assert.Equal(t, 4, summary.Resources)
+	assert.Equal(t, 3, summary.Compliant)
+	assert.Equal(t, 1, summary.NonCompliant)
+	assert.Equal(t, 1, summary.DefinitionsWithErrors)
+	assert.Equal(t, 75.0, summary.CompliantPercentage())
+	assert.Equal(t, 25.0, summary.NonCompliantPercentage())
+
+	empty := Summarize(nil)
+	assert.Equal(t, 0.0, empty.CompliantPercentage())
+	assert.Equal(t, 0.0, empty.NonCompliantPercentage())
+}
+
+func TestGate(t *testing.T) {
+	compliant := NewMockResource("res-1", "instance", "ec2", "aws", "us-east-1", "123", nil)
+
+	warned := NewMockResource("res-2", "instance", "ec2", "aws", "us-east-1", "123", nil)
+	warned.AddComplianceWarning(&cr.ComplianceWarning{Message: "warning"})
+
+	nonCompliant := NewMockResource("res-3", "bucket", "s3", "aws", "us-east-1", "123", nil)
+	nonCompliant.AddComplianceError(&cr.ComplianceError{Message: "error"})
+
+	results := []Result{
+		{
+			Definition:     &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+			Resources:      []cr.CloudResource{compliant, warned},
+			CompliantCount: 2,
+		},
+		{
+			Definition:        &types.ResourceDefinition{Service: "s3", ResourceType: "bucket"},
+			Resources:         []cr.CloudResource{nonCompliant},
+			NonCompliantCount: 1,
+		},
+		{
+			Definition: &types.ResourceDefinition{Service: "rds", ResourceType: "db"},
+			Error:      errors.New("test error"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		gate       *Gate
+		violations []string
+	}{
+		{
+			name: "Default gate",
+			gate: DefaultGate(),
+		},
+		{
+			name:       "Fail on error",
+			gate:       &Gate{FailOn: FailOnError, MaxNonCompliant: -1},
+			violations: []string{"s3.bucket has 1 non-compliant resource(s)"},
+		},
+		{
+			name: "Fail on warning",
+			gate: &Gate{FailOn: FailOnWarning, MaxNonCompliant: -1},
+			violations: []string{
+				"ec2.instance has 1 resource(s) with compliance errors or warnings",
+				"s3.bucket has 1 resource(s) with compliance errors or warnings",
+			},
+		},
+		{
+			name:       "Minimum compliance not met",
+			gate:       &Gate{FailOn: FailOnNone, MinCompliance: 80, MaxNonCompliant: -1},
+			violations: []string{"compliance is 66.7%, minimum required is 80.0%"},
+		},
+		{
+			name: "Minimum compliance met",
+			gate: &Gate{FailOn: FailOnNone, MinCompliance: 50, MaxNonCompliant: -1},
+		},
+		{
+			name: "Max non-compliant per definition",
+			gate: &Gate{FailOn: FailOnNone, MaxNonCompliant: 0},
+			violations: []string{
+				"s3.bucket has 1 non-compliant resource(s), maximum allowed is 0",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, tc.gate.Validate())
+			assert.Equal(t, tc.violations, tc.gate.Evaluate(results))
+		})
+	}
+}
+
+func TestGateValidate(t *testing.T) {
+	assert.Error(t, (&Gate{FailOn: "sometimes"}).Validate())
+	assert.Error(t, (&Gate{FailOn: FailOnError, MinCompliance: 101}).Validate())
+	assert.Error(t, (&Gate{FailOn: FailOnError, MinCompliance: -1}).Validate())
+	assert.NoError(t, DefaultGate().Validate())
+}
+
+func TestFilter(t *testing.T) {
+	compliant := NewMockResource("res-1", "instance", "ec2", "aws", "us-east-1", "111", nil)
+
+	nonCompliant := NewMockResource("res-2", "instance", "ec2", "aws", "eu-west-1", "222", nil)
+	nonCompliant.AddComplianceError(&cr.ComplianceError{Message: "error"})
+
+	bucket := NewMockResource("res-3", "bucket", "s3", "aws", "us-east-1", "111", nil)
+
+	results := []Result{
+		{
+			Definition:        &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+			Resources:         []cr.CloudResource{compliant, nonCompliant},
+			CompliantCount:    1,
+			NonCompliantCount: 1,
+			Retries:           2,
+			Warnings:          []string{"some resources may be missing"},
+		},
+		{
+			Definition:     &types.ResourceDefinition{Service: "s3", ResourceType: "bucket"},
+			Resources:      []cr.CloudResource{bucket},
+			CompliantCount: 1,
+		},
+		{
+			Definition: &types.ResourceDefinition{Service: "rds", ResourceType: "db"},
+			Error:      errors.New("test error"),
+		},
+	}
+
+	t.Run("Empty filter", func(t *testing.T) {
+		assert.Equal(t, results, (&Filter{}).Apply(results))
+		assert.Equal(t, results, (*Filter)(nil).Apply(results))
+	})
+
+	t.Run("Services", func(t *testing.T) {
+		filtered := (&Filter{Services: []string{"ec2", "rds"}}).Apply(results)
+		require.Len(t, filtered, 2)
+		assert.Equal(t, "ec2", filtered[0].Definition.Service)
+		assert.Equal(t, "rds", filtered[1].Definition.Service)
+		assert.Error(t, filtered[1].Error)
+	})
+
+	t.Run("Owners and regions", func(t *testing.T) {
+		filtered := (&Filter{Owners: []string{"222"}, Regions: []string{"eu-west-1"}}).Apply(results)
+		require.Len(t, filtered, 3)
+		assert.Equal(t, []cr.CloudResource{nonCompliant}, filtered[0].Resources)
+		assert.Equal(t, 0, filtered[0].CompliantCount)
+		assert.Equal(t, 1, filtered[0].NonCompliantCount)
+		assert.Equal(t, 2, filtered[0].Retries)
+		assert.Equal(t, []string{"some resources may be missing"}, filtered[0].Warnings)
+		assert.Empty(t, filtered[1].Resources)
+	})
+
+	t.Run("Non-compliant only", func(t *testing.T) {
+		filtered := (&Filter{NonCompliantOnly: true}).Apply(results)
+		assert.Equal(t, []cr.CloudResource{nonCompliant}, filtered[0].Resources)
+		assert.Empty(t, filtered[1].Resources)
+		assert.Equal(t, 0, filtered[1].CompliantCount)
+	})
+}
+
+func TestCompare(t *testing.T) {
+	newResource := func(id string, compliant bool) *MockResource {
+		resource := NewMockResource(id, "instance", "ec2", "aws", "us-east-1", "123", nil)
+		if !compliant {
+			resource.AddComplianceError(&cr.ComplianceError{Message: "error"})
+		}
+		return resource
+	}
+
+	withErrors := func(id string, errs ...*cr.ComplianceError) *MockResource {
+		resource := NewMockResource(id, "instance", "ec2", "aws", "us-east-1", "123", nil)
+		for _, e := range errs {
+			resource.AddComplianceError(e)
+		}
+		return resource
+	}
+	missingOwner := &cr.ComplianceError{Code: cr.CodeMissingMandatoryTag, Message: "Missing mandatory tag: `owner`", Key: "owner"}
+	invalidEnv := func(value string) *cr.ComplianceError {
+		return &cr.ComplianceError{Code: cr.CodeValueNotAllowed, Message: "Tag `env` has invalid value `" + value + "`", Key: "env", Value: value}
+	}
+
+	ec2 := &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"}
+	rds := &types.ResourceDefinition{Service: "rds", ResourceType: "db"}
+
+	baseline := []Result{
+		{
+			Definition: ec2,
+			Resources: []cr.CloudResource{
+				newResource("debt", false), newResource("fixed", false), newResource("regressed", true),
+				withErrors("worse", missingOwner), withErrors("changed", invalidEnv("qa")),
+			},
+			CompliantCount:    1,
+			NonCompliantCount: 4,
+		},
+		{
+			Definition:        rds,
+			Resources:         []cr.CloudResource{newResource("db-1", false)},
+			NonCompliantCount: 1,
+		},
+	}
+
+	current := []Result{
+		{
+			Definition: ec2,
+			Resources: []cr.CloudResource{
+				newResource("debt", false), newResource("fixed", true), newResource("regressed", false), newResource("added", false),
+				withErrors("worse", missingOwner, invalidEnv("qa")), withErrors("changed", invalidEnv("Qa")),
+			},
+			CompliantCount:    1,
+			NonCompliantCount: 5,
+			Retries:           2,
+			Warnings:          []string{"some resources may be missing"},
+		},
+		{
+			Definition: rds,
+			Error:      errors.New("test error"),
+		},
+	}
+
+	diff := Compare(baseline, current)
+
+	ids := func(entries []*DiffEntry) []string {
+		var out []string
+		for _, entry := range entries {
+			out = append(out, entry.Definition+"/"+entry.Resource.ID())
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"ec2.instance/added", "ec2.instance/changed", "ec2.instance/regressed", "ec2.instance/worse"}, ids(diff.New))
+	assert.Equal(t, []string{"ec2.instance/changed", "ec2.instance/fixed"}, ids(diff.Fixed))
+	assert.Equal(t, []string{"ec2.instance/debt", "ec2.instance/worse"}, ids(diff.Unchanged))
+
+	// only the new error of a resource that was already non-compliant is new
+	assert.Equal(t, []*cr.ComplianceError{invalidEnv("qa")}, diff.New[3].Errors)
+	assert.Equal(t, []*cr.ComplianceError{missingOwner}, diff.Unchanged[1].Errors)
+	assert.Equal(t, []*cr.ComplianceError{invalidEnv("qa")}, diff.Fixed[0].Errors)
+
+	results := diff.Results()
+	require.Len(t, results, 2)
+	assert.Len(t, results[0].Resources, 5)
+	assert.Equal(t, 1, results[0].CompliantCount)
+	assert.Equal(t, 4, results[0].NonCompliantCount)
+	assert.Equal(t, 2, results[0].Retries)
+	assert.Equal(t, []string{"some resources may be missing"}, results[0].Warnings)
+	assert.Error(t, results[1].Error)
+
+	report := diff.String()
+	assert.Contains(t, report, "New violations: 4 resources")
+	assert.Contains(t, report, "Fixed: 2 resources")
+	assert.Contains(t, report, "Unchanged: 2 resources")
+	assert.Contains(t, report, "  ec2.instance: added\n    - error\n")
+	assert.NotContains(t, report, "debt")
+
+	gate := &Gate{FailOn: FailOnError, MaxNonCompliant: -1}
+	assert.Empty(t, gate.Evaluate(Compare(current, current).Results()))
+}
+
+func TestCompareWarnings(t *testing.T) {
+	ec2 := &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"}
+	unknownEnv := &cr.ComplianceWarning{Code: cr.CodeUnknownValue, Message: "Tag `env` value is only known after apply", Key: "env", Value: "(known after apply)"}
+	missingCostCenter := &cr.ComplianceWarning{Code: cr.CodeRuleMissingRecommendedTag, Message: "Missing recommended tag `cost-center`", Key: "cost-center"}
+
+	withWarnings := func(id string, warnings ...*cr.ComplianceWarning) *MockResource {
+		resource := NewMockResource(id, "instance", "ec2", "aws", "us-east-1", "123", nil)
+		for _, w := range warnings {
+			resource.AddComplianceWarning(w)
+		}
+		return resource
+	}
+
+	baseline := []Result{{
+		Definition:     ec2,
+		Resources:      []cr.CloudResource{withWarnings("legacy", unknownEnv), withWarnings("worse", unknownEnv)},
+		CompliantCount: 2,
+	}}
+	current := []Result{{
+		Definition:     ec2,
+		Resources:      []cr.CloudResource{withWarnings("legacy", unknownEnv), withWarnings("worse", unknownEnv, missingCostCenter)},
+		CompliantCount: 2,
+	}}
+
+	gate := &Gate{FailOn: FailOnWarning, MaxNonCompliant: -1}
+
+	// warnings that are in the baseline don't fail the run
+	results := Compare(baseline, baseline).Results()
+	require.Len(t, results[0].Resources, 2)
+	assert.Empty(t, results[0].Resources[0].ComplianceWarnings())
+	assert.Empty(t, gate.Evaluate(results))
+
+	// only the new warning of a resource is kept
+	results = Compare(baseline, current).Results()
+	require.Len(t, results[0].Resources, 2)
+	assert.Equal(t, 2, results[0].CompliantCount)
+	assert.Empty(t, results[0].Resources[0].ComplianceWarnings())
+	assert.Equal(t, []*cr.ComplianceWarning{missingCostCenter}, results[0].Resources[1].ComplianceWarnings())
+	assert.Equal(t, "worse", results[0].Resources[1].ID())
+	assert.Equal(t, []string{"ec2.instance has 1 resource(s) with compliance errors or warnings"}, gate.Evaluate(results))
+}
@@ -84,6 +84,11 @@ func (p *DefaultParser) processResource(config *ptypes.Policy, service, resource
 			MandatoryKeys: make([]string, 0),
 			Validations:   make(map[string]*ptypes.Validation),
 			Rules:         make([]*ptypes.Rule, 0),
+			Origins: &ptypes.Origins{
+				MandatoryKeys: make(map[string]*ptypes.Origin),
+				Validations:   make(map[string]*ptypes.Origin),
+				Rules:         make([]*ptypes.Origin, 0),
+			},
 		},
 	}
 
@@ -99,11 +104,14 @@ func (p *DefaultParser) processResource(config *ptypes.Policy, service, resource
 		}
 	}
 
+	origins := definition.Origins
+	resourceName := fmt.Sprintf("%s.%s", service, resourceType)
 	resourceKeys := make(map[string]bool)
 
 	if resourceConfig.MandatoryKeys != nil {
 		for _, key := range resourceConfig.MandatoryKeys {
 			resourceKeys[key] = true
+			origins.MandatoryKeys[key] = newOrigin(ptypes.OriginResource, resourceName, -1)
 		}
 	}
 
@@ -118,6 +126,7 @@ func (p *DefaultParser) processResource(config *ptypes.Policy, service, resource
 				for _, key := range blueprint.MandatoryKeys {
 					if !resourceKeys[key] {
 						resourceKeys[key] = true
+						origins.MandatoryKeys[key] = newOrigin(ptypes.OriginBlueprint, name, -1)
 					}
 				}
 			}
@@ -126,17 +135,22 @@ func (p *DefaultParser) processResource(config *ptypes.Policy, service, resource
 				for key, validation := range blueprint.Validations {
 					if resourceConfig.Validations == nil {
 						definition.Validations[key] = validation
+						origins.Validations[key] = newOrigin(ptypes.OriginBlueprint, name, -1)
 						continue
 					}
 
 					if _, hasValidation := resourceConfig.Validations[key]; !hasValidation {
 						definition.Validations[key] = validation
+						origins.Validations[key] = newOrigin(ptypes.OriginBlueprint, name, -1)
 					}
 				}
 			}
 
 			if blueprint.Rules != nil {
 				definition.Rules = append(definition.Rules, blueprint.Rules...)
+				for i := range blueprint.Rules {
+					origins.Rules = append(origins.Rules, newOrigin(ptypes.OriginBlueprint, name, i))
+				}
 			}
 		}
 	}
@@ -150,11 +164,25 @@ func (p *DefaultParser) processResource(config *ptypes.Policy, service, resource
 			definition.Validations = make(map[string]*ptypes.Validation)
 		}
 		maps.Copy(definition.Validations, resourceConfig.Validations)
+		for key := range resourceConfig.Validations {
+			origins.Validations[key] = newOrigin(ptypes.OriginResource, resourceName, -1)
+		}
 	}
 
 	if resourceConfig.Rules != nil {
 		definition.Rules = append(definition.Rules, resourceConfig.Rules...)
+		for i := range resourceConfig.Rules {
+			origins.Rules = append(origins.Rules, newOrigin(ptypes.OriginResource, resourceName, i))
+		}
 	}
 
 	return definition, nil
 }
+
+func newOrigin(kind ptypes.OriginKind, name string, ruleIndex int) *ptypes.Origin {
+	return &ptypes.Origin{
+		Kind:      kind,
+		Name:      name,
+		RuleIndex: ruleIndex,
+	}
+}
@@ -303,6 +303,13 @@ resources:
 	assert.Contains(t, def.Validations, "name")
 
 	assert.Len(t, def.Rules, 1)
+
+	assert.Equal(t, &types.Origin{Kind: types.OriginBlueprint, Name: "base", RuleIndex: -1}, def.Origins.MandatoryKey("environment"))
+	assert.Equal(t, &types.Origin{Kind: types.OriginResource, Name: "ec2.instance", RuleIndex: -1}, def.Origins.MandatoryKey("name"))
+	assert.Equal(t, &types.Origin{Kind: types.OriginBlueprint, Name: "production", RuleIndex: -1}, def.Origins.Validation("cost-center"))
+	assert.Equal(t, &types.Origin{Kind: types.OriginResource, Name: "ec2.instance", RuleIndex: -1}, def.Origins.Validation("name"))
+	assert.Equal(t, &types.Origin{Kind: types.OriginBlueprint, Name: "production", RuleIndex: 0}, def.Origins.Rule(0))
+	assert.Nil(t, def.Origins.Rule(1))
 }
 
 func TestInvalidPolicies(t *testing.T) {
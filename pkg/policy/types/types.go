@@ -17,6 +17,58 @@ type TagPolicy struct {
 	MandatoryKeys []string               `yaml:"mandatoryKeys" validate:"omitempty,dive,required"`
 	Validations   map[string]*Validation `yaml:"validations" validate:"omitempty,dive"`
 	Rules         []*Rule                `yaml:"rules" validate:"omitempty,dive"`
+	Origins       *Origins               `yaml:"-" validate:"-"`
+}
+
+// OriginKind represents the kind of policy element a check was defined in
+type OriginKind string
+
+const (
+	// OriginBlueprint indicates the check was defined in a blueprint
+	OriginBlueprint OriginKind = "blueprint"
+	// OriginResource indicates the check was defined in a resource configuration
+	OriginResource OriginKind = "resource"
+)
+
+// Origin identifies the policy location a check was defined in
+type Origin struct {
+	Kind OriginKind
+	// Name is the blueprint name or the `service.resourceType` of the resource
+	Name string
+	// RuleIndex is the index of the rule within its blueprint or resource, -1 for non rule checks
+	RuleIndex int
+}
+
+// Origins tracks where each check of a processed TagPolicy was defined
+type Origins struct {
+	MandatoryKeys map[string]*Origin
+	Validations   map[string]*Origin
+	// Rules holds the origin of each rule, in the same order as TagPolicy.Rules
+	Rules []*Origin
+}
+
+// MandatoryKey returns the origin of a mandatory key, nil if unknown
+func (o *Origins) MandatoryKey(key string) *Origin {
+	if o == nil {
+		return nil
+	}
+	return o.MandatoryKeys[key]
+}
+
+// Validation returns the origin of a tag validation, nil if unknown
+func (o *Origins) Validation(key string) *Origin {
+	if o == nil {
+		return nil
+	}
+	return o.Validations[key]
+}
+
+// Rule returns the origin of the rule at the given index, nil if unknown
+func (o *Origins) Rule(index int) *Origin {
+	if o == nil || index < 0 || index >= len(o.Rules) {
+		return nil
+	}
+	return o.Rules[index]
 }
 
 // Policy represents the top-level policy configuration for resource tagging
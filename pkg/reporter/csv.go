@@ -0,0 +1,113 @@
+package reporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+var csvHeader = []string{"provider", "account", "region", "service", "type", "arn", "severity", "message", "tags", "code", "key"}
+
+// CSVReporter renders patrol results as a flat CSV export with one row per finding
+type CSVReporter struct{}
+
+// NewCSVReporter creates a new CSVReporter instance
+func NewCSVReporter() *CSVReporter {
+	return &CSVReporter{}
+}
+
+// Report writes a header followed by one row for every (resource, finding) pair. The
+// warnings of the finder are written as warning rows without a resource.
+func (r *CSVReporter) Report(w io.Writer, results []patrol.Result) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, result := range sortResults(results) {
+		for _, warning := range result.Warnings {
+			if err := writer.Write(csvWarningRecord(result, warning)); err != nil {
+				return err
+			}
+		}
+
+		for _, resource := range result.Resources {
+			tags, err := serializeTags(resource.Tags())
+			if err != nil {
+				return err
+			}
+
+			for _, f := range resourceFindings(resource) {
+				if err := writer.Write(csvRecord(resource, f, tags)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRecord(resource cr.CloudResource, f *finding, tags string) []string {
+	record := []string{
+		resource.Provider(),
+		resource.OwnerID(),
+		resource.Region(),
+		resource.Service(),
+		resource.Type(),
+		resource.ID(),
+		f.Severity,
+		f.Message,
+		tags,
+		string(f.Code),
+		f.Key,
+	}
+
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return record
+}
+
+// csvWarningRecord renders a warning of the finder, which belongs to a resource definition
+// rather than a resource
+func csvWarningRecord(result patrol.Result, warning string) []string {
+	var service, resourceType string
+	if result.Definition != nil {
+		service, resourceType = result.Definition.Service, result.Definition.ResourceType
+	}
+
+	record := []string{"", "", "", service, resourceType, "", severityWarning, warning, "", "", ""}
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return record
+}
+
+// serializeTags renders the tags as a JSON object ordered by key, so keys and values
+// containing separators stay unambiguous
+func serializeTags(tags map[string]string) (string, error) {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// escapeFormula prefixes cells that spreadsheets would evaluate as a formula with a quote
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsAny(cell[:1], "=+-@\t\r") {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,177 @@
+package reporter
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+//go:embed templates/report.html
+var htmlTemplateContent string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlTemplateContent))
+
+type htmlReport struct {
+	Title            string
+	GeneratedAt      string
+	Summary          *patrol.Summary
+	DefinitionErrors []*htmlDefinitionMessage
+	SearchWarnings   []*htmlDefinitionMessage
+	Breakdowns       []*htmlBreakdown
+	Resources        []*htmlResource
+}
+
+type htmlDefinitionMessage struct {
+	Name    string
+	Message string
+}
+
+type htmlBreakdown struct {
+	Title string
+	Rows  []*htmlBreakdownRow
+}
+
+type htmlBreakdownRow struct {
+	Name         string
+	Total        int
+	Compliant    int
+	NonCompliant int
+}
+
+// CompliantPercentage returns the percentage of compliant resources in the row
+func (r *htmlBreakdownRow) CompliantPercentage() float64 {
+	return patrol.Percentage(r.Compliant, r.Total)
+}
+
+// NonCompliantPercentage returns the percentage of non-compliant resources in the row
+func (r *htmlBreakdownRow) NonCompliantPercentage() float64 {
+	return patrol.Percentage(r.NonCompliant, r.Total)
+}
+
+type htmlResource struct {
+	Definition string
+	ID         string
+	OwnerID    string
+	Region     string
+	Errors     []string
+	Warnings   []string
+}
+
+// HTMLReporter renders patrol results as a self-contained HTML dashboard
+type HTMLReporter struct {
+	Title string
+	Now   func() time.Time
+}
+
+// NewHTMLReporter creates a new HTMLReporter instance
+func NewHTMLReporter() *HTMLReporter {
+	return &HTMLReporter{
+		Title: "TagPatrol Compliance Report",
+		Now:   time.Now,
+	}
+}
+
+// Report writes a single static HTML page with the summary, per-service and
+// per-account breakdowns and a filterable table of non-compliant resources
+func (r *HTMLReporter) Report(w io.Writer, results []patrol.Result) error {
+	report := &htmlReport{
+		Title:       r.Title,
+		GeneratedAt: r.Now().UTC().Format(time.RFC1123),
+		Summary:     patrol.Summarize(results),
+	}
+
+	services := make(map[string]*htmlBreakdownRow)
+	accounts := make(map[string]*htmlBreakdownRow)
+
+	for _, result := range sortResults(results) {
+		name := definitionName(result)
+
+		for _, warning := range result.Warnings {
+			report.SearchWarnings = append(report.SearchWarnings, &htmlDefinitionMessage{Name: name, Message: warning})
+		}
+
+		if result.Error != nil {
+			report.DefinitionErrors = append(report.DefinitionErrors, &htmlDefinitionMessage{
+				Name:    name,
+				Message: result.Error.Error(),
+			})
+			continue
+		}
+
+		service := ""
+		if result.Definition != nil {
+			service = result.Definition.Service
+		}
+
+		for _, resource := range result.Resources {
+			compliant := resource.IsCompliant()
+			countBreakdown(services, service, compliant)
+			countBreakdown(accounts, resource.OwnerID(), compliant)
+
+			if compliant {
+				continue
+			}
+
+			row := &htmlResource{
+				Definition: name,
+				ID:         resource.ID(),
+				OwnerID:    resource.OwnerID(),
+				Region:     resource.Region(),
+			}
+			for _, e := range resource.ComplianceErrors() {
+				row.Errors = append(row.Errors, e.Message)
+			}
+			for _, warn := range resource.ComplianceWarnings() {
+				row.Warnings = append(row.Warnings, warn.Message)
+			}
+			report.Resources = append(report.Resources, row)
+		}
+	}
+
+	report.Breakdowns = []*htmlBreakdown{
+		{Title: "service", Rows: sortedBreakdown(services)},
+		{Title: "account", Rows: sortedBreakdown(accounts)},
+	}
+
+	return htmlTemplate.Execute(w, report)
+}
+
+func countBreakdown(rows map[string]*htmlBreakdownRow, name string, compliant bool) {
+	if name == "" {
+		name = "unknown"
+	}
+
+	row, ok := rows[name]
+	if !ok {
+		row = &htmlBreakdownRow{Name: name}
+		rows[name] = row
+	}
+
+	row.Total++
+	if compliant {
+		row.Compliant++
+	} else {
+		row.NonCompliant++
+	}
+}
+
+// sortedBreakdown orders the rows by non-compliant count, then by name
+func sortedBreakdown(rows map[string]*htmlBreakdownRow) []*htmlBreakdownRow {
+	sorted := make([]*htmlBreakdownRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].NonCompliant != sorted[j].NonCompliant {
+			return sorted[i].NonCompliant > sorted[j].NonCompliant
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,358 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	ptypes "github.com/eliran89c/tag-patrol/pkg/policy/types"
+)
+
+// JSONSchemaVersion is the version of the JSON document layout. It is bumped
+// whenever a field is renamed or removed, new fields may be added at any time.
+const JSONSchemaVersion = "1"
+
+// JSONDocument is the top-level JSON report document
+type JSONDocument struct {
+	Version     string            `json:"version"`
+	Summary     *JSONSummary      `json:"summary"`
+	Definitions []*JSONDefinition `json:"definitions"`
+	Baseline    *JSONBaseline     `json:"baseline,omitempty"`
+}
+
+// JSONSummary holds the aggregated totals of a patrol run
+type JSONSummary struct {
+	Definitions             int     `json:"definitions"`
+	Resources               int     `json:"resources"`
+	Compliant               int     `json:"compliant"`
+	NonCompliant            int     `json:"nonCompliant"`
+	DefinitionsWithErrors   int     `json:"definitionsWithErrors"`
+	CompliantPercentage     float64 `json:"compliantPercentage"`
+	NonCompliantPercentage  float64 `json:"nonCompliantPercentage"`
+	DefinitionsWithWarnings int     `json:"definitionsWithWarnings,omitempty"`
+	Retries                 int     `json:"retries,omitempty"`
+}
+
+// JSONDefinition holds the outcome of validating a single resource definition
+type JSONDefinition struct {
+	Service      string          `json:"service"`
+	ResourceType string          `json:"resourceType"`
+	Compliant    int             `json:"compliant"`
+	NonCompliant int             `json:"nonCompliant"`
+	Error        string          `json:"error,omitempty"`
+	Retries      int             `json:"retries,omitempty"`
+	Warnings     []string        `json:"warnings,omitempty"`
+	Resources    []*JSONResource `json:"resources"`
+}
+
+// JSONResource holds a single resource with its tags and compliance findings
+type JSONResource struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Service   string            `json:"service"`
+	Provider  string            `json:"provider"`
+	Region    string            `json:"region"`
+	OwnerID   string            `json:"ownerId"`
+	Tags      map[string]string `json:"tags"`
+	Compliant bool              `json:"compliant"`
+	Errors    []*JSONFinding    `json:"errors"`
+	Warnings  []*JSONFinding    `json:"warnings"`
+}
+
+// JSONFinding holds a single compliance error or warning
+type JSONFinding struct {
+	Code     string      `json:"code,omitempty"`
+	Message  string      `json:"message"`
+	Key      string      `json:"key,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Expected string      `json:"expected,omitempty"`
+	Origin   *JSONOrigin `json:"origin,omitempty"`
+}
+
+// JSONBaseline holds the comparison of the results against a baseline
+type JSONBaseline struct {
+	New       []*JSONDiffEntry `json:"new"`
+	Fixed     []*JSONDiffEntry `json:"fixed"`
+	Unchanged []*JSONDiffEntry `json:"unchanged"`
+}
+
+// JSONDiffEntry holds a resource along with its new, fixed or unchanged compliance errors
+type JSONDiffEntry struct {
+	Definition string         `json:"definition"`
+	ID         string         `json:"id"`
+	Region     string         `json:"region"`
+	OwnerID    string         `json:"ownerId"`
+	Errors     []*JSONFinding `json:"errors"`
+}
+
+// JSONOrigin holds the policy location a check was defined in
+type JSONOrigin struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	RuleIndex *int   `json:"ruleIndex,omitempty"`
+}
+
+// JSONReporter renders patrol results as a versioned JSON document
+type JSONReporter struct {
+	Indent string
+}
+
+// NewJSONReporter creates a new JSONReporter instance
+func NewJSONReporter() *JSONReporter {
+	return &JSONReporter{Indent: "  "}
+}
+
+// Report writes the JSON document for the given results
+func (r *JSONReporter) Report(w io.Writer, results []patrol.Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", r.Indent)
+
+	return encoder.Encode(NewJSONDocument(results))
+}
+
+// ReportDiff writes the JSON document for the results narrowed down by the baseline
+// comparison, along with the new, fixed and unchanged resources
+func (r *JSONReporter) ReportDiff(w io.Writer, diff *patrol.Diff) error {
+	doc := NewJSONDocument(diff.Results())
+	doc.Baseline = &JSONBaseline{
+		New:       newJSONDiffEntries(diff.New),
+		Fixed:     newJSONDiffEntries(diff.Fixed),
+		Unchanged: newJSONDiffEntries(diff.Unchanged),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", r.Indent)
+
+	return encoder.Encode(doc)
+}
+
+// NewJSONDocument builds the JSON report document for the given results
+func NewJSONDocument(results []patrol.Result) *JSONDocument {
+	summary := patrol.Summarize(results)
+
+	doc := &JSONDocument{
+		Version: JSONSchemaVersion,
+		Summary: &JSONSummary{
+			Definitions:             summary.Definitions,
+			Resources:               summary.Resources,
+			Compliant:               summary.Compliant,
+			NonCompliant:            summary.NonCompliant,
+			DefinitionsWithErrors:   summary.DefinitionsWithErrors,
+			CompliantPercentage:     summary.CompliantPercentage(),
+			NonCompliantPercentage:  summary.NonCompliantPercentage(),
+			DefinitionsWithWarnings: summary.DefinitionsWithWarnings,
+			Retries:                 summary.Retries,
+		},
+		Definitions: make([]*JSONDefinition, 0, len(results)),
+	}
+
+	for _, result := range sortResults(results) {
+		definition := &JSONDefinition{
+			Compliant:    result.CompliantCount,
+			NonCompliant: result.NonCompliantCount,
+			Retries:      result.Retries,
+			Warnings:     result.Warnings,
+			Resources:    make([]*JSONResource, 0, len(result.Resources)),
+		}
+
+		if result.Definition != nil {
+			definition.Service = result.Definition.Service
+			definition.ResourceType = result.Definition.ResourceType
+		}
+
+		if result.Error != nil {
+			definition.Error = result.Error.Error()
+		}
+
+		for _, resource := range result.Resources {
+			definition.Resources = append(definition.Resources, newJSONResource(resource))
+		}
+
+		sort.SliceStable(definition.Resources, func(i, j int) bool {
+			return definition.Resources[i].ID < definition.Resources[j].ID
+		})
+
+		doc.Definitions = append(doc.Definitions, definition)
+	}
+
+	return doc
+}
+
+func newJSONResource(resource cr.CloudResource) *JSONResource {
+	jr := &JSONResource{
+		ID:        resource.ID(),
+		Type:      resource.Type(),
+		Service:   resource.Service(),
+		Provider:  resource.Provider(),
+		Region:    resource.Region(),
+		OwnerID:   resource.OwnerID(),
+		Tags:      make(map[string]string, len(resource.Tags())),
+		Compliant: resource.IsCompliant(),
+		Errors:    make([]*JSONFinding, 0, len(resource.ComplianceErrors())),
+		Warnings:  make([]*JSONFinding, 0, len(resource.ComplianceWarnings())),
+	}
+
+	maps.Copy(jr.Tags, resource.Tags())
+
+	for _, f := range resourceFindings(resource) {
+		jf := newJSONFinding(f)
+		if f.Severity == severityWarning {
+			jr.Warnings = append(jr.Warnings, jf)
+		} else {
+			jr.Errors = append(jr.Errors, jf)
+		}
+	}
+
+	return jr
+}
+
+func newJSONDiffEntries(entries []*patrol.DiffEntry) []*JSONDiffEntry {
+	out := make([]*JSONDiffEntry, 0, len(entries))
+	for _, entry := range entries {
+		je := &JSONDiffEntry{
+			Definition: entry.Definition,
+			ID:         entry.Resource.ID(),
+			Region:     entry.Resource.Region(),
+			OwnerID:    entry.Resource.OwnerID(),
+			Errors:     make([]*JSONFinding, 0, len(entry.Errors)),
+		}
+		for _, f := range errorFindings(entry.Errors) {
+			je.Errors = append(je.Errors, newJSONFinding(f))
+		}
+		out = append(out, je)
+	}
+	return out
+}
+
+func newJSONFinding(f *finding) *JSONFinding {
+	return &JSONFinding{
+		Code:     string(f.Code),
+		Message:  f.Message,
+		Key:      f.Key,
+		Value:    f.Value,
+		Expected: f.Expected,
+		Origin:   newJSONOrigin(f.Origin),
+	}
+}
+
+func newJSONOrigin(origin *ptypes.Origin) *JSONOrigin {
+	if origin == nil {
+		return nil
+	}
+
+	jo := &JSONOrigin{
+		Kind: string(origin.Kind),
+		Name: origin.Name,
+	}
+
+	if origin.RuleIndex >= 0 {
+		index := origin.RuleIndex
+		jo.RuleIndex = &index
+	}
+
+	return jo
+}
+
+// ReadJSONDocument decodes a JSON document previously written by the JSONReporter
+func ReadJSONDocument(r io.Reader) (*JSONDocument, error) {
+	doc := &JSONDocument{}
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return nil, fmt.Errorf("error decoding results: %w", err)
+	}
+
+	if doc.Version != JSONSchemaVersion {
+		return nil, fmt.Errorf("unsupported results version `%s`, expected `%s`", doc.Version, JSONSchemaVersion)
+	}
+
+	return doc, nil
+}
+
+// Results rebuilds the patrol results stored in the document
+func (d *JSONDocument) Results() []patrol.Result {
+	results := make([]patrol.Result, 0, len(d.Definitions))
+
+	for _, definition := range d.Definitions {
+		result := patrol.Result{
+			Definition: &ptypes.ResourceDefinition{
+				Service:      definition.Service,
+				ResourceType: definition.ResourceType,
+			},
+			CompliantCount:    definition.Compliant,
+			NonCompliantCount: definition.NonCompliant,
+			Retries:           definition.Retries,
+			Warnings:          definition.Warnings,
+			Resources:         make([]cr.CloudResource, 0, len(definition.Resources)),
+		}
+
+		if definition.Error != "" {
+			result.Error = errors.New(definition.Error)
+		}
+
+		for _, resource := range definition.Resources {
+			result.Resources = append(result.Resources, resource.resource())
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func (r *JSONResource) resource() *cr.GenericResource {
+	resource := &cr.GenericResource{
+		ResourceID:     r.ID,
+		ResourceType:   r.Type,
+		ServiceName:    r.Service,
+		ProviderName:   r.Provider,
+		ResourceRegion: r.Region,
+		Owner:          r.OwnerID,
+		ResourceTags:   make(map[string]string, len(r.Tags)),
+	}
+
+	maps.Copy(resource.ResourceTags, r.Tags)
+
+	for _, f := range r.Errors {
+		resource.AddComplianceError(f.complianceError())
+	}
+
+	for _, f := range r.Warnings {
+		warning := cr.ComplianceWarning(*f.complianceError())
+		resource.AddComplianceWarning(&warning)
+	}
+
+	return resource
+}
+
+func (f *JSONFinding) complianceError() *cr.ComplianceError {
+	return &cr.ComplianceError{
+		Code:     cr.Code(f.Code),
+		Message:  f.Message,
+		Key:      f.Key,
+		Value:    f.Value,
+		Expected: f.Expected,
+		Origin:   f.Origin.origin(),
+	}
+}
+
+func (o *JSONOrigin) origin() *ptypes.Origin {
+	if o == nil {
+		return nil
+	}
+
+	origin := &ptypes.Origin{
+		Kind:      ptypes.OriginKind(o.Kind),
+		Name:      o.Name,
+		RuleIndex: -1,
+	}
+
+	if o.RuleIndex != nil {
+		origin.RuleIndex = *o.RuleIndex
+	}
+
+	return origin
+}
@@ -0,0 +1,141 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+	SystemErr string           `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnitReporter renders patrol results as a JUnit XML report
+type JUnitReporter struct{}
+
+// NewJUnitReporter creates a new JUnitReporter instance
+func NewJUnitReporter() *JUnitReporter {
+	return &JUnitReporter{}
+}
+
+// Report writes a JUnit XML report where every resource definition is a test
+// suite and every resource is a test case that fails on compliance errors. The
+// warnings of the finder are written to the system-err of the suite.
+func (r *JUnitReporter) Report(w io.Writer, results []patrol.Result) error {
+	suites := &junitTestSuites{Name: "tagpatrol"}
+
+	for _, result := range sortResults(results) {
+		name := definitionName(result)
+		suite := &junitTestSuite{Name: name, SystemErr: strings.Join(result.Warnings, "\n")}
+
+		if result.Error != nil {
+			suite.Tests = 1
+			suite.Errors = 1
+			suite.TestCases = append(suite.TestCases, &junitTestCase{
+				Name:      name,
+				ClassName: name,
+				Error: &junitProblem{
+					Message: "error finding resources",
+					Type:    "DefinitionError",
+					Body:    result.Error.Error(),
+				},
+			})
+		}
+
+		resources := make([]cr.CloudResource, len(result.Resources))
+		copy(resources, result.Resources)
+		sort.SliceStable(resources, func(i, j int) bool {
+			return resources[i].ID() < resources[j].ID()
+		})
+
+		for _, resource := range resources {
+			suite.Tests++
+			suite.TestCases = append(suite.TestCases, newJUnitTestCase(name, resource))
+
+			if !resource.IsCompliant() {
+				suite.Failures++
+			}
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newJUnitTestCase(className string, resource cr.CloudResource) *junitTestCase {
+	testCase := &junitTestCase{
+		Name:      resource.ID(),
+		ClassName: className,
+	}
+
+	if errs := resource.ComplianceErrors(); len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Message)
+		}
+
+		testCase.Failure = &junitProblem{
+			Message: fmt.Sprintf("%d compliance error(s)", len(errs)),
+			Type:    "ComplianceError",
+			Body:    strings.Join(messages, "\n"),
+		}
+	}
+
+	if warnings := resource.ComplianceWarnings(); len(warnings) > 0 {
+		messages := make([]string, 0, len(warnings))
+		for _, warn := range warnings {
+			messages = append(messages, "Warning: "+warn.Message)
+		}
+		testCase.SystemOut = strings.Join(messages, "\n")
+	}
+
+	return testCase
+}
@@ -0,0 +1,185 @@
+package reporter
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// markdownCode renders a table cell as inline code, escaping pipes and line breaks
+func markdownCode(value string) string {
+	value = markdownEscaper.Replace(value)
+	if strings.Contains(value, "`") {
+		return "`` " + value + " ``"
+	}
+	return "`" + value + "`"
+}
+
+// MarkdownReporter renders patrol results as Markdown for pull request comments and wikis
+type MarkdownReporter struct {
+	MaxRows int
+}
+
+// NewMarkdownReporter creates a new MarkdownReporter instance. MaxRows caps the
+// total number of non-compliant resource rows rendered, 0 means no limit.
+func NewMarkdownReporter(maxRows int) *MarkdownReporter {
+	return &MarkdownReporter{MaxRows: maxRows}
+}
+
+// Report writes a summary table followed by a collapsible section for every
+// resource definition with non-compliant resources
+func (r *MarkdownReporter) Report(w io.Writer, results []patrol.Result) error {
+	var sb strings.Builder
+	r.writeResults(&sb, results)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ReportDiff writes the report of the results narrowed down by the baseline comparison,
+// followed by a baseline section listing the new, fixed and unchanged resources
+func (r *MarkdownReporter) ReportDiff(w io.Writer, diff *patrol.Diff) error {
+	var sb strings.Builder
+	r.writeResults(&sb, diff.Results())
+
+	sb.WriteString("\n## Baseline Comparison\n\n")
+	sb.WriteString("| Change | Resources |\n")
+	sb.WriteString("|--------|-----------|\n")
+	fmt.Fprintf(&sb, "| New violations | %d |\n", len(diff.New))
+	fmt.Fprintf(&sb, "| Fixed | %d |\n", len(diff.Fixed))
+	fmt.Fprintf(&sb, "| Unchanged | %d |\n", len(diff.Unchanged))
+
+	r.writeDiffEntries(&sb, "New violations", diff.New)
+	r.writeDiffEntries(&sb, "Fixed", diff.Fixed)
+	r.writeDiffEntries(&sb, "Unchanged", diff.Unchanged)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeDiffEntries writes a collapsible table of baseline comparison entries, capped at MaxRows
+func (r *MarkdownReporter) writeDiffEntries(sb *strings.Builder, title string, entries []*patrol.DiffEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n<details>\n<summary><strong>%s</strong>: %d resources</summary>\n\n", title, len(entries))
+	sb.WriteString("| Definition | Resource | Account | Region | Errors |\n")
+	sb.WriteString("|------------|----------|---------|--------|--------|\n")
+
+	for i, entry := range entries {
+		if r.MaxRows > 0 && i >= r.MaxRows {
+			fmt.Fprintf(sb, "\n_%d more resource(s) not shown._\n", len(entries)-i)
+			break
+		}
+
+		errs := make([]string, 0, len(entry.Errors))
+		for _, e := range entry.Errors {
+			errs = append(errs, markdownEscaper.Replace(e.Message))
+		}
+
+		fmt.Fprintf(sb, "| %s | %s | %s | %s | %s |\n",
+			markdownCode(entry.Definition),
+			markdownCode(entry.Resource.ID()),
+			markdownEscaper.Replace(entry.Resource.OwnerID()),
+			markdownEscaper.Replace(entry.Resource.Region()),
+			strings.Join(errs, "<br>"))
+	}
+
+	sb.WriteString("\n</details>\n")
+}
+
+// writeResults writes the summary and non-compliant resource tables of the results
+func (r *MarkdownReporter) writeResults(sb *strings.Builder, results []patrol.Result) {
+	summary := patrol.Summarize(results)
+
+	sb.WriteString("## TagPatrol Summary\n\n")
+	sb.WriteString("| Metric | Value |\n")
+	sb.WriteString("|--------|-------|\n")
+	fmt.Fprintf(sb, "| Resource definitions | %d |\n", summary.Definitions)
+	fmt.Fprintf(sb, "| Resources | %d |\n", summary.Resources)
+	fmt.Fprintf(sb, "| Compliant | %d (%.1f%%) |\n", summary.Compliant, summary.CompliantPercentage())
+	fmt.Fprintf(sb, "| Non-compliant | %d (%.1f%%) |\n", summary.NonCompliant, summary.NonCompliantPercentage())
+	fmt.Fprintf(sb, "| Definition errors | %d |\n", summary.DefinitionsWithErrors)
+	if summary.DefinitionsWithWarnings > 0 {
+		fmt.Fprintf(sb, "| Definition warnings | %d |\n", summary.DefinitionsWithWarnings)
+	}
+
+	sorted := sortResults(results)
+
+	if len(sorted) > 0 {
+		sb.WriteString("\n| Definition | Compliant | Non-compliant | Status |\n")
+		sb.WriteString("|------------|-----------|---------------|--------|\n")
+		for _, result := range sorted {
+			status := ":white_check_mark:"
+			switch {
+			case result.Error != nil:
+				status = ":warning: " + markdownEscaper.Replace(result.Error.Error())
+			case result.NonCompliantCount > 0:
+				status = ":x:"
+			}
+			fmt.Fprintf(sb, "| %s | %d | %d | %s |\n", markdownCode(definitionName(result)), result.CompliantCount, result.NonCompliantCount, status)
+		}
+	}
+
+	if summary.DefinitionsWithWarnings > 0 {
+		sb.WriteString("\n### Warnings\n\n")
+		for _, result := range sorted {
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(sb, "- %s: %s\n", markdownCode(definitionName(result)), markdownEscaper.Replace(warning))
+			}
+		}
+	}
+
+	rows := 0
+	for _, result := range sorted {
+		if result.Error != nil || result.NonCompliantCount == 0 {
+			continue
+		}
+
+		fmt.Fprintf(sb, "\n<details>\n<summary><strong>%s</strong>: %d non-compliant</summary>\n\n", html.EscapeString(definitionName(result)), result.NonCompliantCount)
+		sb.WriteString("| Resource | Account | Region | Errors | Warnings |\n")
+		sb.WriteString("|----------|---------|--------|--------|----------|\n")
+
+		hidden := 0
+		for _, resource := range result.Resources {
+			if resource.IsCompliant() {
+				continue
+			}
+
+			if r.MaxRows > 0 && rows >= r.MaxRows {
+				hidden++
+				continue
+			}
+			rows++
+
+			errs := make([]string, 0, len(resource.ComplianceErrors()))
+			for _, e := range resource.ComplianceErrors() {
+				errs = append(errs, markdownEscaper.Replace(e.Message))
+			}
+
+			warnings := make([]string, 0, len(resource.ComplianceWarnings()))
+			for _, warn := range resource.ComplianceWarnings() {
+				warnings = append(warnings, markdownEscaper.Replace(warn.Message))
+			}
+
+			fmt.Fprintf(sb, "| %s | %s | %s | %s | %s |\n",
+				markdownCode(resource.ID()),
+				markdownEscaper.Replace(resource.OwnerID()),
+				markdownEscaper.Replace(resource.Region()),
+				strings.Join(errs, "<br>"),
+				strings.Join(warnings, "<br>"))
+		}
+
+		if hidden > 0 {
+			fmt.Fprintf(sb, "\n_%d more non-compliant resource(s) not shown._\n", hidden)
+		}
+
+		sb.WriteString("\n</details>\n")
+	}
+}
@@ -0,0 +1,158 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+var (
+	missingTagCodes    = []cr.Code{cr.CodeMissingMandatoryTag, cr.CodeRuleMissingRequiredTag, cr.CodeRuleMissingRecommendedTag}
+	labelValueEscaper  = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	resourceLabelNames = []string{"provider", "service", "resource_type", "account", "region"}
+)
+
+// metricFamily holds the samples of a single gauge
+type metricFamily struct {
+	name       string
+	help       string
+	labelNames []string
+	samples    map[string]float64
+	labels     map[string][]string
+}
+
+func newMetricFamily(name, help string, labelNames ...string) *metricFamily {
+	return &metricFamily{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		samples:    make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+}
+
+func (m *metricFamily) add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+	m.samples[key] += value
+	m.labels[key] = labelValues
+}
+
+func (m *metricFamily) set(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+	m.samples[key] = value
+	m.labels[key] = labelValues
+}
+
+func (m *metricFamily) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(sb, "# TYPE %s gauge\n", m.name)
+
+	keys := make([]string, 0, len(m.samples))
+	for key := range m.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sb.WriteString(m.name)
+
+		if len(m.labelNames) > 0 {
+			pairs := make([]string, 0, len(m.labelNames))
+			for i, name := range m.labelNames {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(m.labels[key][i])))
+			}
+			fmt.Fprintf(sb, "{%s}", strings.Join(pairs, ","))
+		}
+
+		fmt.Fprintf(sb, " %g\n", m.samples[key])
+	}
+}
+
+// PrometheusReporter renders patrol results as Prometheus text exposition format
+// gauges, suitable for the node_exporter textfile collector
+type PrometheusReporter struct {
+	Now func() time.Time
+}
+
+// NewPrometheusReporter creates a new PrometheusReporter instance
+func NewPrometheusReporter() *PrometheusReporter {
+	return &PrometheusReporter{Now: time.Now}
+}
+
+// Report writes the compliance gauges for the given results
+func (r *PrometheusReporter) Report(w io.Writer, results []patrol.Result) error {
+	summary := patrol.Summarize(results)
+
+	var (
+		total         = newMetricFamily("tagpatrol_resources", "Number of resources found.", resourceLabelNames...)
+		compliant     = newMetricFamily("tagpatrol_resources_compliant", "Number of compliant resources.", resourceLabelNames...)
+		nonCompliant  = newMetricFamily("tagpatrol_resources_non_compliant", "Number of non-compliant resources.", resourceLabelNames...)
+		missingTags   = newMetricFamily("tagpatrol_missing_tag_resources", "Number of resources missing a tag, by severity.", "service", "resource_type", "key", "severity")
+		findings      = newMetricFamily("tagpatrol_findings", "Number of compliance findings, by severity.", "service", "resource_type", "severity")
+		definitionErr = newMetricFamily("tagpatrol_definition_error", "Whether resources of a definition could not be retrieved (1) or not (0).", "service", "resource_type")
+		warnings      = newMetricFamily("tagpatrol_definition_warnings", "Number of warnings reported while finding the resources of a definition.", "service", "resource_type")
+		definitions   = newMetricFamily("tagpatrol_definitions", "Number of resource definitions processed.")
+		ratio         = newMetricFamily("tagpatrol_compliance_ratio", "Ratio of compliant resources across all definitions.")
+		lastRun       = newMetricFamily("tagpatrol_last_run_timestamp_seconds", "Unix timestamp of the run that produced these metrics.")
+	)
+
+	for _, result := range sortResults(results) {
+		var service, resourceType string
+		if result.Definition != nil {
+			service, resourceType = result.Definition.Service, result.Definition.ResourceType
+		}
+
+		warnings.set(float64(len(result.Warnings)), service, resourceType)
+
+		if result.Error != nil {
+			definitionErr.set(1, service, resourceType)
+			continue
+		}
+		definitionErr.set(0, service, resourceType)
+
+		findings.add(0, service, resourceType, severityError)
+		findings.add(0, service, resourceType, severityWarning)
+
+		for _, resource := range result.Resources {
+			labels := []string{resource.Provider(), service, resourceType, resource.OwnerID(), resource.Region()}
+
+			total.add(1, labels...)
+			if resource.IsCompliant() {
+				compliant.add(1, labels...)
+				nonCompliant.add(0, labels...)
+			} else {
+				compliant.add(0, labels...)
+				nonCompliant.add(1, labels...)
+			}
+
+			// a tag missing for several reasons, e.g. mandatory and required by a rule, is
+			// counted once per resource
+			missing := make(map[[2]string]bool)
+			for _, f := range resourceFindings(resource) {
+				findings.add(1, service, resourceType, f.Severity)
+				if slices.Contains(missingTagCodes, f.Code) && !missing[[2]string{f.Key, f.Severity}] {
+					missing[[2]string{f.Key, f.Severity}] = true
+					missingTags.add(1, service, resourceType, f.Key, f.Severity)
+				}
+			}
+		}
+	}
+
+	definitions.set(float64(summary.Definitions))
+	ratio.set(summary.CompliantPercentage() / 100)
+	lastRun.set(float64(r.Now().Unix()))
+
+	var sb strings.Builder
+	for _, family := range []*metricFamily{definitions, definitionErr, warnings, total, compliant, nonCompliant, findings, missingTags, ratio, lastRun} {
+		family.write(&sb)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,149 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+// Format represents a supported report output format
+type Format string
+
+const (
+	// FormatText renders a human readable plain text report
+	FormatText Format = "text"
+	// FormatJSON renders a versioned, machine readable JSON document
+	FormatJSON Format = "json"
+	// FormatSARIF renders a SARIF 2.1.0 log for code scanning dashboards
+	FormatSARIF Format = "sarif"
+	// FormatJUnit renders a JUnit XML report for CI test dashboards
+	FormatJUnit Format = "junit"
+	// FormatCSV renders a flat CSV export with one row per finding
+	FormatCSV Format = "csv"
+	// FormatHTML renders a self-contained HTML compliance dashboard
+	FormatHTML Format = "html"
+	// FormatMarkdown renders a Markdown report for pull request comments and wikis
+	FormatMarkdown Format = "markdown"
+	// FormatPrometheus renders compliance gauges in the Prometheus text exposition format
+	FormatPrometheus Format = "prometheus"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// Reporter defines the interface for rendering patrol results
+type Reporter interface {
+	Report(w io.Writer, results []patrol.Result) error
+}
+
+// DiffReporter is implemented by reporters that render the comparison against a
+// baseline along with the current results
+type DiffReporter interface {
+	ReportDiff(w io.Writer, diff *patrol.Diff) error
+}
+
+// Options configures the behavior of the reporters
+type Options struct {
+	// MaxRows caps the number of resource rows rendered by size constrained
+	// formats such as markdown, 0 means no limit
+	MaxRows int
+}
+
+// DefaultOptions returns the default reporter options
+func DefaultOptions() *Options {
+	return &Options{
+		MaxRows: 0,
+	}
+}
+
+// Formats returns all the supported output formats
+func Formats() []Format {
+	return []Format{FormatText, FormatJSON, FormatSARIF, FormatJUnit, FormatCSV, FormatHTML, FormatMarkdown, FormatPrometheus}
+}
+
+// New creates a Reporter for the specified output format and options
+func New(format Format, options *Options) (Reporter, error) {
+	if options == nil {
+		options = DefaultOptions()
+	}
+
+	switch format {
+	case FormatText:
+		return NewTextReporter(), nil
+	case FormatJSON:
+		return NewJSONReporter(), nil
+	case FormatSARIF:
+		return NewSARIFReporter(), nil
+	case FormatJUnit:
+		return NewJUnitReporter(), nil
+	case FormatCSV:
+		return NewCSVReporter(), nil
+	case FormatHTML:
+		return NewHTMLReporter(), nil
+	case FormatMarkdown:
+		return NewMarkdownReporter(options.MaxRows), nil
+	case FormatPrometheus:
+		return NewPrometheusReporter(), nil
+	}
+
+	names := make([]string, 0, len(Formats()))
+	for _, f := range Formats() {
+		names = append(names, string(f))
+	}
+	return nil, fmt.Errorf("unsupported output format `%s`, must be one of: %s", format, strings.Join(names, ", "))
+}
+
+// sortResults returns a copy of the results ordered by service and resource type
+func sortResults(results []patrol.Result) []patrol.Result {
+	sorted := make([]patrol.Result, len(results))
+	copy(sorted, results)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return definitionName(sorted[i]) < definitionName(sorted[j])
+	})
+
+	return sorted
+}
+
+func definitionName(result patrol.Result) string {
+	if result.Definition == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s", result.Definition.Service, result.Definition.ResourceType)
+}
+
+// finding is a compliance error or warning along with its severity
+type finding struct {
+	cr.ComplianceError
+	Severity string
+}
+
+// resourceFindings returns the compliance errors followed by the compliance warnings of a resource
+func resourceFindings(resource cr.CloudResource) []*finding {
+	findings := make([]*finding, 0, len(resource.ComplianceErrors())+len(resource.ComplianceWarnings()))
+
+	for _, e := range resource.ComplianceErrors() {
+		findings = append(findings, &finding{ComplianceError: *e, Severity: severityError})
+	}
+
+	for _, w := range resource.ComplianceWarnings() {
+		findings = append(findings, &finding{ComplianceError: cr.ComplianceError(*w), Severity: severityWarning})
+	}
+
+	return findings
+}
+
+// errorFindings returns the given compliance errors as findings
+func errorFindings(errs []*cr.ComplianceError) []*finding {
+	findings := make([]*finding, 0, len(errs))
+	for _, e := range errs {
+		findings = append(findings, &finding{ComplianceError: *e, Severity: severityError})
+	}
+	return findings
+}
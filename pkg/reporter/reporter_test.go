@@ -0,0 +1,667 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	"github.com/eliran89c/tag-patrol/pkg/policy/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockResource struct {
+	id           string
+	resourceType string
+	service      string
+	provider     string
+	region       string
+	ownerID      string
+	tags         map[string]string
+	errors       []*cr.ComplianceError
+	warnings     []*cr.ComplianceWarning
+}
+
+func NewMockResource(id, resourceType, service, provider, region, ownerID string, tags map[string]string) *MockResource {
+	return &MockResource{
+		id:           id,
+		resourceType: resourceType,
+		service:      service,
+		provider:     provider,
+		region:       region,
+		ownerID:      ownerID,
+		tags:         tags,
+		errors:       make([]*cr.ComplianceError, 0),
+		warnings:     make([]*cr.ComplianceWarning, 0),
+	}
+}
+
+func (m *MockResource) ID() string {
+	return m.id
+}
+
+func (m *MockResource) Type() string {
+	return m.resourceType
+}
+
+func (m *MockResource) Service() string {
+	return m.service
+}
+
+func (m *MockResource) Provider() string {
+	return m.provider
+}
+
+func (m *MockResource) Region() string {
+	return m.region
+}
+
+func (m *MockResource) OwnerID() string {
+	return m.ownerID
+}
+
+func (m *MockResource) Tags() map[string]string {
+	return m.tags
+}
+
+func (m *MockResource) IsCompliant() bool {
+	return len(m.errors) == 0
+}
+
+func (m *MockResource) AddComplianceError(err *cr.ComplianceError) {
+	m.errors = append(m.errors, err)
+}
+
+func (m *MockResource) AddComplianceWarning(warning *cr.ComplianceWarning) {
+	m.warnings = append(m.warnings, warning)
+}
+
+func (m *MockResource) ComplianceErrors() []*cr.ComplianceError {
+	return m.errors
+}
+
+func (m *MockResource) ComplianceWarnings() []*cr.ComplianceWarning {
+	return m.warnings
+}
+
+func testResults() []patrol.Result {
+	compliant := NewMockResource("arn:aws:s3:::compliant-bucket", "AWS::S3::Bucket", "s3", "aws", "us-east-1", "123456789012",
+		map[string]string{"env": "prod", "owner": "team-a"})
+	compliant.AddComplianceWarning(&cr.ComplianceWarning{
+		Code:    cr.CodeRuleMissingRecommendedTag,
+		Message: "Missing recommended tag `cost-center` based on rule condition",
+		Key:     "cost-center",
+		Origin:  &types.Origin{Kind: types.OriginBlueprint, Name: "production", RuleIndex: 0},
+	})
+
+	nonCompliant := NewMockResource("arn:aws:ec2:us-west-2:123456789012:instance/i-1", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012",
+		map[string]string{"env": "dev"})
+	nonCompliant.AddComplianceError(&cr.ComplianceError{
+		Code:     cr.CodeMissingMandatoryTag,
+		Message:  "Missing mandatory tag: `owner`",
+		Key:      "owner",
+		Expected: "tag must exist",
+		Origin:   &types.Origin{Kind: types.OriginBlueprint, Name: "base", RuleIndex: -1},
+	})
+
+	return []patrol.Result{
+		{
+			Definition:     &types.ResourceDefinition{Service: "s3", ResourceType: "bucket"},
+			Resources:      []cr.CloudResource{compliant},
+			CompliantCount: 1,
+		},
+		{
+			Definition: &types.ResourceDefinition{Service: "rds", ResourceType: "db"},
+			Error:      errors.New("access denied"),
+		},
+		{
+			Definition:        &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+			Resources:         []cr.CloudResource{nonCompliant},
+			NonCompliantCount: 1,
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range Formats() {
+		t.Run(string(format), func(t *testing.T) {
+			rep, err := New(format, nil)
+			require.NoError(t, err)
+			assert.NotNil(t, rep)
+		})
+	}
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		rep, err := New("yaml", nil)
+		assert.Error(t, err)
+		assert.Nil(t, rep)
+		assert.Contains(t, err.Error(), "unsupported output format `yaml`")
+	})
+}
+
+func TestTextReporter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewTextReporter().Report(&buf, testResults()))
+
+	output := buf.String()
+	assert.Contains(t, output, "Processed 3 resource definitions")
+	assert.Contains(t, output, "Error processing rds.db: access denied")
+	assert.Contains(t, output, "Resource: ec2.instance - Compliant: 0, Non-compliant: 1")
+	assert.Contains(t, output, "  Non-compliant resource: arn:aws:ec2:us-west-2:123456789012:instance/i-1")
+	assert.Contains(t, output, "    Error: Missing mandatory tag: `owner`")
+	assert.NotContains(t, output, "compliant-bucket")
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&buf, testResults()))
+
+	var doc JSONDocument
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, JSONSchemaVersion, doc.Version)
+	assert.Equal(t, 3, doc.Summary.Definitions)
+	assert.Equal(t, 2, doc.Summary.Resources)
+	assert.Equal(t, 1, doc.Summary.Compliant)
+	assert.Equal(t, 1, doc.Summary.NonCompliant)
+	assert.Equal(t, 1, doc.Summary.DefinitionsWithErrors)
+	assert.Equal(t, 50.0, doc.Summary.CompliantPercentage)
+
+	require.Len(t, doc.Definitions, 3)
+	assert.Equal(t, "ec2", doc.Definitions[0].Service)
+	assert.Equal(t, "rds", doc.Definitions[1].Service)
+	assert.Equal(t, "s3", doc.Definitions[2].Service)
+
+	assert.Equal(t, "access denied", doc.Definitions[1].Error)
+	assert.Empty(t, doc.Definitions[1].Resources)
+
+	instance := doc.Definitions[0].Resources[0]
+	assert.Equal(t, "arn:aws:ec2:us-west-2:123456789012:instance/i-1", instance.ID)
+	assert.Equal(t, "us-west-2", instance.Region)
+	assert.Equal(t, "123456789012", instance.OwnerID)
+	assert.Equal(t, map[string]string{"env": "dev"}, instance.Tags)
+	assert.False(t, instance.Compliant)
+	require.Len(t, instance.Errors, 1)
+	assert.Equal(t, "Missing mandatory tag: `owner`", instance.Errors[0].Message)
+	assert.Equal(t, "missing_mandatory_tag", instance.Errors[0].Code)
+	assert.Equal(t, "owner", instance.Errors[0].Key)
+	assert.Equal(t, "tag must exist", instance.Errors[0].Expected)
+	assert.Equal(t, &JSONOrigin{Kind: "blueprint", Name: "base"}, instance.Errors[0].Origin)
+	assert.Empty(t, instance.Warnings)
+
+	bucket := doc.Definitions[2].Resources[0]
+	assert.True(t, bucket.Compliant)
+	require.Len(t, bucket.Warnings, 1)
+	require.NotNil(t, bucket.Warnings[0].Origin.RuleIndex)
+	assert.Equal(t, 0, *bucket.Warnings[0].Origin.RuleIndex)
+}
+
+func TestJSONReporterEmptyCollections(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&buf, nil))
+
+	assert.Contains(t, buf.String(), `"definitions": []`)
+}
+
+func TestReadJSONDocument(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&buf, testResults()))
+
+	doc, err := ReadJSONDocument(&buf)
+	require.NoError(t, err)
+
+	results := doc.Results()
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "ec2", results[0].Definition.Service)
+	assert.Equal(t, "instance", results[0].Definition.ResourceType)
+	assert.Equal(t, 1, results[0].NonCompliantCount)
+	require.Len(t, results[0].Resources, 1)
+
+	instance := results[0].Resources[0]
+	assert.Equal(t, "arn:aws:ec2:us-west-2:123456789012:instance/i-1", instance.ID())
+	assert.Equal(t, "aws", instance.Provider())
+	assert.Equal(t, map[string]string{"env": "dev"}, instance.Tags())
+	assert.False(t, instance.IsCompliant())
+	require.Len(t, instance.ComplianceErrors(), 1)
+	assert.Equal(t, cr.CodeMissingMandatoryTag, instance.ComplianceErrors()[0].Code)
+	assert.Equal(t, &types.Origin{Kind: types.OriginBlueprint, Name: "base", RuleIndex: -1}, instance.ComplianceErrors()[0].Origin)
+
+	require.Error(t, results[1].Error)
+	assert.Equal(t, "access denied", results[1].Error.Error())
+
+	bucket := results[2].Resources[0]
+	assert.True(t, bucket.IsCompliant())
+	require.Len(t, bucket.ComplianceWarnings(), 1)
+	assert.Equal(t, 0, bucket.ComplianceWarnings()[0].Origin.RuleIndex)
+
+	// re-rendering the loaded results produces the same document
+	var again bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&again, results))
+	var original bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&original, testResults()))
+	assert.JSONEq(t, original.String(), again.String())
+}
+
+func TestJSONReporterRetries(t *testing.T) {
+	results := testResults()
+	results[0].Retries = 2
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&buf, results))
+
+	doc, err := ReadJSONDocument(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, doc.Summary.Retries)
+
+	retried := 0
+	for _, result := range doc.Results() {
+		retried += result.Retries
+	}
+	assert.Equal(t, 2, retried)
+
+	// retries are omitted when no API call was retried
+	buf.Reset()
+	require.NoError(t, NewJSONReporter().Report(&buf, testResults()))
+	assert.NotContains(t, buf.String(), `"retries"`)
+}
+
+func TestReportSearchWarnings(t *testing.T) {
+	results := testResults()
+	// the first result is the s3.bucket definition, which is rendered last
+	results[0].Warnings = []string{"Resource Explorer truncated the results, some resources may be missing"}
+
+	var text bytes.Buffer
+	require.NoError(t, NewTextReporter().Report(&text, results))
+	assert.Contains(t, text.String(), "Warnings: 1 resource definitions had warnings, results may be incomplete")
+	assert.Contains(t, text.String(), "Warning for s3.bucket: Resource Explorer truncated the results, some resources may be missing")
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONReporter().Report(&buf, results))
+
+	doc, err := ReadJSONDocument(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, doc.Summary.DefinitionsWithWarnings)
+	assert.Equal(t, results[0].Warnings, doc.Definitions[2].Warnings)
+	assert.Equal(t, results[0].Warnings, doc.Results()[2].Warnings)
+
+	warning := "s3.bucket: Resource Explorer truncated the results, some resources may be missing"
+	for _, tc := range []struct {
+		name     string
+		reporter Reporter
+		expected string
+	}{
+		{"SARIF", NewSARIFReporter(), `"text": "` + warning + `"`},
+		{"JUnit", NewJUnitReporter(), "<system-err>Resource Explorer truncated the results, some resources may be missing</system-err>"},
+		{"CSV", NewCSVReporter(), `,,,s3,bucket,,warning,"Resource Explorer truncated the results, some resources may be missing",,,`},
+		{"HTML", NewHTMLReporter(), "<li><strong>s3.bucket</strong>: Resource Explorer truncated the results, some resources may be missing</li>"},
+		{"Markdown", NewMarkdownReporter(0), "- `s3.bucket`: Resource Explorer truncated the results, some resources may be missing"},
+		{"Prometheus", NewPrometheusReporter(), `tagpatrol_definition_warnings{service="s3",resource_type="bucket"} 1`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			require.NoError(t, tc.reporter.Report(&buf, results))
+			assert.Contains(t, buf.String(), tc.expected)
+		})
+	}
+}
+
+func TestReadJSONDocumentErrors(t *testing.T) {
+	_, err := ReadJSONDocument(strings.NewReader("not json"))
+	assert.Error(t, err)
+
+	_, err = ReadJSONDocument(strings.NewReader(`{"version": "99", "definitions": []}`))
+	assert.ErrorContains(t, err, "unsupported results version `99`")
+}
+
+func TestSARIFReporter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewSARIFReporter().Report(&buf, testResults()))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+
+	run := log.Runs[0]
+	assert.Equal(t, "tagpatrol", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, len(sarifRules))
+
+	require.Len(t, run.Invocations, 1)
+	assert.False(t, run.Invocations[0].ExecutionSuccessful)
+	require.Len(t, run.Invocations[0].ToolExecutionNotifications, 1)
+	assert.Equal(t, "access denied", run.Invocations[0].ToolExecutionNotifications[0].Message.Text)
+
+	// the warning of the compliant bucket isn't a result
+	require.Len(t, run.Results, 1)
+
+	missing := run.Results[0]
+	assert.Equal(t, "TP001", missing.RuleID)
+	assert.Equal(t, "error", missing.Level)
+	assert.Equal(t, "arn:aws:ec2:us-west-2:123456789012:instance/i-1", missing.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "123456789012", missing.Properties.OwnerID)
+	assert.NotEmpty(t, missing.PartialFingerprints["tagPatrolResource/v1"])
+	require.Len(t, missing.Properties.Errors, 1)
+	assert.Equal(t, "owner", missing.Properties.Errors[0].Key)
+	assert.Equal(t, "base", missing.Properties.Errors[0].Origin.Name)
+}
+
+func TestSARIFReporterGroupsFindings(t *testing.T) {
+	resource := NewMockResource("arn:aws:ec2:us-west-2:123456789012:instance/i-2", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012",
+		map[string]string{"env": "qa"})
+	resource.AddComplianceError(&cr.ComplianceError{Code: cr.CodeValueNotAllowed, Message: "Tag `env` has invalid value `qa`", Key: "env", Value: "qa"})
+	resource.AddComplianceError(&cr.ComplianceError{Code: cr.CodeMissingMandatoryTag, Message: "Missing mandatory tag: `owner`", Key: "owner"})
+	resource.AddComplianceWarning(&cr.ComplianceWarning{Code: "custom", Message: "Unknown check"})
+
+	var buf bytes.Buffer
+	require.NoError(t, NewSARIFReporter().Report(&buf, []patrol.Result{{
+		Definition:        &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+		Resources:         []cr.CloudResource{resource},
+		NonCompliantCount: 1,
+	}}))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Len(t, log.Runs[0].Results, 1)
+
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, "TP002", result.RuleID)
+	assert.Equal(t, "error", result.Level)
+	assert.Equal(t, "Tag `env` has invalid value `qa`\nMissing mandatory tag: `owner`\nUnknown check", result.Message.Text)
+	assert.Len(t, result.Properties.Errors, 2)
+	require.Len(t, result.Properties.Warnings, 1)
+	assert.Equal(t, "custom", result.Properties.Warnings[0].Code)
+}
+
+func TestRuleFor(t *testing.T) {
+	tests := []struct {
+		code     cr.Code
+		severity string
+		rule     string
+	}{
+		{cr.CodeMissingMandatoryTag, severityError, "TP001"},
+		{cr.CodeValueNotAllowed, severityError, "TP002"},
+		{cr.CodeRegexMismatch, severityError, "TP003"},
+		{cr.CodeInvalidBool, severityError, "TP004"},
+		{cr.CodeInvalidInt, severityError, "TP005"},
+		{cr.CodeBelowMinimum, severityError, "TP006"},
+		{cr.CodeAboveMaximum, severityError, "TP006"},
+		{cr.CodeRuleMissingRequiredTag, severityError, "TP007"},
+		{cr.CodeRuleMissingRecommendedTag, severityWarning, "TP008"},
+		{cr.CodeRuleError, severityError, "TP009"},
+		{cr.CodeRuleWarning, severityWarning, "TP010"},
+		{"", severityError, "TP009"},
+		{"", severityWarning, "TP010"},
+		{"custom", "", "TP009"},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.code)+"/"+tc.severity, func(t *testing.T) {
+			f := &finding{ComplianceError: cr.ComplianceError{Code: tc.code}, Severity: tc.severity}
+			assert.Equal(t, tc.rule, sarifRules[ruleFor(f)].id)
+		})
+	}
+}
+
+func TestReportDiff(t *testing.T) {
+	baseline := testResults()
+	current := testResults()
+
+	regressed := NewMockResource("arn:aws:ec2:us-west-2:123456789012:instance/i-1", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012",
+		map[string]string{"env": "qa"})
+	regressed.AddComplianceError(current[2].Resources[0].ComplianceErrors()[0])
+	regressed.AddComplianceError(&cr.ComplianceError{Code: cr.CodeValueNotAllowed, Message: "Tag `env` has invalid value `qa`", Key: "env", Value: "qa"})
+	current[2].Resources = []cr.CloudResource{regressed}
+
+	diff := patrol.Compare(baseline, current)
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewJSONReporter().ReportDiff(&buf, diff))
+
+		var doc JSONDocument
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+		require.NotNil(t, doc.Baseline)
+		require.Len(t, doc.Baseline.New, 1)
+		require.Len(t, doc.Baseline.New[0].Errors, 1)
+		assert.Equal(t, "env", doc.Baseline.New[0].Errors[0].Key)
+		require.Len(t, doc.Baseline.Unchanged, 1)
+		assert.Equal(t, "owner", doc.Baseline.Unchanged[0].Errors[0].Key)
+		assert.Empty(t, doc.Baseline.Fixed)
+	})
+
+	t.Run("SARIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewSARIFReporter().ReportDiff(&buf, diff))
+
+		var log sarifLog
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+		results := log.Runs[0].Results
+		require.Len(t, results, 2)
+		assert.Equal(t, "new", results[0].BaselineState)
+		assert.Equal(t, "TP002", results[0].RuleID)
+		assert.Equal(t, "unchanged", results[1].BaselineState)
+		assert.Equal(t, "TP001", results[1].RuleID)
+
+		buf.Reset()
+		require.NoError(t, NewSARIFReporter().ReportDiff(&buf, patrol.Compare(baseline, testResults()[:2])))
+
+		var fixed sarifLog
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &fixed))
+		require.Len(t, fixed.Runs[0].Results, 1)
+		assert.Equal(t, "absent", fixed.Runs[0].Results[0].BaselineState)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		fixed := testResults()
+		fixed[2].Resources = []cr.CloudResource{NewMockResource("arn:aws:ec2:us-west-2:123456789012:instance/i-1", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012", nil)}
+
+		var buf bytes.Buffer
+		require.NoError(t, NewMarkdownReporter(0).ReportDiff(&buf, patrol.Compare(baseline, fixed)))
+
+		output := buf.String()
+		assert.Contains(t, output, "## Baseline Comparison")
+		assert.Contains(t, output, "| Fixed | 1 |")
+		assert.Contains(t, output, "<summary><strong>Fixed</strong>: 1 resources</summary>")
+		assert.Contains(t, output, "| `ec2.instance` | `arn:aws:ec2:us-west-2:123456789012:instance/i-1` | 123456789012 | us-west-2 | Missing mandatory tag: `owner` |")
+	})
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewJUnitReporter().Report(&buf, testResults()))
+
+	output := buf.String()
+	assert.True(t, strings.HasPrefix(output, xml.Header))
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Errors)
+	require.Len(t, suites.Suites, 3)
+
+	instances := suites.Suites[0]
+	assert.Equal(t, "ec2.instance", instances.Name)
+	require.Len(t, instances.TestCases, 1)
+	require.NotNil(t, instances.TestCases[0].Failure)
+	assert.Equal(t, "Missing mandatory tag: `owner`", instances.TestCases[0].Failure.Body)
+
+	databases := suites.Suites[1]
+	assert.Equal(t, "rds.db", databases.Name)
+	require.Len(t, databases.TestCases, 1)
+	require.NotNil(t, databases.TestCases[0].Error)
+	assert.Equal(t, "access denied", databases.TestCases[0].Error.Body)
+
+	buckets := suites.Suites[2]
+	require.Len(t, buckets.TestCases, 1)
+	assert.Nil(t, buckets.TestCases[0].Failure)
+	assert.Contains(t, buckets.TestCases[0].SystemOut, "Warning: Missing recommended tag `cost-center`")
+}
+
+func TestCSVReporter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewCSVReporter().Report(&buf, testResults()))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{
+		"aws", "123456789012", "us-west-2", "ec2", "AWS::EC2::Instance",
+		"arn:aws:ec2:us-west-2:123456789012:instance/i-1",
+		"error", "Missing mandatory tag: `owner`", `{"env":"dev"}`,
+		"missing_mandatory_tag", "owner",
+	}, records[1])
+	assert.Equal(t, "warning", records[2][6])
+	assert.Equal(t, `{"env":"prod","owner":"team-a"}`, records[2][8])
+}
+
+func TestCSVReporterEscapesFormulas(t *testing.T) {
+	resource := NewMockResource("=HYPERLINK(\"http://example.com\")", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012",
+		map[string]string{"a=b;c": "d;e=f"})
+	resource.AddComplianceError(&cr.ComplianceError{Code: cr.CodeRegexMismatch, Message: "-1 is not allowed", Key: "@owner"})
+
+	var buf bytes.Buffer
+	require.NoError(t, NewCSVReporter().Report(&buf, []patrol.Result{{
+		Definition: &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+		Resources:  []cr.CloudResource{resource},
+	}}))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, `'=HYPERLINK("http://example.com")`, records[1][5])
+	assert.Equal(t, "'-1 is not allowed", records[1][7])
+	assert.Equal(t, `{"a=b;c":"d;e=f"}`, records[1][8])
+	assert.Equal(t, "'@owner", records[1][10])
+}
+
+func TestHTMLReporter(t *testing.T) {
+	rep := NewHTMLReporter()
+	rep.Now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.Report(&buf, testResults()))
+
+	output := buf.String()
+	assert.Contains(t, output, "<title>TagPatrol Compliance Report</title>")
+	assert.Contains(t, output, "Generated Thu, 02 Jan 2025 03:04:05 UTC")
+	assert.Contains(t, output, "<strong>rds.db</strong>: access denied")
+	assert.Contains(t, output, "<td>arn:aws:ec2:us-west-2:123456789012:instance/i-1</td>")
+	assert.Contains(t, output, "<li>Missing mandatory tag: `owner`</li>")
+	assert.Contains(t, output, "width: 50.00%")
+	assert.NotContains(t, output, "<td>arn:aws:s3:::compliant-bucket</td>")
+	assert.NotContains(t, output, "ZgotmplZ")
+}
+
+func TestHTMLReporterWithoutDefinition(t *testing.T) {
+	resource := NewMockResource("i-1", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012", nil)
+	resource.AddComplianceError(&cr.ComplianceError{Message: "Missing mandatory tag: `owner`"})
+
+	var buf bytes.Buffer
+	require.NoError(t, NewHTMLReporter().Report(&buf, []patrol.Result{{Resources: []cr.CloudResource{resource}, NonCompliantCount: 1}}))
+	assert.Contains(t, buf.String(), "<td>i-1</td>")
+	assert.Contains(t, buf.String(), "unknown")
+}
+
+func TestMarkdownReporter(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewMarkdownReporter(0).Report(&buf, testResults()))
+
+		output := buf.String()
+		assert.Contains(t, output, "| Resources | 2 |")
+		assert.Contains(t, output, "| `rds.db` | 0 | 0 | :warning: access denied |")
+		assert.Contains(t, output, "| `ec2.instance` | 0 | 1 | :x: |")
+		assert.Contains(t, output, "<summary><strong>ec2.instance</strong>: 1 non-compliant</summary>")
+		assert.Contains(t, output, "| `arn:aws:ec2:us-west-2:123456789012:instance/i-1` | 123456789012 | us-west-2 | Missing mandatory tag: `owner` |  |")
+		assert.NotContains(t, output, "not shown")
+	})
+
+	t.Run("Capped rows", func(t *testing.T) {
+		var resources []cr.CloudResource
+		for i := range 5 {
+			resource := NewMockResource(fmt.Sprintf("bucket-%d", i), "AWS::S3::Bucket", "s3", "aws", "us-east-1", "123456789012", nil)
+			resource.AddComplianceError(&cr.ComplianceError{Message: "Tag `a|b` is invalid"})
+			resources = append(resources, resource)
+		}
+
+		results := []patrol.Result{{
+			Definition:        &types.ResourceDefinition{Service: "s3", ResourceType: "bucket"},
+			Resources:         resources,
+			NonCompliantCount: 5,
+		}}
+
+		var buf bytes.Buffer
+		require.NoError(t, NewMarkdownReporter(2).Report(&buf, results))
+
+		output := buf.String()
+		assert.Contains(t, output, "`bucket-1`")
+		assert.NotContains(t, output, "`bucket-2`")
+		assert.Contains(t, output, "_3 more non-compliant resource(s) not shown._")
+		assert.Contains(t, output, "Tag `a\\|b` is invalid")
+	})
+
+	t.Run("Escapes every cell", func(t *testing.T) {
+		resource := NewMockResource("bucket|a\nb", "AWS::S3::Bucket", "s3", "aws", "us-east-1|x", "1234\n5678", nil)
+		resource.AddComplianceError(&cr.ComplianceError{Message: "Missing mandatory tag: `owner`"})
+
+		var buf bytes.Buffer
+		require.NoError(t, NewMarkdownReporter(0).Report(&buf, []patrol.Result{{
+			Definition:        &types.ResourceDefinition{Service: "s3", ResourceType: "bucket"},
+			Resources:         []cr.CloudResource{resource},
+			NonCompliantCount: 1,
+		}}))
+
+		assert.Contains(t, buf.String(), "| `bucket\\|a<br>b` | 1234<br>5678 | us-east-1\\|x | Missing mandatory tag: `owner` |  |\n")
+	})
+}
+
+func TestPrometheusReporter(t *testing.T) {
+	rep := NewPrometheusReporter()
+	rep.Now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.Report(&buf, testResults()))
+
+	output := buf.String()
+	assert.Contains(t, output, "# TYPE tagpatrol_resources gauge\n")
+	assert.Contains(t, output, "tagpatrol_definitions 3\n")
+	assert.Contains(t, output, `tagpatrol_definition_error{service="rds",resource_type="db"} 1`)
+	assert.Contains(t, output, `tagpatrol_definition_error{service="ec2",resource_type="instance"} 0`)
+	assert.Contains(t, output, `tagpatrol_resources{provider="aws",service="ec2",resource_type="instance",account="123456789012",region="us-west-2"} 1`)
+	assert.Contains(t, output, `tagpatrol_resources_non_compliant{provider="aws",service="ec2",resource_type="instance",account="123456789012",region="us-west-2"} 1`)
+	assert.Contains(t, output, `tagpatrol_resources_compliant{provider="aws",service="s3",resource_type="bucket",account="123456789012",region="us-east-1"} 1`)
+	assert.Contains(t, output, `tagpatrol_missing_tag_resources{service="ec2",resource_type="instance",key="owner",severity="error"} 1`)
+	assert.Contains(t, output, `tagpatrol_missing_tag_resources{service="s3",resource_type="bucket",key="cost-center",severity="warning"} 1`)
+	assert.Contains(t, output, `tagpatrol_findings{service="ec2",resource_type="instance",severity="warning"} 0`)
+	assert.Contains(t, output, "tagpatrol_compliance_ratio 0.5\n")
+	assert.Contains(t, output, "tagpatrol_last_run_timestamp_seconds 1.7e+09\n")
+
+	// a resource missing a tag for several reasons counts once
+	resource := NewMockResource("i-2", "AWS::EC2::Instance", "ec2", "aws", "us-west-2", "123456789012", nil)
+	resource.AddComplianceError(&cr.ComplianceError{Code: cr.CodeMissingMandatoryTag, Message: "Missing mandatory tag: `owner`", Key: "owner"})
+	resource.AddComplianceError(&cr.ComplianceError{Code: cr.CodeRuleMissingRequiredTag, Message: "Missing required tag `owner` based on rule condition", Key: "owner"})
+
+	buf.Reset()
+	require.NoError(t, rep.Report(&buf, []patrol.Result{{
+		Definition:        &types.ResourceDefinition{Service: "ec2", ResourceType: "instance"},
+		Resources:         []cr.CloudResource{resource},
+		NonCompliantCount: 1,
+	}}))
+	assert.Contains(t, buf.String(), `tagpatrol_missing_tag_resources{service="ec2",resource_type="instance",key="owner",severity="error"} 1`)
+	assert.Contains(t, buf.String(), `tagpatrol_findings{service="ec2",resource_type="instance",severity="error"} 2`)
+}
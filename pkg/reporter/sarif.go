@@ -0,0 +1,381 @@
+package reporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+
+	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+const (
+	sarifVersion        = "2.1.0"
+	sarifSchema         = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName       = "tagpatrol"
+	sarifInformationURI = "https://github.com/eliran89c/tag-patrol"
+)
+
+// sarifRule describes a single ruler check reported as a SARIF rule
+type sarifRule struct {
+	id          string
+	name        string
+	description string
+	level       string
+	codes       []cr.Code
+}
+
+// sarifRules lists the checks performed by the ruler
+var sarifRules = []*sarifRule{
+	{
+		id:          "TP001",
+		name:        "MissingMandatoryTag",
+		description: "A mandatory tag is missing",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeMissingMandatoryTag},
+	},
+	{
+		id:          "TP002",
+		name:        "TagValueNotAllowed",
+		description: "A tag value is not in the allowed values",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeValueNotAllowed},
+	},
+	{
+		id:          "TP003",
+		name:        "TagValueRegexMismatch",
+		description: "A tag value does not match the required regex",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeRegexMismatch},
+	},
+	{
+		id:          "TP004",
+		name:        "TagValueNotBoolean",
+		description: "A tag value is not a valid boolean",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeInvalidBool},
+	},
+	{
+		id:          "TP005",
+		name:        "TagValueNotInteger",
+		description: "A tag value is not a valid integer",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeInvalidInt},
+	},
+	{
+		id:          "TP006",
+		name:        "TagValueOutOfRange",
+		description: "A tag value is outside of the allowed numeric range",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeBelowMinimum, cr.CodeAboveMaximum},
+	},
+	{
+		id:          "TP007",
+		name:        "RuleMissingRequiredTag",
+		description: "A tag required by a conditional rule is missing",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeRuleMissingRequiredTag},
+	},
+	{
+		id:          "TP008",
+		name:        "RuleMissingRecommendedTag",
+		description: "A tag recommended by a conditional rule is missing",
+		level:       severityWarning,
+		codes:       []cr.Code{cr.CodeRuleMissingRecommendedTag},
+	},
+	{
+		id:          "TP009",
+		name:        "RuleError",
+		description: "A conditional rule reported an error",
+		level:       severityError,
+		codes:       []cr.Code{cr.CodeRuleError},
+	},
+	{
+		id:          "TP010",
+		name:        "RuleWarning",
+		description: "A conditional rule reported a warning",
+		level:       severityWarning,
+		codes:       []cr.Code{cr.CodeRuleWarning},
+	},
+	{
+		id:          "TP011",
+		name:        "TagValueUnknown",
+		description: "A tag value is only known at deploy time and was not validated",
+		level:       severityWarning,
+		codes:       []cr.Code{cr.CodeUnknownValue},
+	},
+}
+
+// ruleFor returns the index of the SARIF rule for a finding, findings without
+// a known code fall back to the generic rule error or warning
+func ruleFor(f *finding) int {
+	fallback := cr.CodeRuleError
+	if f.Severity == severityWarning {
+		fallback = cr.CodeRuleWarning
+	}
+
+	for _, code := range []cr.Code{f.Code, fallback} {
+		for i, rule := range sarifRules {
+			if slices.Contains(rule.codes, code) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        *sarifTool         `json:"tool"`
+	Invocations []*sarifInvocation `json:"invocations"`
+	Results     []*sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver *sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri"`
+	Version        string                `json:"version,omitempty"`
+	Rules          []*sarifReportingRule `json:"rules"`
+}
+
+type sarifReportingRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name"`
+	ShortDescription     *sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration *sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                 `json:"executionSuccessful"`
+	ToolExecutionNotifications []*sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string        `json:"level"`
+	Message *sarifMessage `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	BaselineState       string            `json:"baselineState,omitempty"`
+	Message             *sarifMessage     `json:"message"`
+	Locations           []*sarifLocation  `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          *sarifProperties  `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []*sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifProperties struct {
+	Provider     string            `json:"provider"`
+	Service      string            `json:"service"`
+	ResourceType string            `json:"resourceType"`
+	Region       string            `json:"region"`
+	OwnerID      string            `json:"ownerId"`
+	Tags         map[string]string `json:"tags"`
+	Errors       []*JSONFinding    `json:"errors"`
+	Warnings     []*JSONFinding    `json:"warnings,omitempty"`
+}
+
+// SARIFReporter renders patrol results as a SARIF 2.1.0 log
+type SARIFReporter struct {
+	ToolVersion string
+}
+
+// NewSARIFReporter creates a new SARIFReporter instance
+func NewSARIFReporter() *SARIFReporter {
+	return &SARIFReporter{}
+}
+
+// Report writes a SARIF log where every ruler check is a rule and every non-compliant
+// resource is a result, reported under the rule of its first error
+func (r *SARIFReporter) Report(w io.Writer, results []patrol.Result) error {
+	run := r.newRun(results)
+
+	for _, result := range sortResults(results) {
+		if result.Error != nil {
+			continue
+		}
+
+		for _, resource := range result.Resources {
+			if !resource.IsCompliant() {
+				run.Results = append(run.Results, newSARIFResult(resource, resourceFindings(resource)))
+			}
+		}
+	}
+
+	return writeSARIF(w, run)
+}
+
+// ReportDiff writes a SARIF log with a result for every new, unchanged and fixed resource
+// of the baseline comparison, with its baseline state set to `new`, `unchanged` and
+// `absent` respectively
+func (r *SARIFReporter) ReportDiff(w io.Writer, diff *patrol.Diff) error {
+	run := r.newRun(diff.Results())
+
+	for _, group := range []struct {
+		state   string
+		entries []*patrol.DiffEntry
+	}{
+		{"new", diff.New},
+		{"unchanged", diff.Unchanged},
+		{"absent", diff.Fixed},
+	} {
+		for _, entry := range group.entries {
+			result := newSARIFResult(entry.Resource, errorFindings(entry.Errors))
+			result.BaselineState = group.state
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return writeSARIF(w, run)
+}
+
+// newRun creates a run with every ruler check as a rule, reporting the definitions that
+// failed and the warnings of the finder as tool execution notifications
+func (r *SARIFReporter) newRun(results []patrol.Result) *sarifRun {
+	run := &sarifRun{
+		Tool: &sarifTool{
+			Driver: &sarifDriver{
+				Name:           sarifToolName,
+				InformationURI: sarifInformationURI,
+				Version:        r.ToolVersion,
+				Rules:          make([]*sarifReportingRule, 0, len(sarifRules)),
+			},
+		},
+		Results: make([]*sarifResult, 0),
+	}
+
+	for _, rule := range sarifRules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifReportingRule{
+			ID:                   rule.id,
+			Name:                 rule.name,
+			ShortDescription:     &sarifMessage{Text: rule.description},
+			DefaultConfiguration: &sarifConfiguration{Level: rule.level},
+		})
+	}
+
+	invocation := &sarifInvocation{ExecutionSuccessful: true}
+
+	for _, result := range sortResults(results) {
+		if result.Error != nil {
+			invocation.ExecutionSuccessful = false
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, &sarifNotification{
+				Level:   severityError,
+				Message: &sarifMessage{Text: result.Error.Error()},
+			})
+		}
+
+		for _, warning := range result.Warnings {
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, &sarifNotification{
+				Level:   severityWarning,
+				Message: &sarifMessage{Text: definitionName(result) + ": " + warning},
+			})
+		}
+	}
+
+	run.Invocations = []*sarifInvocation{invocation}
+	return run
+}
+
+func writeSARIF(w io.Writer, run *sarifRun) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(&sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []*sarifRun{run},
+	})
+}
+
+// newSARIFResult groups the findings of a non-compliant resource into a single result
+func newSARIFResult(resource cr.CloudResource, findings []*finding) *sarifResult {
+	properties := &sarifProperties{
+		Provider:     resource.Provider(),
+		Service:      resource.Service(),
+		ResourceType: resource.Type(),
+		Region:       resource.Region(),
+		OwnerID:      resource.OwnerID(),
+		Tags:         resource.Tags(),
+		Errors:       make([]*JSONFinding, 0, len(findings)),
+	}
+
+	messages := make([]string, 0, len(findings))
+	for _, f := range findings {
+		messages = append(messages, f.Message)
+		if f.Severity == severityWarning {
+			properties.Warnings = append(properties.Warnings, newJSONFinding(f))
+		} else {
+			properties.Errors = append(properties.Errors, newJSONFinding(f))
+		}
+	}
+
+	// errors come first, so the result is reported under the rule of the first error
+	index := 0
+	level := severityError
+	if len(findings) > 0 {
+		index = ruleFor(findings[0])
+		level = findings[0].Severity
+	}
+	fingerprint := sha256.Sum256([]byte(resource.ID()))
+
+	return &sarifResult{
+		RuleID:    sarifRules[index].id,
+		RuleIndex: index,
+		Level:     level,
+		Message:   &sarifMessage{Text: strings.Join(messages, "\n")},
+		Locations: []*sarifLocation{
+			{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: &sarifArtifactLocation{URI: resource.ID()},
+				},
+				LogicalLocations: []*sarifLogicalLocation{
+					{FullyQualifiedName: resource.ID(), Kind: "resource"},
+				},
+			},
+		},
+		PartialFingerprints: map[string]string{
+			"tagPatrolResource/v1": hex.EncodeToString(fingerprint[:]),
+		},
+		Properties: properties,
+	}
+}
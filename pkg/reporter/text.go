@@ -0,0 +1,63 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+)
+
+// TextReporter renders patrol results as human readable plain text
+type TextReporter struct{}
+
+// NewTextReporter creates a new TextReporter instance
+func NewTextReporter() *TextReporter {
+	return &TextReporter{}
+}
+
+// Report writes the summary followed by every non-compliant resource and its findings
+func (r *TextReporter) Report(w io.Writer, results []patrol.Result) error {
+	var sb strings.Builder
+
+	fmt.Fprintln(&sb, patrol.Summarize(results))
+
+	for _, result := range sortResults(results) {
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(&sb, "Warning for %s: %s\n", definitionName(result), warning)
+		}
+
+		if result.Error != nil {
+			fmt.Fprintf(&sb, "Error processing %s: %v\n", definitionName(result), result.Error)
+			continue
+		}
+
+		if result.NonCompliantCount == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\nResource: %s - Compliant: %d, Non-compliant: %d\n",
+			definitionName(result),
+			result.CompliantCount,
+			result.NonCompliantCount)
+
+		for _, resource := range result.Resources {
+			if resource.IsCompliant() {
+				continue
+			}
+
+			fmt.Fprintf(&sb, "  Non-compliant resource: %s\n", resource.ID())
+
+			for _, e := range resource.ComplianceErrors() {
+				fmt.Fprintf(&sb, "    Error: %s\n", e.Message)
+			}
+
+			for _, warn := range resource.ComplianceWarnings() {
+				fmt.Fprintf(&sb, "    Warning: %s\n", warn.Message)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -40,88 +40,162 @@ func (r *DefaultRuler) ValidateAll(resources []cr.CloudResource, policy *ptypes.
 
 // Validate applies all tag policy rules to a single resource
 func (r *DefaultRuler) Validate(resource cr.CloudResource, policy *ptypes.TagPolicy) {
-	r.validateMandatoryKeys(resource, policy.MandatoryKeys)
-	r.validateTagValues(resource, policy.Validations)
-	r.applyRules(resource, policy.Rules)
+	r.validateMandatoryKeys(resource, policy.MandatoryKeys, policy.Origins)
+	r.validateTagValues(resource, policy.Validations, policy.Origins)
+	r.applyRules(resource, policy.Rules, policy.Origins)
 }
 
-func (r *DefaultRuler) validateMandatoryKeys(resource cr.CloudResource, keys []string) {
+func (r *DefaultRuler) validateMandatoryKeys(resource cr.CloudResource, keys []string, origins *ptypes.Origins) {
 	for _, key := range keys {
-		if _, exists := resource.Tags()[key]; !exists {
-			resource.AddComplianceError(fmt.Sprintf("Missing mandatory tag: `%s`", key))
+		if _, exists := tagValue(resource, key); !exists {
+			if isUnknownValue(resource, key) {
+				resource.AddComplianceWarning(unknownTagWarning(key, "tag must exist", origins.MandatoryKey(key)))
+				continue
+			}
+			resource.AddComplianceError(&cr.ComplianceError{
+				Code:     cr.CodeMissingMandatoryTag,
+				Message:  fmt.Sprintf("Missing mandatory tag: `%s`", key),
+				Key:      key,
+				Expected: "tag must exist",
+				Origin:   origins.MandatoryKey(key),
+			})
 		}
 	}
 }
 
-func (r *DefaultRuler) validateTagValues(resource cr.CloudResource, validations map[string]*ptypes.Validation) {
+func (r *DefaultRuler) validateTagValues(resource cr.CloudResource, validations map[string]*ptypes.Validation, origins *ptypes.Origins) {
 	for key, validation := range validations {
-		value, exists := resource.Tags()[key]
+		value, exists := tagValue(resource, key)
 		if !exists {
 			continue
 		}
 
+		origin := origins.Validation(key)
+
+		if isUnknownValue(resource, key) {
+			resource.AddComplianceWarning(&cr.ComplianceWarning{
+				Code:    cr.CodeUnknownValue,
+				Message: fmt.Sprintf("Tag `%s` has a value that is only known at deploy time and was not validated", key),
+				Key:     key,
+				Value:   value,
+				Origin:  origin,
+			})
+			continue
+		}
+
 		switch validation.Type {
 		case ptypes.TagTypeString:
-			r.validateString(resource, key, value, validation)
+			r.validateString(resource, key, value, validation, origin)
 		case ptypes.TagTypeBool:
-			r.validateBool(resource, key, value)
+			r.validateBool(resource, key, value, origin)
 		case ptypes.TagTypeInt:
-			r.validateInt(resource, key, value, validation)
+			r.validateInt(resource, key, value, validation, origin)
 		}
 	}
 }
 
-func (r *DefaultRuler) validateString(resource cr.CloudResource, key, value string, validation *ptypes.Validation) {
+func (r *DefaultRuler) validateString(resource cr.CloudResource, key, value string, validation *ptypes.Validation, origin *ptypes.Origin) {
 	if len(validation.AllowedValues) > 0 {
-		valid := slices.Contains(validation.AllowedValues, value)
+		valid := slices.ContainsFunc(validation.AllowedValues, func(allowed string) bool {
+			return policyValue(resource, allowed) == value
+		})
 
 		if !valid {
-			resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%s` which is not in allowed values: `%s`", key, value, strings.Join(validation.AllowedValues, ", ")))
+			resource.AddComplianceError(&cr.ComplianceError{
+				Code:     cr.CodeValueNotAllowed,
+				Message:  fmt.Sprintf("Tag `%s` has value `%s` which is not in allowed values: `%s`", key, value, strings.Join(validation.AllowedValues, ", ")),
+				Key:      key,
+				Value:    value,
+				Expected: fmt.Sprintf("one of: %s", strings.Join(validation.AllowedValues, ", ")),
+				Origin:   origin,
+			})
 		}
 	}
 
 	if validation.Regex != "" {
-		regex, err := regexp.Compile(validation.Regex)
+		regex, err := policyRegex(resource, validation.Regex)
 		if err == nil && !regex.MatchString(value) {
-			resource.AddComplianceError(fmt.Sprintf("Tag `%s` with value `%s` does not match regex: `%s`", key, value, validation.Regex))
+			resource.AddComplianceError(&cr.ComplianceError{
+				Code:     cr.CodeRegexMismatch,
+				Message:  fmt.Sprintf("Tag `%s` with value `%s` does not match regex: `%s`", key, value, validation.Regex),
+				Key:      key,
+				Value:    value,
+				Expected: fmt.Sprintf("matches regex: %s", validation.Regex),
+				Origin:   origin,
+			})
 		}
 	}
 }
 
-func (r *DefaultRuler) validateBool(resource cr.CloudResource, key, value string) {
+func (r *DefaultRuler) validateBool(resource cr.CloudResource, key, value string, origin *ptypes.Origin) {
 	valid := slices.Contains([]string{"true", "false"}, value)
 
 	if !valid {
-		resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%s` which is not a valid boolean", key, value))
+		resource.AddComplianceError(&cr.ComplianceError{
+			Code:     cr.CodeInvalidBool,
+			Message:  fmt.Sprintf("Tag `%s` has value `%s` which is not a valid boolean", key, value),
+			Key:      key,
+			Value:    value,
+			Expected: "one of: true, false",
+			Origin:   origin,
+		})
 	}
 }
 
-func (r *DefaultRuler) validateInt(resource cr.CloudResource, key, value string, validation *ptypes.Validation) {
+func (r *DefaultRuler) validateInt(resource cr.CloudResource, key, value string, validation *ptypes.Validation, origin *ptypes.Origin) {
 	intVal, err := strconv.Atoi(value)
 	if err != nil {
-		resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%s` which is not a valid integer", key, value))
+		resource.AddComplianceError(&cr.ComplianceError{
+			Code:     cr.CodeInvalidInt,
+			Message:  fmt.Sprintf("Tag `%s` has value `%s` which is not a valid integer", key, value),
+			Key:      key,
+			Value:    value,
+			Expected: "a valid integer",
+			Origin:   origin,
+		})
 		return
 	}
 
 	if validation.MinValue != 0 && intVal < validation.MinValue {
-		resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%d` which is less than minimum: %d", key, intVal, validation.MinValue))
+		resource.AddComplianceError(&cr.ComplianceError{
+			Code:     cr.CodeBelowMinimum,
+			Message:  fmt.Sprintf("Tag `%s` has value `%d` which is less than minimum: %d", key, intVal, validation.MinValue),
+			Key:      key,
+			Value:    value,
+			Expected: fmt.Sprintf(">= %d", validation.MinValue),
+			Origin:   origin,
+		})
 	}
 
 	if validation.MaxValue != 0 && intVal > validation.MaxValue {
-		resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%d` which is greater than maximum: %d", key, intVal, validation.MaxValue))
+		resource.AddComplianceError(&cr.ComplianceError{
+			Code:     cr.CodeAboveMaximum,
+			Message:  fmt.Sprintf("Tag `%s` has value `%d` which is greater than maximum: %d", key, intVal, validation.MaxValue),
+			Key:      key,
+			Value:    value,
+			Expected: fmt.Sprintf("<= %d", validation.MaxValue),
+			Origin:   origin,
+		})
 	}
 
 	if len(validation.AllowedValues) > 0 {
 		if !slices.Contains(validation.AllowedValues, value) {
-			resource.AddComplianceError(fmt.Sprintf("Tag `%s` has value `%s` which is not in allowed values: `%s`", key, value, strings.Join(validation.AllowedValues, ", ")))
+			resource.AddComplianceError(&cr.ComplianceError{
+				Code:     cr.CodeValueNotAllowed,
+				Message:  fmt.Sprintf("Tag `%s` has value `%s` which is not in allowed values: `%s`", key, value, strings.Join(validation.AllowedValues, ", ")),
+				Key:      key,
+				Value:    value,
+				Expected: fmt.Sprintf("one of: %s", strings.Join(validation.AllowedValues, ", ")),
+				Origin:   origin,
+			})
 		}
 	}
 }
 
-func (r *DefaultRuler) applyRules(resource cr.CloudResource, rules []*ptypes.Rule) {
-	for _, rule := range rules {
+func (r *DefaultRuler) applyRules(resource cr.CloudResource, rules []*ptypes.Rule, origins *ptypes.Origins) {
+	for i, rule := range rules {
 		if r.evaluateCondition(resource, rule.When) {
-			r.applyAction(resource, rule.Then)
+			r.applyAction(resource, rule.Then, origins.Rule(i))
 		}
 	}
 }
@@ -132,39 +206,42 @@ func (r *DefaultRuler) evaluateCondition(resource cr.CloudResource, condition *p
 	}
 
 	if condition.Exists != nil {
-		_, exists := resource.Tags()[condition.Exists.Key]
+		_, exists := tagValue(resource, condition.Exists.Key)
 		return exists
 	}
 
 	if condition.Equals != nil {
-		value, exists := resource.Tags()[condition.Equals.Key]
-		if !exists {
+		value, exists := tagValue(resource, condition.Equals.Key)
+		if !exists || isUnknownValue(resource, condition.Equals.Key) {
 			return false
 		}
 		strValue := fmt.Sprintf("%v", condition.Equals.Value)
-		return value == strValue
+		return value == policyValue(resource, strValue)
 	}
 
 	if condition.NotEquals != nil {
-		value, exists := resource.Tags()[condition.NotEquals.Key]
+		value, exists := tagValue(resource, condition.NotEquals.Key)
+		if isUnknownValue(resource, condition.NotEquals.Key) {
+			return false
+		}
 		if !exists {
 			return true // If the key doesn't exist, it's not equal
 		}
 		strValue := fmt.Sprintf("%v", condition.NotEquals.Value)
-		return value != strValue
+		return value != policyValue(resource, strValue)
 	}
 
 	if condition.Contains != nil {
-		value, exists := resource.Tags()[condition.Contains.Key]
-		if !exists {
+		value, exists := tagValue(resource, condition.Contains.Key)
+		if !exists || isUnknownValue(resource, condition.Contains.Key) {
 			return false
 		}
-		return strings.Contains(value, condition.Contains.Value)
+		return strings.Contains(value, policyValue(resource, condition.Contains.Value))
 	}
 
 	if condition.GreaterThan != nil {
-		value, exists := resource.Tags()[condition.GreaterThan.Key]
-		if !exists {
+		value, exists := tagValue(resource, condition.GreaterThan.Key)
+		if !exists || isUnknownValue(resource, condition.GreaterThan.Key) {
 			return false
 		}
 		numValue, err := strconv.ParseFloat(value, 64)
@@ -175,8 +252,8 @@ func (r *DefaultRuler) evaluateCondition(resource cr.CloudResource, condition *p
 	}
 
 	if condition.LessThan != nil {
-		value, exists := resource.Tags()[condition.LessThan.Key]
-		if !exists {
+		value, exists := tagValue(resource, condition.LessThan.Key)
+		if !exists || isUnknownValue(resource, condition.LessThan.Key) {
 			return false
 		}
 		numValue, err := strconv.ParseFloat(value, 64)
@@ -207,32 +284,116 @@ func (r *DefaultRuler) evaluateCondition(resource cr.CloudResource, condition *p
 	return false
 }
 
-func (r *DefaultRuler) applyAction(resource cr.CloudResource, action *ptypes.Action) {
+func (r *DefaultRuler) applyAction(resource cr.CloudResource, action *ptypes.Action, origin *ptypes.Origin) {
 	if action == nil {
 		return
 	}
 
 	if action.MustContainKeys != nil {
 		for _, key := range action.MustContainKeys {
-			if _, exists := resource.Tags()[key]; !exists {
-				resource.AddComplianceError(fmt.Sprintf("Missing required tag `%s` based on rule condition", key))
+			if _, exists := tagValue(resource, key); !exists {
+				if isUnknownValue(resource, key) {
+					resource.AddComplianceWarning(unknownTagWarning(key, "tag must exist", origin))
+					continue
+				}
+				resource.AddComplianceError(&cr.ComplianceError{
+					Code:     cr.CodeRuleMissingRequiredTag,
+					Message:  fmt.Sprintf("Missing required tag `%s` based on rule condition", key),
+					Key:      key,
+					Expected: "tag must exist",
+					Origin:   origin,
+				})
 			}
 		}
 	}
 
 	if action.ShouldContainKeys != nil {
 		for _, key := range action.ShouldContainKeys {
-			if _, exists := resource.Tags()[key]; !exists {
-				resource.AddComplianceWarning(fmt.Sprintf("Missing recommended tag `%s` based on rule condition", key))
+			if _, exists := tagValue(resource, key); !exists {
+				if isUnknownValue(resource, key) {
+					resource.AddComplianceWarning(unknownTagWarning(key, "tag should exist", origin))
+					continue
+				}
+				resource.AddComplianceWarning(&cr.ComplianceWarning{
+					Code:     cr.CodeRuleMissingRecommendedTag,
+					Message:  fmt.Sprintf("Missing recommended tag `%s` based on rule condition", key),
+					Key:      key,
+					Expected: "tag should exist",
+					Origin:   origin,
+				})
 			}
 		}
 	}
 
 	if action.Error != "" {
-		resource.AddComplianceError(action.Error)
+		resource.AddComplianceError(&cr.ComplianceError{
+			Code:    cr.CodeRuleError,
+			Message: action.Error,
+			Origin:  origin,
+		})
 	}
 
 	if action.Warn != "" {
-		resource.AddComplianceWarning(action.Warn)
+		resource.AddComplianceWarning(&cr.ComplianceWarning{
+			Code:    cr.CodeRuleWarning,
+			Message: action.Warn,
+			Origin:  origin,
+		})
+	}
+}
+
+// tagValue looks up a policy tag key on the resource, normalizing the key for
+// providers that restrict tag keys
+func tagValue(resource cr.CloudResource, key string) (string, bool) {
+	if normalizer, ok := resource.(cr.TagNormalizer); ok {
+		key = normalizer.NormalizeKey(key)
+	}
+
+	value, exists := resource.Tags()[key]
+	return value, exists
+}
+
+// unknownTagWarning reports a tag that is missing from a resource whose tags are only
+// partly known, the tag may still be set at deploy time
+func unknownTagWarning(key, expected string, origin *ptypes.Origin) *cr.ComplianceWarning {
+	return &cr.ComplianceWarning{
+		Code:     cr.CodeUnknownValue,
+		Message:  fmt.Sprintf("Tag `%s` is not set, but the tags are only known at deploy time and it was not checked", key),
+		Key:      key,
+		Expected: expected,
+		Origin:   origin,
+	}
+}
+
+// isUnknownValue reports whether the value of a policy tag key on the resource is only
+// known at deploy time. Conditions on such values don't match, value checks are skipped
+// and missing tags are reported as warnings.
+func isUnknownValue(resource cr.CloudResource, key string) bool {
+	reporter, ok := resource.(cr.UnknownValueReporter)
+	if !ok {
+		return false
+	}
+
+	if normalizer, ok := resource.(cr.TagNormalizer); ok {
+		key = normalizer.NormalizeKey(key)
+	}
+	return reporter.IsUnknownValue(key)
+}
+
+// policyRegex compiles a policy regex for matching the resource tags. Providers that
+// normalize tag values only store lowercase values, so the regex is matched
+// case-insensitively like allowed values are.
+func policyRegex(resource cr.CloudResource, pattern string) (*regexp.Regexp, error) {
+	if _, ok := resource.(cr.TagNormalizer); ok {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// policyValue normalizes a policy tag value for comparison with the resource tags
+func policyValue(resource cr.CloudResource, value string) string {
+	if normalizer, ok := resource.(cr.TagNormalizer); ok {
+		return normalizer.NormalizeValue(value)
 	}
+	return value
 }
@@ -1,6 +1,7 @@
 package ruler
 
 import (
+	"strings"
 	"testing"
 
 	cr "github.com/eliran89c/tag-patrol/pkg/cloudresource"
@@ -67,12 +68,12 @@ func (m *MockResource) IsCompliant() bool {
 	return len(m.errors) == 0
 }
 
-func (m *MockResource) AddComplianceError(msg string) {
-	m.errors = append(m.errors, &cr.ComplianceError{Message: msg})
+func (m *MockResource) AddComplianceError(err *cr.ComplianceError) {
+	m.errors = append(m.errors, err)
 }
 
-func (m *MockResource) AddComplianceWarning(msg string) {
-	m.warnings = append(m.warnings, &cr.ComplianceWarning{Message: msg})
+func (m *MockResource) AddComplianceWarning(warning *cr.ComplianceWarning) {
+	m.warnings = append(m.warnings, warning)
 }
 
 func (m *MockResource) ComplianceErrors() []*cr.ComplianceError {
@@ -108,7 +109,7 @@ func TestValidateMandatoryKeys(t *testing.T) {
 
 		mandatoryKeys := []string{"environment", "owner"}
 
-		ruler.validateMandatoryKeys(resource, mandatoryKeys)
+		ruler.validateMandatoryKeys(resource, mandatoryKeys, nil)
 
 		assert.True(t, resource.IsCompliant())
 		assert.Empty(t, resource.ComplianceErrors())
@@ -129,7 +130,7 @@ func TestValidateMandatoryKeys(t *testing.T) {
 
 		mandatoryKeys := []string{"environment", "owner", "cost-center"}
 
-		ruler.validateMandatoryKeys(resource, mandatoryKeys)
+		ruler.validateMandatoryKeys(resource, mandatoryKeys, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 2)
@@ -153,7 +154,7 @@ func TestValidateMandatoryKeys(t *testing.T) {
 
 		var mandatoryKeys []string
 
-		ruler.validateMandatoryKeys(resource, mandatoryKeys)
+		ruler.validateMandatoryKeys(resource, mandatoryKeys, nil)
 
 		assert.True(t, resource.IsCompliant())
 		assert.Empty(t, resource.ComplianceErrors())
@@ -183,7 +184,7 @@ func TestValidateTagValues(t *testing.T) {
 			},
 		}
 
-		ruler.validateTagValues(resource, validations)
+		ruler.validateTagValues(resource, validations, nil)
 
 		assert.True(t, resource.IsCompliant())
 		assert.Empty(t, resource.ComplianceErrors())
@@ -209,7 +210,7 @@ func TestValidateTagValues(t *testing.T) {
 			},
 		}
 
-		ruler.validateTagValues(resource, validations)
+		ruler.validateTagValues(resource, validations, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 1)
@@ -251,11 +252,11 @@ func TestValidateTagValues(t *testing.T) {
 		}
 
 		// Test valid case
-		ruler.validateTagValues(validResource, validations)
+		ruler.validateTagValues(validResource, validations, nil)
 		assert.True(t, validResource.IsCompliant())
 
 		// Test invalid case
-		ruler.validateTagValues(invalidResource, validations)
+		ruler.validateTagValues(invalidResource, validations, nil)
 		assert.False(t, invalidResource.IsCompliant())
 		assert.Contains(t, invalidResource.ComplianceErrors()[0].Message, "does not match regex")
 	})
@@ -305,13 +306,13 @@ func TestValidateTagValues(t *testing.T) {
 			},
 		}
 
-		ruler.validateTagValues(validResource1, validations)
+		ruler.validateTagValues(validResource1, validations, nil)
 		assert.True(t, validResource1.IsCompliant())
 
-		ruler.validateTagValues(validResource2, validations)
+		ruler.validateTagValues(validResource2, validations, nil)
 		assert.True(t, validResource2.IsCompliant())
 
-		ruler.validateTagValues(invalidResource, validations)
+		ruler.validateTagValues(invalidResource, validations, nil)
 		assert.False(t, invalidResource.IsCompliant())
 		assert.Contains(t, invalidResource.ComplianceErrors()[0].Message, "not a valid boolean")
 	})
@@ -364,14 +365,14 @@ func TestValidateTagValues(t *testing.T) {
 			},
 		}
 
-		ruler.validateTagValues(validResource, validations)
+		ruler.validateTagValues(validResource, validations, nil)
 		assert.True(t, validResource.IsCompliant())
 
-		ruler.validateTagValues(invalidResource, validations)
+		ruler.validateTagValues(invalidResource, validations, nil)
 		assert.False(t, invalidResource.IsCompliant())
 		assert.Contains(t, invalidResource.ComplianceErrors()[0].Message, "not a valid integer")
 
-		ruler.validateTagValues(outOfRangeResource, validations)
+		ruler.validateTagValues(outOfRangeResource, validations, nil)
 		assert.False(t, outOfRangeResource.IsCompliant())
 		assert.Contains(t, outOfRangeResource.ComplianceErrors()[0].Message, "greater than maximum")
 	})
@@ -412,10 +413,10 @@ func TestValidateTagValues(t *testing.T) {
 
 		ruler := NewRuler()
 
-		ruler.validateTagValues(validResource, validations)
+		ruler.validateTagValues(validResource, validations, nil)
 		assert.True(t, validResource.IsCompliant())
 
-		ruler.validateTagValues(invalidResource, validations)
+		ruler.validateTagValues(invalidResource, validations, nil)
 
 		assert.False(t, invalidResource.IsCompliant(), "Resource with invalid integer value should be non-compliant")
 		errors := invalidResource.ComplianceErrors()
@@ -442,7 +443,7 @@ func TestValidateTagValues(t *testing.T) {
 			},
 		}
 
-		ruler.validateTagValues(resource, validations)
+		ruler.validateTagValues(resource, validations, nil)
 
 		assert.True(t, resource.IsCompliant())
 		assert.Empty(t, resource.ComplianceErrors())
@@ -478,7 +479,7 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(resource, rules)
+		ruler.applyRules(resource, rules, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 2) // two from the missing keys
@@ -510,7 +511,7 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(resource, rules)
+		ruler.applyRules(resource, rules, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 1) // one from the missing key
@@ -558,11 +559,11 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(matchResource, rules)
+		ruler.applyRules(matchResource, rules, nil)
 		assert.True(t, matchResource.IsCompliant())          // Warning doesn't affect compliance
 		assert.Len(t, matchResource.ComplianceWarnings(), 2) // One from the warning message, one from should contain
 
-		ruler.applyRules(noMatchResource, rules)
+		ruler.applyRules(noMatchResource, rules, nil)
 		assert.True(t, noMatchResource.IsCompliant())
 		assert.Empty(t, noMatchResource.ComplianceWarnings())
 	})
@@ -608,12 +609,12 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(matchResource, rules)
+		ruler.applyRules(matchResource, rules, nil)
 		assert.True(t, matchResource.IsCompliant())
 		assert.Len(t, matchResource.ComplianceWarnings(), 1)
 		assert.Contains(t, matchResource.ComplianceWarnings()[0].Message, "Test resources should be cleaned up regularly")
 
-		ruler.applyRules(noMatchResource, rules)
+		ruler.applyRules(noMatchResource, rules, nil)
 		assert.True(t, noMatchResource.IsCompliant())
 		assert.Empty(t, noMatchResource.ComplianceWarnings())
 	})
@@ -700,19 +701,19 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(gtMatchResource, gtRules)
+		ruler.applyRules(gtMatchResource, gtRules, nil)
 		assert.False(t, gtMatchResource.IsCompliant())
 		assert.Len(t, gtMatchResource.ComplianceErrors(), 2)
 
-		ruler.applyRules(gtNoMatchResource, gtRules)
+		ruler.applyRules(gtNoMatchResource, gtRules, nil)
 		assert.True(t, gtNoMatchResource.IsCompliant())
 		assert.Empty(t, gtNoMatchResource.ComplianceErrors())
 
-		ruler.applyRules(ltMatchResource, ltRules)
+		ruler.applyRules(ltMatchResource, ltRules, nil)
 		assert.True(t, ltMatchResource.IsCompliant())
 		assert.Len(t, ltMatchResource.ComplianceWarnings(), 1)
 
-		ruler.applyRules(ltNoMatchResource, ltRules)
+		ruler.applyRules(ltNoMatchResource, ltRules, nil)
 		assert.True(t, ltNoMatchResource.IsCompliant())
 		assert.Empty(t, ltNoMatchResource.ComplianceWarnings())
 	})
@@ -769,11 +770,11 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(bothMatchResource, rules)
+		ruler.applyRules(bothMatchResource, rules, nil)
 		assert.False(t, bothMatchResource.IsCompliant())
 		assert.Len(t, bothMatchResource.ComplianceErrors(), 3)
 
-		ruler.applyRules(oneMatchResource, rules)
+		ruler.applyRules(oneMatchResource, rules, nil)
 		assert.True(t, oneMatchResource.IsCompliant())
 		assert.Empty(t, oneMatchResource.ComplianceErrors())
 	})
@@ -843,15 +844,15 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(firstMatchResource, rules)
+		ruler.applyRules(firstMatchResource, rules, nil)
 		assert.False(t, firstMatchResource.IsCompliant())
 		assert.Len(t, firstMatchResource.ComplianceErrors(), 3)
 
-		ruler.applyRules(secondMatchResource, rules)
+		ruler.applyRules(secondMatchResource, rules, nil)
 		assert.False(t, secondMatchResource.IsCompliant())
 		assert.Len(t, secondMatchResource.ComplianceErrors(), 3)
 
-		ruler.applyRules(noMatchResource, rules)
+		ruler.applyRules(noMatchResource, rules, nil)
 		assert.True(t, noMatchResource.IsCompliant())
 		assert.Empty(t, noMatchResource.ComplianceErrors())
 	})
@@ -896,7 +897,7 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(resource, rules)
+		ruler.applyRules(resource, rules, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 4) // 2 errors + 2 missing keys
@@ -933,7 +934,7 @@ func TestApplyRules(t *testing.T) {
 			},
 		}
 
-		ruler.applyRules(resource, rules)
+		ruler.applyRules(resource, rules, nil)
 
 		assert.False(t, resource.IsCompliant())
 		assert.Len(t, resource.ComplianceErrors(), 2)
@@ -1002,6 +1003,208 @@ func TestValidate(t *testing.T) {
 	assert.True(t, errorMessages["Missing required tag `backup-policy` based on rule condition"])
 }
 
+// LowercaseResource is a MockResource of a provider that only allows lowercase tags
+type LowercaseResource struct {
+	*MockResource
+}
+
+func (r *LowercaseResource) NormalizeKey(key string) string {
+	return strings.ToLower(key)
+}
+
+func (r *LowercaseResource) NormalizeValue(value string) string {
+	return strings.ToLower(value)
+}
+
+func TestValidateNormalizedTags(t *testing.T) {
+	ruler := NewRuler()
+
+	resource := &LowercaseResource{NewMockResource("test-id", "test-type", "test-service", "test-provider", "test-region", "test-owner",
+		map[string]string{
+			"environment": "production",
+			"costcenter":  "1234",
+			"team":        "platform-1",
+		},
+	)}
+
+	policy := &types.TagPolicy{
+		MandatoryKeys: []string{"Environment", "CostCenter", "Owner"},
+		Validations: map[string]*types.Validation{
+			"Environment": {
+				Type:          types.TagTypeString,
+				AllowedValues: []string{"Development", "Production"},
+			},
+			"Team": {
+				Type:  types.TagTypeString,
+				Regex: "^[A-Z][a-z]+-[0-9]+$",
+			},
+			"CostCenter": {
+				Type:  types.TagTypeString,
+				Regex: "^CC-[0-9]+$",
+			},
+		},
+		Rules: []*types.Rule{
+			{
+				When: &types.Condition{
+					Equals: &types.EqualsCondition{Key: "Environment", Value: "Production"},
+				},
+				Then: &types.Action{
+					MustContainKeys: []string{"BackupPolicy"},
+				},
+			},
+		},
+	}
+
+	ruler.Validate(resource, policy)
+
+	// the policy keys are kept in the messages
+	var messages []string
+	for _, err := range resource.ComplianceErrors() {
+		messages = append(messages, err.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"Missing mandatory tag: `Owner`",
+		"Missing required tag `BackupPolicy` based on rule condition",
+		"Tag `CostCenter` with value `1234` does not match regex: `^CC-[0-9]+$`",
+	}, messages)
+}
+
+func TestValidateUnknownValues(t *testing.T) {
+	ruler := NewRuler()
+
+	resource := &cr.GenericResource{
+		ResourceID:    "aws_instance.web",
+		ResourceTags:  map[string]string{"env": "(known after apply)", "owner": "(known after apply)"},
+		UnknownValues: []string{"env", "owner"},
+	}
+
+	policy := &types.TagPolicy{
+		MandatoryKeys: []string{"env", "owner"},
+		Validations: map[string]*types.Validation{
+			"env":   {Type: types.TagTypeString, AllowedValues: []string{"prod", "dev"}},
+			"owner": {Type: types.TagTypeString, Regex: "^team-"},
+		},
+		Rules: []*types.Rule{
+			{
+				When: &types.Condition{NotEquals: &types.EqualsCondition{Key: "env", Value: "prod"}},
+				Then: &types.Action{Error: "Non production resources need an expiry"},
+			},
+		},
+	}
+
+	ruler.Validate(resource, policy)
+
+	// the keys satisfy the mandatory checks, the values aren't validated and don't match conditions
+	assert.True(t, resource.IsCompliant())
+	require.Len(t, resource.ComplianceWarnings(), 2)
+	for _, warning := range resource.ComplianceWarnings() {
+		assert.Equal(t, cr.CodeUnknownValue, warning.Code)
+		assert.Equal(t, "(known after apply)", warning.Value)
+	}
+}
+
+func TestValidateUnknownTags(t *testing.T) {
+	ruler := NewRuler()
+
+	// the tag map is only partly known, e.g. the provider default tags of a planned resource
+	resource := &cr.GenericResource{
+		ResourceID:   "aws_instance.web",
+		ResourceTags: map[string]string{"env": "dev"},
+		UnknownTags:  true,
+	}
+
+	policy := &types.TagPolicy{
+		MandatoryKeys: []string{"env", "owner"},
+		Rules: []*types.Rule{
+			{
+				When: &types.Condition{Equals: &types.EqualsCondition{Key: "env", Value: "dev"}},
+				Then: &types.Action{MustContainKeys: []string{"expires-at"}},
+			},
+			{
+				When: &types.Condition{NotEquals: &types.EqualsCondition{Key: "tier", Value: "web"}},
+				Then: &types.Action{Error: "Only web resources are allowed"},
+			},
+		},
+	}
+
+	ruler.Validate(resource, policy)
+
+	// missing tags may still be set at deploy time, they are reported as warnings
+	assert.True(t, resource.IsCompliant())
+	require.Len(t, resource.ComplianceWarnings(), 2)
+	assert.Equal(t, cr.CodeUnknownValue, resource.ComplianceWarnings()[0].Code)
+	assert.Equal(t, "owner", resource.ComplianceWarnings()[0].Key)
+	assert.Equal(t, cr.CodeUnknownValue, resource.ComplianceWarnings()[1].Code)
+	assert.Equal(t, "expires-at", resource.ComplianceWarnings()[1].Key)
+}
+
+func TestValidateStructuredFindings(t *testing.T) {
+	ruler := NewRuler()
+
+	resource := NewMockResource("test-id", "test-type", "test-service", "test-provider", "test-region", "test-owner",
+		map[string]string{
+			"environment": "qa",
+			"ttl":         "120",
+		},
+	)
+
+	blueprintOrigin := &types.Origin{Kind: types.OriginBlueprint, Name: "base", RuleIndex: -1}
+	resourceOrigin := &types.Origin{Kind: types.OriginResource, Name: "ec2.instance", RuleIndex: -1}
+	ruleOrigin := &types.Origin{Kind: types.OriginResource, Name: "ec2.instance", RuleIndex: 0}
+
+	policy := &types.TagPolicy{
+		MandatoryKeys: []string{"owner"},
+		Validations: map[string]*types.Validation{
+			"environment": {Type: types.TagTypeString, AllowedValues: []string{"dev", "prod"}},
+			"ttl":         {Type: types.TagTypeInt, MaxValue: 90},
+		},
+		Rules: []*types.Rule{
+			{
+				When: &types.Condition{Exists: &types.ExistsCondition{Key: "ttl"}},
+				Then: &types.Action{ShouldContainKeys: []string{"expires-at"}},
+			},
+		},
+		Origins: &types.Origins{
+			MandatoryKeys: map[string]*types.Origin{"owner": blueprintOrigin},
+			Validations:   map[string]*types.Origin{"environment": blueprintOrigin, "ttl": resourceOrigin},
+			Rules:         []*types.Origin{ruleOrigin},
+		},
+	}
+
+	ruler.Validate(resource, policy)
+
+	errorsByCode := make(map[cr.Code]*cr.ComplianceError)
+	for _, err := range resource.ComplianceErrors() {
+		errorsByCode[err.Code] = err
+	}
+	require.Len(t, errorsByCode, 3)
+
+	missing := errorsByCode[cr.CodeMissingMandatoryTag]
+	require.NotNil(t, missing)
+	assert.Equal(t, "owner", missing.Key)
+	assert.Equal(t, blueprintOrigin, missing.Origin)
+
+	notAllowed := errorsByCode[cr.CodeValueNotAllowed]
+	require.NotNil(t, notAllowed)
+	assert.Equal(t, "environment", notAllowed.Key)
+	assert.Equal(t, "qa", notAllowed.Value)
+	assert.Equal(t, "one of: dev, prod", notAllowed.Expected)
+	assert.Equal(t, blueprintOrigin, notAllowed.Origin)
+
+	aboveMax := errorsByCode[cr.CodeAboveMaximum]
+	require.NotNil(t, aboveMax)
+	assert.Equal(t, "ttl", aboveMax.Key)
+	assert.Equal(t, "120", aboveMax.Value)
+	assert.Equal(t, "<= 90", aboveMax.Expected)
+	assert.Equal(t, resourceOrigin, aboveMax.Origin)
+
+	require.Len(t, resource.ComplianceWarnings(), 1)
+	warning := resource.ComplianceWarnings()[0]
+	assert.Equal(t, cr.CodeRuleMissingRecommendedTag, warning.Code)
+	assert.Equal(t, "expires-at", warning.Key)
+	assert.Equal(t, ruleOrigin, warning.Origin)
+}
+
 func TestValidateAll(t *testing.T) {
 	ruler := NewRuler()
 
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/eliran89c/tag-patrol/pkg/cloudresource/provider/kubernetes"
+	"github.com/eliran89c/tag-patrol/pkg/patrol"
+	ptypes "github.com/eliran89c/tag-patrol/pkg/policy/types"
+	"github.com/eliran89c/tag-patrol/pkg/ruler"
+)
+
+// maxRequestBytes limits the size of an admission review request body
+const maxRequestBytes = 3 * 1024 * 1024
+
+// WarningsAnnotation is the audit annotation key holding the compliance warnings of an admitted object
+const WarningsAnnotation = "warnings"
+
+// AdmissionReview is the admission.k8s.io/v1 AdmissionReview object
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest holds the subset of the admission request used by the webhook
+type AdmissionRequest struct {
+	UID       string           `json:"uid"`
+	Kind      GroupVersionKind `json:"kind"`
+	Namespace string           `json:"namespace,omitempty"`
+	Operation string           `json:"operation"`
+	Object    json.RawMessage  `json:"object,omitempty"`
+}
+
+// GroupVersionKind identifies the kind of the admitted object
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// AdmissionResponse is the webhook decision for an admission request
+type AdmissionResponse struct {
+	UID              string            `json:"uid"`
+	Allowed          bool              `json:"allowed"`
+	Status           *Status           `json:"status,omitempty"`
+	Warnings         []string          `json:"warnings,omitempty"`
+	AuditAnnotations map[string]string `json:"auditAnnotations,omitempty"`
+}
+
+// Status holds the reason an admission request was rejected
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Options defines the configuration options for the webhook handler
+type Options struct {
+	// Annotations treats object annotations as tags in addition to labels
+	Annotations bool
+	// WarnOnly admits non-compliant objects and reports their errors as warnings
+	WarnOnly bool
+}
+
+// DefaultOptions returns the default webhook options
+func DefaultOptions() *Options {
+	return &Options{
+		Annotations: false,
+		WarnOnly:    false,
+	}
+}
+
+// Handler is a validating admission webhook that checks object labels against the policy.
+// Objects with compliance errors are rejected, compliance warnings are returned to
+// the client and recorded as audit annotations.
+type Handler struct {
+	definitions map[string]*ptypes.ResourceDefinition
+	ruler       patrol.Ruler
+	options     *Options
+}
+
+// New creates a new webhook handler for the given resource definitions, using the
+// API group as service and the kind as resource type
+func New(definitions []*ptypes.ResourceDefinition, options *Options) *Handler {
+	if options == nil {
+		options = DefaultOptions()
+	}
+
+	h := &Handler{
+		definitions: make(map[string]*ptypes.ResourceDefinition, len(definitions)),
+		ruler:       ruler.NewRuler(),
+		options:     options,
+	}
+
+	for _, definition := range definitions {
+		h.definitions[definitionKey(definition.Service, definition.ResourceType)] = definition
+	}
+
+	return h
+}
+
+// ServeHTTP handles AdmissionReview requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "unsupported content type, expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var review AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(w, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.Review(review.Request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&AdmissionReview{
+		APIVersion: review.APIVersion,
+		Kind:       review.Kind,
+		Response:   response,
+	})
+}
+
+// Review validates the object of an admission request against the policy
+func (h *Handler) Review(request *AdmissionRequest) (*AdmissionResponse, error) {
+	response := &AdmissionResponse{UID: request.UID, Allowed: true}
+
+	if request.Operation != "CREATE" && request.Operation != "UPDATE" {
+		return response, nil
+	}
+
+	group := request.Kind.Group
+	if group == "" {
+		group = kubernetes.CoreGroup
+	}
+
+	definition, ok := h.definitions[definitionKey(group, request.Kind.Kind)]
+	if !ok {
+		return response, nil
+	}
+
+	object := &kubernetes.Object{}
+	if err := json.Unmarshal(request.Object, object); err != nil {
+		return nil, fmt.Errorf("error decoding object: %w", err)
+	}
+
+	// objects created without an explicit namespace or name get them during admission
+	if object.Metadata.Namespace == "" {
+		object.Metadata.Namespace = request.Namespace
+	}
+	if object.Metadata.Name == "" {
+		object.Metadata.Name = object.Metadata.GenerateName
+	}
+	object.APIVersion = strings.Trim(request.Kind.Group+"/"+request.Kind.Version, "/")
+	object.Kind = request.Kind.Kind
+
+	resource := kubernetes.NewResource(object, "", h.options.Annotations)
+	h.ruler.Validate(resource, definition.TagPolicy)
+
+	var violations []string
+	for _, e := range resource.ComplianceErrors() {
+		violations = append(violations, e.Message)
+	}
+	for _, w := range resource.ComplianceWarnings() {
+		response.Warnings = append(response.Warnings, w.Message)
+	}
+
+	if len(violations) > 0 && h.options.WarnOnly {
+		response.Warnings = append(violations, response.Warnings...)
+		violations = nil
+	}
+
+	if len(violations) > 0 {
+		response.Allowed = false
+		response.Status = &Status{
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("%s %s violates the tag policy:\n- %s", object.Kind, resource.ID(), strings.Join(violations, "\n- ")),
+		}
+	}
+
+	if len(response.Warnings) > 0 {
+		response.AuditAnnotations = map[string]string{WarningsAnnotation: strings.Join(response.Warnings, "; ")}
+	}
+
+	return response, nil
+}
+
+func definitionKey(group, kind string) string {
+	return group + "/" + strings.ToLower(kind)
+}
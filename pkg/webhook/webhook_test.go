@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eliran89c/tag-patrol/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+resources:
+  apps:
+    Deployment:
+      mandatoryKeys:
+        - owner
+      validations:
+        env:
+          type: string
+          allowedValues: [prod, dev]
+      rules:
+        - when:
+            equals:
+              key: env
+              value: prod
+          then:
+            shouldContainKeys:
+              - cost-center
+  core:
+    Namespace:
+      mandatoryKeys:
+        - team
+`
+
+func newTestHandler(t *testing.T, options *Options) *Handler {
+	t.Helper()
+
+	definitions, err := policy.NewParser().ParseBytes([]byte(testPolicy))
+	require.NoError(t, err)
+
+	return New(definitions, options)
+}
+
+func admissionReview(t *testing.T, group, kind, operation string, object map[string]any) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(object)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(&AdmissionReview{
+		APIVersion: "admission.k8s.io/v1",
+		Kind:       "AdmissionReview",
+		Request: &AdmissionRequest{
+			UID:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+			Kind:      GroupVersionKind{Group: group, Version: "v1", Kind: kind},
+			Namespace: "shop",
+			Operation: operation,
+			Object:    raw,
+		},
+	})
+	require.NoError(t, err)
+
+	return body
+}
+
+func deployment(labels map[string]string) map[string]any {
+	return map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "web", "labels": labels},
+	}
+}
+
+func serve(t *testing.T, h *Handler, body []byte) *AdmissionReview {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var review AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	require.NotNil(t, review.Response)
+	assert.Equal(t, "admission.k8s.io/v1", review.APIVersion)
+	assert.Equal(t, "AdmissionReview", review.Kind)
+	assert.Equal(t, "705ab4f5-6393-11e8-b7cc-42010a800002", review.Response.UID)
+
+	return &review
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	t.Run("Compliant", func(t *testing.T) {
+		review := serve(t, h, admissionReview(t, "apps", "Deployment", "CREATE", deployment(map[string]string{"owner": "a", "env": "dev"})))
+		assert.True(t, review.Response.Allowed)
+		assert.Nil(t, review.Response.Status)
+		assert.Empty(t, review.Response.Warnings)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		review := serve(t, h, admissionReview(t, "apps", "Deployment", "UPDATE", deployment(map[string]string{"env": "qa"})))
+		assert.False(t, review.Response.Allowed)
+		require.NotNil(t, review.Response.Status)
+		assert.Equal(t, http.StatusForbidden, review.Response.Status.Code)
+		assert.Contains(t, review.Response.Status.Message, "Deployment shop/web violates the tag policy")
+		assert.Contains(t, review.Response.Status.Message, "- Missing mandatory tag: `owner`")
+		assert.Contains(t, review.Response.Status.Message, "`env`")
+	})
+
+	t.Run("Warnings", func(t *testing.T) {
+		review := serve(t, h, admissionReview(t, "apps", "Deployment", "CREATE", deployment(map[string]string{"owner": "a", "env": "prod"})))
+		assert.True(t, review.Response.Allowed)
+		require.Len(t, review.Response.Warnings, 1)
+		assert.Contains(t, review.Response.Warnings[0], "cost-center")
+		assert.Equal(t, review.Response.Warnings[0], review.Response.AuditAnnotations[WarningsAnnotation])
+	})
+
+	t.Run("Core Group", func(t *testing.T) {
+		namespace := map[string]any{"apiVersion": "v1", "kind": "Namespace", "metadata": map[string]any{"name": "shop"}}
+		review := serve(t, h, admissionReview(t, "", "Namespace", "CREATE", namespace))
+		assert.False(t, review.Response.Allowed)
+		assert.Contains(t, review.Response.Status.Message, "`team`")
+	})
+
+	t.Run("Kind Without Definition", func(t *testing.T) {
+		review := serve(t, h, admissionReview(t, "batch", "Job", "CREATE", map[string]any{"metadata": map[string]any{"name": "job"}}))
+		assert.True(t, review.Response.Allowed)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		review := serve(t, h, admissionReview(t, "apps", "Deployment", "DELETE", nil))
+		assert.True(t, review.Response.Allowed)
+	})
+}
+
+func TestHandlerWarnOnly(t *testing.T) {
+	h := newTestHandler(t, &Options{WarnOnly: true})
+
+	review := serve(t, h, admissionReview(t, "apps", "Deployment", "CREATE", deployment(nil)))
+	assert.True(t, review.Response.Allowed)
+	assert.Nil(t, review.Response.Status)
+	assert.Equal(t, []string{"Missing mandatory tag: `owner`"}, review.Response.Warnings)
+}
+
+func TestHandlerAnnotations(t *testing.T) {
+	object := deployment(nil)
+	object["metadata"].(map[string]any)["annotations"] = map[string]string{"owner": "a"}
+
+	review := serve(t, newTestHandler(t, nil), admissionReview(t, "apps", "Deployment", "CREATE", object))
+	assert.False(t, review.Response.Allowed)
+
+	review = serve(t, newTestHandler(t, &Options{Annotations: true}), admissionReview(t, "apps", "Deployment", "CREATE", object))
+	assert.True(t, review.Response.Allowed)
+}
+
+func TestHandlerBadRequests(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{name: "Method", method: http.MethodGet, contentType: "application/json", code: http.StatusMethodNotAllowed},
+		{name: "Content Type", method: http.MethodPost, contentType: "text/plain", body: "{}", code: http.StatusUnsupportedMediaType},
+		{name: "Invalid JSON", method: http.MethodPost, contentType: "application/json", body: "{", code: http.StatusBadRequest},
+		{name: "No Request", method: http.MethodPost, contentType: "application/json; charset=utf-8", body: `{"kind": "AdmissionReview"}`, code: http.StatusBadRequest},
+		{
+			name:        "Invalid Object",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"request": {"uid": "1", "kind": {"group": "apps", "kind": "Deployment"}, "operation": "CREATE", "object": "not an object"}}`,
+			code:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/validate", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}